@@ -0,0 +1,140 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ReadyCondition reports whether all the logging resources of the cluster are in sync.
+	ReadyCondition = "Ready"
+
+	// ReconciledReason is used when a resource was successfully reconciled.
+	ReconciledReason = "Reconciled"
+	// ReconcileFailedReason is used when a resource failed to reconcile.
+	ReconcileFailedReason = "ReconcileFailed"
+	// RequeuedReason is used when a resource asked to be reconciled again later.
+	RequeuedReason = "Requeued"
+	// NotReconciledReason is used when a resource was not reconciled because a previous one did not complete.
+	NotReconciledReason = "NotReconciled"
+	// LoggingDisabledReason is used when logging is disabled for the cluster.
+	LoggingDisabledReason = "LoggingDisabled"
+)
+
+// Phases of the rollout of the configuration of a cluster.
+const (
+	// RolloutPendingPhase is used when a configuration update of the cluster is held back by the rollout.
+	RolloutPendingPhase = "Pending"
+	// RolloutProgressingPhase is used when the configuration of the cluster was updated and Alloy is not healthy yet.
+	RolloutProgressingPhase = "Progressing"
+	// RolloutHealthyPhase is used when Alloy is healthy with the current configuration of the cluster.
+	RolloutHealthyPhase = "Healthy"
+	// RolloutFailedPhase is used when Alloy did not become healthy in time after a configuration update of the
+	// cluster. It pauses the rollout to the other clusters.
+	RolloutFailedPhase = "Failed"
+)
+
+// ClusterLoggingStatusStatus defines the observed state of the logging pipeline of a cluster.
+type ClusterLoggingStatusStatus struct {
+	// LoggingEnabled is true when logging is enabled for the cluster.
+	LoggingEnabled bool `json:"loggingEnabled"`
+
+	// ObservabilityBundleVersion is the version of the observability-bundle app detected for the cluster.
+	// +optional
+	ObservabilityBundleVersion string `json:"observabilityBundleVersion,omitempty"`
+
+	// ConfigHash is the hash of the generated logging and events logger configurations.
+	// +optional
+	ConfigHash string `json:"configHash,omitempty"`
+
+	// Rollout reports the progress of the rollout of the configuration to the cluster, when rollouts are enabled.
+	// +optional
+	Rollout *RolloutStatus `json:"rollout,omitempty"`
+
+	// LastError is the last error returned while reconciling the cluster.
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+
+	// LastReconcileTime is the last time the cluster was reconciled.
+	// +optional
+	LastReconcileTime *metav1.Time `json:"lastReconcileTime,omitempty"`
+
+	// Conditions holds a Ready condition and one condition per reconciled resource
+	// (LoggingSecretReady, LoggingConfigReady, EventsLoggerSecretReady, EventsLoggerConfigReady).
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// RolloutStatus defines the state of the rollout of the configuration to a cluster.
+type RolloutStatus struct {
+	// Phase is Pending, Progressing, Healthy or Failed.
+	Phase string `json:"phase"`
+
+	// Canary is true when the cluster receives configuration updates before the others.
+	// +optional
+	Canary bool `json:"canary,omitempty"`
+
+	// Revision is the rollout revision the configuration of the cluster was last rolled out at.
+	// +optional
+	Revision string `json:"revision,omitempty"`
+
+	// ConfigHash is the hash of the configuration rolled out to the cluster.
+	// +optional
+	ConfigHash string `json:"configHash,omitempty"`
+
+	// UpdateTime is the last time the configuration of the cluster was updated.
+	// +optional
+	UpdateTime *metav1.Time `json:"updateTime,omitempty"`
+
+	// Message explains why the rollout is held back, or why Alloy is not healthy.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:shortName=cls
+//+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+//+kubebuilder:printcolumn:name="Reason",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].reason"
+//+kubebuilder:printcolumn:name="Rollout",type="string",JSONPath=".status.rollout.phase"
+//+kubebuilder:printcolumn:name="Bundle",type="string",JSONPath=".status.observabilityBundleVersion"
+//+kubebuilder:printcolumn:name="Last Reconcile",type="date",JSONPath=".status.lastReconcileTime"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// ClusterLoggingStatus reports the state of the logging resources managed for the cluster of the same name.
+type ClusterLoggingStatus struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status ClusterLoggingStatusStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ClusterLoggingStatusList contains a list of ClusterLoggingStatus
+type ClusterLoggingStatusList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterLoggingStatus `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterLoggingStatus{}, &ClusterLoggingStatusList{})
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LoggingPolicySpec defines the logging settings applied to the clusters
+// selected by the policy. Unset fields fall back to the operator flags.
+type LoggingPolicySpec struct {
+	// ClusterSelector selects the clusters, within the namespace of the policy, the policy applies to.
+	// An empty selector selects all clusters of the namespace.
+	// +optional
+	ClusterSelector metav1.LabelSelector `json:"clusterSelector,omitempty"`
+
+	// Logging enables or disables logging for the selected clusters.
+	// The giantswarm.io/logging cluster label still applies on top of it.
+	// +optional
+	Logging *bool `json:"logging,omitempty"`
+
+	// NodeFiltering enables or disables node filtering in the Alloy logging configuration.
+	// +optional
+	NodeFiltering *bool `json:"nodeFiltering,omitempty"`
+
+	// Tracing enables or disables tracing support in the events logger.
+	// +optional
+	Tracing *bool `json:"tracing,omitempty"`
+
+	// NetworkMonitoring enables or disables network monitoring.
+	// The giantswarm.io/network-monitoring cluster label still applies on top of it.
+	// +optional
+	NetworkMonitoring *bool `json:"networkMonitoring,omitempty"`
+
+	// AuthMode is how the selected clusters authenticate to Loki and Tempo: basic or mtls.
+	// The mtls mode requires a client certificate issuer to be configured for the operator.
+	// +kubebuilder:validation:Enum=basic;mtls
+	// +optional
+	AuthMode string `json:"authMode,omitempty"`
+
+	// DefaultNamespaces is the list of namespaces to collect logs from by default on workload clusters.
+	// +optional
+	DefaultNamespaces []string `json:"defaultNamespaces,omitempty"`
+
+	// IncludeEventsFromNamespaces is the list of namespaces to collect events from on workload clusters.
+	// If empty, events are collected from all namespaces.
+	// +optional
+	IncludeEventsFromNamespaces []string `json:"includeEventsFromNamespaces,omitempty"`
+
+	// ExcludeEventsFromNamespaces is the list of namespaces to exclude events from on workload clusters.
+	// +optional
+	ExcludeEventsFromNamespaces []string `json:"excludeEventsFromNamespaces,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:shortName=lp
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// LoggingPolicy is the Schema for the loggingpolicies API
+type LoggingPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec LoggingPolicySpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// LoggingPolicyList contains a list of LoggingPolicy
+type LoggingPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []LoggingPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&LoggingPolicy{}, &LoggingPolicyList{})
+}
@@ -18,13 +18,20 @@ package controller
 
 import (
 	"context"
+	"slices"
+	"time"
 
 	appv1alpha1 "github.com/giantswarm/apiextensions-application/api/v1alpha1"
 	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
 	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
-	capi "sigs.k8s.io/cluster-api/api/core/v1beta1" //nolint:staticcheck // SA1019 deprecated package
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/cluster-api/util/patch"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
@@ -32,13 +39,19 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	loggingv1alpha1 "github.com/giantswarm/logging-operator/api/v1alpha1"
 	"github.com/giantswarm/logging-operator/internal/controller/predicates"
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
 	"github.com/giantswarm/logging-operator/pkg/common"
 	"github.com/giantswarm/logging-operator/pkg/config"
 	"github.com/giantswarm/logging-operator/pkg/key"
+	"github.com/giantswarm/logging-operator/pkg/loggingpolicy"
+	"github.com/giantswarm/logging-operator/pkg/metrics"
 	"github.com/giantswarm/logging-operator/pkg/resource"
+	"github.com/giantswarm/logging-operator/pkg/rollout"
 )
 
 // CapiClusterReconciler reconciles a Cluster object
@@ -46,11 +59,21 @@ type CapiClusterReconciler struct {
 	Client    client.Client
 	Scheme    *runtime.Scheme
 	Config    config.Config
+	Recorder  record.EventRecorder
 	Resources []resource.Interface
+	// CAPIVersion is the version of the CAPI API clusters are read in.
+	CAPIVersion capicluster.Version
+	// Rollout holds configuration updates back until the rollout reaches the cluster, nil when disabled.
+	Rollout *rollout.Rollout
 }
 
 //+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters,verbs=get;list;watch
 //+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters/status,verbs=get
+//+kubebuilder:rbac:groups=logging.giantswarm.io,resources=loggingpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes;gateways,verbs=get;list;watch
+//+kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=apps,resources=daemonsets;deployments,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -63,10 +86,13 @@ type CapiClusterReconciler struct {
 func (r *CapiClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
 	logger := log.FromContext(ctx)
 
-	cluster := &capi.Cluster{}
-	err = r.Client.Get(ctx, types.NamespacedName{Name: req.Name, Namespace: req.Namespace}, cluster)
+	cluster, err := capicluster.Get(ctx, r.Client, r.CAPIVersion, types.NamespacedName{Name: req.Name, Namespace: req.Namespace})
 	if err != nil {
 		if apimachineryerrors.IsNotFound(err) {
+			metrics.DeleteCluster(req.NamespacedName)
+			if r.Rollout != nil {
+				r.Rollout.Forget(req.NamespacedName)
+			}
 			return ctrl.Result{}, nil
 		}
 		return ctrl.Result{}, errors.WithStack(err)
@@ -74,8 +100,18 @@ func (r *CapiClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 	logger.Info("Reconciling CAPI Cluster", "name", cluster.GetName())
 
+	// Merge the logging policies selecting this cluster over the flag defaults
+	// and hand the result over to the resources through the context.
+	clusterConfig, err := loggingpolicy.Resolve(ctx, r.Client, cluster, r.Config)
+	if err != nil {
+		return ctrl.Result{}, errors.WithStack(err)
+	}
+	ctx = config.NewContext(ctx, clusterConfig)
+
 	// Determine if logging should be enabled or disabled
-	if common.IsLoggingEnabled(cluster, r.Config.EnableLoggingFlag) {
+	loggingEnabled := common.IsLoggingEnabled(cluster, clusterConfig.EnableLoggingFlag)
+	r.recordClusterMetrics(ctx, cluster, clusterConfig, loggingEnabled)
+	if loggingEnabled {
 		return r.reconcileCreate(ctx, cluster)
 	} else {
 		return r.reconcileDelete(ctx, cluster)
@@ -83,50 +119,94 @@ func (r *CapiClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 }
 
 // reconcileCreate handles creation/update logic by calling ReconcileCreate method on all reconcilers.
-func (r *CapiClusterReconciler) reconcileCreate(ctx context.Context, cluster *capi.Cluster) (ctrl.Result, error) {
+func (r *CapiClusterReconciler) reconcileCreate(ctx context.Context, cluster *capicluster.Cluster) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 	logger.Info("LOGGING enabled")
 
-	if !controllerutil.ContainsFinalizer(cluster, key.Finalizer) {
+	if !controllerutil.ContainsFinalizer(cluster.Object, key.Finalizer) {
 		logger.Info("adding finalizer", "finalizer", key.Finalizer)
 
 		// We use a patch rather than an update to avoid conflicts when multiple controllers are adding their finalizer to the ClusterCR
 		// We use the patch from sigs.k8s.io/cluster-api/util/patch to handle the patching without conflicts
-		patchHelper, err := patch.NewHelper(cluster, r.Client)
+		patchHelper, err := patch.NewHelper(cluster.Object, r.Client)
 		if err != nil {
 			return ctrl.Result{}, errors.WithStack(err)
 		}
-		controllerutil.AddFinalizer(cluster, key.Finalizer)
-		if err := patchHelper.Patch(ctx, cluster); err != nil {
+		controllerutil.AddFinalizer(cluster.Object, key.Finalizer)
+		if err := patchHelper.Patch(ctx, cluster.Object); err != nil {
 			logger.Error(err, "failed to add finalizer to logger cluster", "finalizer", key.Finalizer)
 			return ctrl.Result{}, errors.WithStack(err)
 		}
 		logger.Info("successfully added finalizer to logged cluster", "finalizer", key.Finalizer)
 	}
 
-	// Call all resources ReconcileCreate methods.
-	for _, resource := range r.Resources {
+	// Tell the resources whether they may update the configurations of the cluster.
+	var decision *rollout.Decision
+	if r.Rollout != nil {
+		var err error
+		decision, err = r.Rollout.Decide(ctx, cluster)
+		if err != nil {
+			return ctrl.Result{}, errors.WithStack(err)
+		}
+		ctx = rollout.NewContext(ctx, decision)
+	}
+
+	// Call all resources ReconcileCreate methods, each one once its dependencies are reconciled.
+	results := resource.Run(r.Resources, func(resource resource.Interface) (ctrl.Result, error) {
+		start := time.Now()
 		result, err := resource.ReconcileCreate(ctx, cluster)
-		if err != nil || !result.IsZero() {
-			return result, errors.WithStack(err)
+		metrics.ObserveResourceReconcile(resource.Name(), metrics.CreateOperation, cluster.GetNamespace(), cluster.GetName(), time.Since(start), err)
+		if err != nil {
+			r.Recorder.Eventf(cluster.Object, v1.EventTypeWarning, common.EventReason(resource.Name(), common.FailedAction), "Failed to reconcile %s: %s", resource.Name(), err)
 		}
+		return result, err
+	})
+
+	conditions := make([]metav1.Condition, 0, len(results))
+	for _, result := range results {
+		if result.BlockedBy != "" {
+			conditions = append(conditions, notReconciledCondition(cluster, result.Name, result.BlockedBy))
+			continue
+		}
+		conditions = append(conditions, resourceCondition(cluster, result.Name, result.Result, result.Err))
 	}
 
-	return ctrl.Result{}, nil
+	result, err := resource.Aggregate(results)
+	statusErr := r.updateStatus(ctx, cluster, true, conditions, err, decision)
+	// Keep checking the health of the cluster while its update is in progress.
+	if decision != nil && decision.InProgress() && result.IsZero() {
+		result.RequeueAfter = rollout.RequeueAfter
+	}
+	return result, errors.WithStack(statusError(ctx, err, statusErr))
 }
 
 // reconcileDelete handles deletion logic by calling reconcileDelete method on all reconcilers.
-func (r *CapiClusterReconciler) reconcileDelete(ctx context.Context, cluster *capi.Cluster) (ctrl.Result, error) {
+func (r *CapiClusterReconciler) reconcileDelete(ctx context.Context, cluster *capicluster.Cluster) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 	logger.Info("LOGGING disabled")
 
-	if controllerutil.ContainsFinalizer(cluster, key.Finalizer) {
-		// Call all resources ReconcileDelete methods.
-		for _, resource := range r.Resources {
-			result, err := resource.ReconcileDelete(ctx, cluster)
-			if err != nil || !result.IsZero() {
-				return result, errors.WithStack(err)
+	// The health of the cluster is no longer checked.
+	if r.Rollout != nil {
+		r.Rollout.Forget(client.ObjectKeyFromObject(cluster.Object))
+	}
+
+	if controllerutil.ContainsFinalizer(cluster.Object, key.Finalizer) {
+		// Call all resources ReconcileDelete methods. Dependencies do not apply to deletions, a failing resource does not
+		// prevent the others from cleaning up, but the finalizer is only removed once all of them are done.
+		var results []resource.Result
+		for _, res := range r.Resources {
+			start := time.Now()
+			result, err := res.ReconcileDelete(ctx, cluster)
+			metrics.ObserveResourceReconcile(res.Name(), metrics.DeleteOperation, cluster.GetNamespace(), cluster.GetName(), time.Since(start), err)
+			if err != nil {
+				r.Recorder.Eventf(cluster.Object, v1.EventTypeWarning, common.EventReason(res.Name(), common.FailedAction), "Failed to delete %s: %s", res.Name(), err)
 			}
+			results = append(results, resource.Result{Name: res.Name(), Result: result, Err: err})
+		}
+		result, err := resource.Aggregate(results)
+		if err != nil || !result.IsZero() {
+			statusErr := r.updateStatus(ctx, cluster, false, nil, err, nil)
+			return result, errors.WithStack(statusError(ctx, err, statusErr))
 		}
 
 		// We get the latest state of the object to avoid race conditions.
@@ -135,25 +215,45 @@ func (r *CapiClusterReconciler) reconcileDelete(ctx context.Context, cluster *ca
 
 		// We use a patch rather than an update to avoid conflicts when multiple controllers are removing their finalizer from the ClusterCR
 		// We use the patch from sigs.k8s.io/cluster-api/util/patch to handle the patching without conflicts
-		patchHelper, err := patch.NewHelper(cluster, r.Client)
+		patchHelper, err := patch.NewHelper(cluster.Object, r.Client)
 		if err != nil {
 			return ctrl.Result{}, errors.WithStack(err)
 		}
-		controllerutil.RemoveFinalizer(cluster, key.Finalizer)
-		if err := patchHelper.Patch(ctx, cluster); err != nil {
+		controllerutil.RemoveFinalizer(cluster.Object, key.Finalizer)
+		if err := patchHelper.Patch(ctx, cluster.Object); err != nil {
 			logger.Error(err, "failed to remove finalizer from logger cluster, requeuing", "finalizer", key.Finalizer)
 			return ctrl.Result{}, errors.WithStack(err)
 		}
 		logger.Info("successfully removed finalizer from logged cluster", "finalizer", key.Finalizer)
 	}
 
+	err := r.updateStatus(ctx, cluster, false, nil, nil, nil)
+	if err != nil {
+		return ctrl.Result{}, errors.WithStack(err)
+	}
+
 	return ctrl.Result{}, nil
 }
 
+// recordClusterMetrics exposes the state of the cluster as metrics.
+func (r *CapiClusterReconciler) recordClusterMetrics(ctx context.Context, cluster *capicluster.Cluster, clusterConfig config.Config, loggingEnabled bool) {
+	state := metrics.ClusterState{
+		LoggingEnabled:           loggingEnabled,
+		NetworkMonitoringEnabled: loggingEnabled && common.IsNetworkMonitoringEnabled(cluster, clusterConfig.EnableNetworkMonitoringFlag),
+	}
+
+	observabilityBundleVersion, err := common.GetObservabilityBundleAppVersion(ctx, r.Client, cluster)
+	if err == nil {
+		state.ObservabilityBundleVersion = observabilityBundleVersion.String()
+	}
+
+	metrics.SetClusterState(types.NamespacedName{Name: cluster.GetName(), Namespace: cluster.GetNamespace()}, state)
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *CapiClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&capi.Cluster{}).
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(r.CAPIVersion.NewObject()).
 		// This ensures we run the reconcile loop when the observability-bundle app resource version changes.
 		Watches(
 			&appv1alpha1.App{},
@@ -167,5 +267,214 @@ func (r *CapiClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			}),
 			builder.WithPredicates(predicates.ObservabilityBundleAppVersionChangedPredicate{}),
 		).
-		Complete(r)
+		// This ensures we run the reconcile loop for the clusters of a namespace when one of its logging policies changes.
+		// All clusters of the namespace are enqueued so that clusters no longer selected by the policy are reconciled too,
+		// and all logging enabled clusters when the rollout is enabled so that the canaries pick up the new revision.
+		Watches(
+			&loggingv1alpha1.LoggingPolicy{},
+			handler.EnqueueRequestsFromMapFunc(r.loggingPolicyClusters),
+		).
+		// This ensures we run the reconcile loop for all logging enabled clusters when the Loki or Tempo ingress
+		// the logging and tracing endpoints are read from changes.
+		Watches(
+			&netv1.Ingress{},
+			handler.EnqueueRequestsFromMapFunc(r.loggingEnabledClusters),
+			builder.WithPredicates(predicate.NewPredicateFuncs(common.IsGatewayEndpoint), predicate.GenerationChangedPredicate{}),
+		).
+		// This ensures we run the reconcile loop for a cluster when the observability-operator rotates its credentials.
+		Watches(
+			&v1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(authSecretCluster),
+			builder.WithPredicates(predicate.NewPredicateFuncs(func(object client.Object) bool {
+				_, ok := common.AuthSecretClusterName(object)
+				return ok
+			})),
+		).
+		// This ensures we run the reconcile loop for a cluster when cert-manager renews its client certificate.
+		Watches(
+			&v1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(clientCertificateCluster),
+			builder.WithPredicates(predicate.NewPredicateFuncs(func(object client.Object) bool {
+				_, ok := common.ClientCertificateClusterName(object)
+				return ok
+			})),
+		).
+		// This ensures we run the reconcile loop for all logging enabled clusters when the CA bundle changes.
+		Watches(
+			&v1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.loggingEnabledClusters),
+			builder.WithPredicates(predicate.NewPredicateFuncs(r.isCABundle)),
+		).
+		Watches(
+			&v1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(r.loggingEnabledClusters),
+			builder.WithPredicates(predicate.NewPredicateFuncs(r.isCABundle)),
+		).
+		// This ensures we run the reconcile loop for all logging enabled clusters when the replacement templates change.
+		Watches(
+			&v1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(r.loggingEnabledClusters),
+			builder.WithPredicates(predicate.NewPredicateFuncs(r.isTemplatesConfigMap)),
+		).
+		// This ensures we run the reconcile loop for a cluster when its values overrides change.
+		Watches(
+			&v1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(r.valuesOverridesClusters),
+			builder.WithPredicates(predicate.NewPredicateFuncs(common.IsValuesOverrides)),
+		)
+
+	// Same for the Loki and Tempo HTTPRoutes, and the gateways they attach to which give the port and protocol of
+	// the endpoints, when the Gateway API is installed.
+	_, err := mgr.GetRESTMapper().RESTMapping(common.HTTPRouteGroupVersionKind.GroupKind(), common.HTTPRouteGroupVersionKind.Version)
+	switch {
+	case err == nil:
+		httpRoute := &unstructured.Unstructured{}
+		httpRoute.SetGroupVersionKind(common.HTTPRouteGroupVersionKind)
+		gateway := &unstructured.Unstructured{}
+		gateway.SetGroupVersionKind(common.GatewayGroupVersionKind)
+		b = b.Watches(
+			httpRoute,
+			handler.EnqueueRequestsFromMapFunc(r.loggingEnabledClusters),
+			builder.WithPredicates(predicate.NewPredicateFuncs(common.IsGatewayEndpoint), predicate.GenerationChangedPredicate{}),
+		).Watches(
+			gateway,
+			handler.EnqueueRequestsFromMapFunc(r.endpointGatewayClusters),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		)
+	case !meta.IsNoMatchError(err):
+		return errors.WithStack(err)
+	}
+
+	return b.Complete(r)
+}
+
+// loggingEnabledClusters returns a reconcile request for each cluster with logging enabled, by its label or by the
+// LoggingPolicies selecting it.
+func (r *CapiClusterReconciler) loggingEnabledClusters(ctx context.Context, _ client.Object) []reconcile.Request {
+	logger := log.FromContext(ctx)
+
+	clusters, err := capicluster.List(ctx, r.Client, r.CAPIVersion)
+	if err != nil {
+		logger.Error(err, "failed to list clusters")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, cluster := range clusters {
+		clusterConfig, err := loggingpolicy.Resolve(ctx, r.Client, cluster, r.Config)
+		if err != nil {
+			// The cluster is reconciled anyway, its reconciliation reports the invalid policy.
+			logger.Error(err, "failed to resolve logging policies", "cluster", cluster.GetName(), "namespace", cluster.GetNamespace())
+		} else if !common.IsLoggingEnabled(cluster, clusterConfig.EnableLoggingFlag) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: cluster.GetName(), Namespace: cluster.GetNamespace()},
+		})
+	}
+	return requests
+}
+
+// endpointGatewayClusters returns a reconcile request for each cluster with logging enabled when the given gateway
+// is the parent of the Loki or Tempo HTTPRoute.
+func (r *CapiClusterReconciler) endpointGatewayClusters(ctx context.Context, gateway client.Object) []reconcile.Request {
+	logger := log.FromContext(ctx)
+
+	isEndpointGateway, err := common.IsEndpointGateway(ctx, r.Client, gateway)
+	if err != nil {
+		logger.Error(err, "failed to read the gateway endpoints", "namespace", gateway.GetNamespace(), "name", gateway.GetName())
+		return nil
+	}
+	if !isEndpointGateway {
+		return nil
+	}
+	return r.loggingEnabledClusters(ctx, gateway)
+}
+
+// isCABundle returns true if the given secret or configmap holds the CA bundle of the installation.
+func (r *CapiClusterReconciler) isCABundle(object client.Object) bool {
+	return common.IsCABundle(r.Config, object)
+}
+
+// isTemplatesConfigMap returns true if the given configmap holds the replacement templates of the resources.
+func (r *CapiClusterReconciler) isTemplatesConfigMap(object client.Object) bool {
+	return common.IsTemplatesConfigMap(r.Config, object)
+}
+
+// authSecretCluster returns a reconcile request for the cluster whose credentials are stored in the given secret.
+func authSecretCluster(_ context.Context, secret client.Object) []reconcile.Request {
+	clusterName, ok := common.AuthSecretClusterName(secret)
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: clusterName, Namespace: secret.GetNamespace()}},
+	}
+}
+
+// clientCertificateCluster returns a reconcile request for the cluster whose client certificate is stored in the given secret.
+func clientCertificateCluster(_ context.Context, secret client.Object) []reconcile.Request {
+	clusterName, ok := common.ClientCertificateClusterName(secret)
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: clusterName, Namespace: secret.GetNamespace()}},
+	}
+}
+
+// valuesOverridesClusters returns a reconcile request for each cluster whose values overrides are stored in the given configmap.
+func (r *CapiClusterReconciler) valuesOverridesClusters(ctx context.Context, configmap client.Object) []reconcile.Request {
+	logger := log.FromContext(ctx)
+
+	clusters, err := capicluster.List(ctx, r.Client, r.CAPIVersion, client.InNamespace(configmap.GetNamespace()))
+	if err != nil {
+		logger.Error(err, "failed to list clusters", "namespace", configmap.GetNamespace())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, cluster := range clusters {
+		if common.ValuesOverridesName(cluster) != configmap.GetName() {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: cluster.GetName(), Namespace: cluster.GetNamespace()},
+		})
+	}
+	return requests
+}
+
+// loggingPolicyClusters returns a reconcile request for each cluster in the namespace of the given policy and, when
+// the rollout is enabled, for each cluster with logging enabled, as the policy is part of the rollout revision.
+func (r *CapiClusterReconciler) loggingPolicyClusters(ctx context.Context, policy client.Object) []reconcile.Request {
+	requests := r.clustersInNamespace(ctx, policy)
+	if r.Rollout == nil {
+		return requests
+	}
+	for _, request := range r.loggingEnabledClusters(ctx, policy) {
+		if !slices.Contains(requests, request) {
+			requests = append(requests, request)
+		}
+	}
+	return requests
+}
+
+// clustersInNamespace returns a reconcile request for each cluster in the namespace of the given object.
+func (r *CapiClusterReconciler) clustersInNamespace(ctx context.Context, object client.Object) []reconcile.Request {
+	logger := log.FromContext(ctx)
+
+	clusters, err := capicluster.List(ctx, r.Client, r.CAPIVersion, client.InNamespace(object.GetNamespace()))
+	if err != nil {
+		logger.Error(err, "failed to list clusters", "namespace", object.GetNamespace())
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(clusters))
+	for _, cluster := range clusters {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: cluster.GetName(), Namespace: cluster.GetNamespace()},
+		})
+	}
+	return requests
 }
@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	capiv1beta2 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	loggingv1alpha1 "github.com/giantswarm/logging-operator/api/v1alpha1"
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
+	"github.com/giantswarm/logging-operator/pkg/config"
+	"github.com/giantswarm/logging-operator/pkg/key"
+)
+
+func TestLoggingEnabledClusters(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = capiv1beta2.AddToScheme(scheme)
+
+	_ = loggingv1alpha1.AddToScheme(scheme)
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&capiv1beta2.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "enabled", Namespace: "org-a"}},
+		&capiv1beta2.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "disabled", Namespace: "org-b", Labels: map[string]string{key.LoggingLabel: "false"}}},
+		&capiv1beta2.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "disabled-by-policy", Namespace: "org-c"}},
+		&loggingv1alpha1.LoggingPolicy{ObjectMeta: metav1.ObjectMeta{Name: "no-logging", Namespace: "org-c"}, Spec: loggingv1alpha1.LoggingPolicySpec{Logging: ptr.To(false)}},
+	).Build()
+	r := &CapiClusterReconciler{Client: c, Config: config.Config{EnableLoggingFlag: true}, CAPIVersion: capicluster.V1Beta2}
+
+	requests := r.loggingEnabledClusters(context.Background(), &netv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: "loki-gateway", Namespace: "loki"}})
+
+	expected := []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "enabled", Namespace: "org-a"}}}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("expected requests %v, got %v", expected, requests)
+	}
+}
+
+func TestAuthSecretCluster(t *testing.T) {
+	requests := authSecretCluster(context.Background(), &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster-observability-logs-auth", Namespace: "org-test"}})
+
+	expected := []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "test-cluster", Namespace: "org-test"}}}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("expected requests %v, got %v", expected, requests)
+	}
+}
+
+func TestValuesOverridesClusters(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = capiv1beta2.AddToScheme(scheme)
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&capiv1beta2.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "convention", Namespace: "org-a"}},
+		&capiv1beta2.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "annotated", Namespace: "org-a", Annotations: map[string]string{key.ValuesOverridesAnnotation: "shared-overrides"}}},
+		&capiv1beta2.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "org-a", Annotations: map[string]string{key.ValuesOverridesAnnotation: "shared-overrides"}}},
+		&capiv1beta2.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "convention", Namespace: "org-b"}},
+	).Build()
+	r := &CapiClusterReconciler{Client: c, CAPIVersion: capicluster.V1Beta2}
+
+	testCases := []struct {
+		name     string
+		expected []reconcile.Request
+	}{
+		{
+			name:     "convention-logging-overrides",
+			expected: []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "convention", Namespace: "org-a"}}},
+		},
+		{
+			name: "shared-overrides",
+			expected: []reconcile.Request{
+				{NamespacedName: types.NamespacedName{Name: "annotated", Namespace: "org-a"}},
+				{NamespacedName: types.NamespacedName{Name: "other", Namespace: "org-a"}},
+			},
+		},
+		{
+			name: "annotated-logging-overrides",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := r.valuesOverridesClusters(context.Background(), &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: tc.name, Namespace: "org-a"}})
+			if !reflect.DeepEqual(requests, tc.expected) {
+				t.Errorf("expected requests %v, got %v", tc.expected, requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	loggingv1alpha1 "github.com/giantswarm/logging-operator/api/v1alpha1"
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
+	"github.com/giantswarm/logging-operator/pkg/common"
+	"github.com/giantswarm/logging-operator/pkg/metrics"
+	eventsloggerconfig "github.com/giantswarm/logging-operator/pkg/resource/events-logger-config"
+	loggingconfig "github.com/giantswarm/logging-operator/pkg/resource/logging-config"
+	"github.com/giantswarm/logging-operator/pkg/rollout"
+)
+
+//+kubebuilder:rbac:groups=logging.giantswarm.io,resources=clusterloggingstatuses,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=logging.giantswarm.io,resources=clusterloggingstatuses/status,verbs=get;update;patch
+
+// conditionType returns the type of the condition reporting the state of the given resource,
+// e.g. LoggingSecretReady for the logging-secret resource.
+func conditionType(resourceName string) string {
+	return common.CamelCase(resourceName) + "Ready"
+}
+
+// resourceCondition returns the condition reporting the outcome of a resource reconciliation.
+func resourceCondition(cluster *capicluster.Cluster, resourceName string, result ctrl.Result, err error) metav1.Condition {
+	condition := metav1.Condition{
+		Type:               conditionType(resourceName),
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: cluster.GetGeneration(),
+		Reason:             loggingv1alpha1.ReconciledReason,
+		Message:            fmt.Sprintf("%s is up to date", resourceName),
+	}
+
+	switch {
+	case err != nil:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = loggingv1alpha1.ReconcileFailedReason
+		condition.Message = err.Error()
+	case !result.IsZero():
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = loggingv1alpha1.RequeuedReason
+		condition.Message = fmt.Sprintf("%s is not ready yet, requeued after %s", resourceName, result.RequeueAfter)
+	}
+
+	return condition
+}
+
+// notReconciledCondition returns the condition of a resource which was skipped because a previous one did not complete.
+func notReconciledCondition(cluster *capicluster.Cluster, resourceName, blockingResourceName string) metav1.Condition {
+	return metav1.Condition{
+		Type:               conditionType(resourceName),
+		Status:             metav1.ConditionUnknown,
+		ObservedGeneration: cluster.GetGeneration(),
+		Reason:             loggingv1alpha1.NotReconciledReason,
+		Message:            fmt.Sprintf("waiting for %s to be reconciled", blockingResourceName),
+	}
+}
+
+// readyCondition returns the Ready condition summarizing the given resource conditions.
+func readyCondition(cluster *capicluster.Cluster, loggingEnabled bool, conditions []metav1.Condition) metav1.Condition {
+	ready := metav1.Condition{
+		Type:               loggingv1alpha1.ReadyCondition,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: cluster.GetGeneration(),
+		Reason:             loggingv1alpha1.ReconciledReason,
+		Message:            "all logging resources are up to date",
+	}
+
+	if !loggingEnabled {
+		ready.Status = metav1.ConditionFalse
+		ready.Reason = loggingv1alpha1.LoggingDisabledReason
+		ready.Message = "logging is disabled for this cluster"
+		return ready
+	}
+
+	for _, condition := range conditions {
+		if condition.Status != metav1.ConditionTrue {
+			ready.Status = metav1.ConditionFalse
+			ready.Reason = condition.Reason
+			ready.Message = fmt.Sprintf("%s: %s", condition.Type, condition.Message)
+			return ready
+		}
+	}
+
+	return ready
+}
+
+// updateStatus reports the outcome of the reconciliation in the ClusterLoggingStatus of the cluster.
+// The ClusterLoggingStatus is owned by the cluster and removed once the cluster is being deleted.
+// The rollout status is tracked when a rollout decision was made for the cluster.
+func (r *CapiClusterReconciler) updateStatus(ctx context.Context, cluster *capicluster.Cluster, loggingEnabled bool, conditions []metav1.Condition, reconcileErr error, decision *rollout.Decision) error {
+	logger := log.FromContext(ctx)
+
+	status := &loggingv1alpha1.ClusterLoggingStatus{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cluster.GetName(),
+			Namespace: cluster.GetNamespace(),
+		},
+	}
+
+	if !cluster.GetDeletionTimestamp().IsZero() {
+		err := r.Client.Delete(ctx, status)
+		if err != nil && !apimachineryerrors.IsNotFound(err) {
+			return errors.WithStack(err)
+		}
+		return nil
+	}
+
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, status, func() error {
+		if status.Labels == nil {
+			status.Labels = map[string]string{}
+		}
+		common.AddCommonLabels(status.Labels)
+		return controllerutil.SetOwnerReference(cluster.Object, status, r.Scheme)
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	status.Status.LoggingEnabled = loggingEnabled
+	now := metav1.Now()
+	status.Status.LastReconcileTime = &now
+
+	status.Status.LastError = ""
+	if reconcileErr != nil {
+		status.Status.LastError = reconcileErr.Error()
+	}
+
+	status.Status.ObservabilityBundleVersion = ""
+	observabilityBundleVersion, err := common.GetObservabilityBundleAppVersion(ctx, r.Client, cluster)
+	if err == nil {
+		status.Status.ObservabilityBundleVersion = observabilityBundleVersion.String()
+	} else if !apimachineryerrors.IsNotFound(err) {
+		logger.Info("cluster logging status - failed to read observability bundle version", "error", err)
+	}
+
+	status.Status.ConfigHash, err = r.configHash(ctx, cluster)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	previousRollout := status.Status.Rollout
+	status.Status.Rollout = nil
+	if decision != nil {
+		status.Status.Rollout = r.Rollout.Status(ctx, cluster, previousRollout, decision, status.Status.ConfigHash)
+		if status.Status.Rollout.Phase == loggingv1alpha1.RolloutFailedPhase && (previousRollout == nil || previousRollout.Phase != loggingv1alpha1.RolloutFailedPhase) {
+			r.Recorder.Eventf(cluster.Object, v1.EventTypeWarning, common.RolloutFailedReason, "Alloy did not become healthy within %s after the configuration update, the rollout is paused: %s", r.Rollout.Config.RolloutHealthTimeout, status.Status.Rollout.Message)
+		}
+	}
+	rolloutPhase := ""
+	if status.Status.Rollout != nil {
+		rolloutPhase = status.Status.Rollout.Phase
+	}
+	metrics.SetClusterRolloutPhase(types.NamespacedName{Name: cluster.GetName(), Namespace: cluster.GetNamespace()}, rolloutPhase)
+
+	if !loggingEnabled {
+		// Resource conditions are meaningless once everything has been cleaned up.
+		status.Status.Conditions = nil
+	}
+	for _, condition := range conditions {
+		meta.SetStatusCondition(&status.Status.Conditions, condition)
+	}
+	meta.SetStatusCondition(&status.Status.Conditions, readyCondition(cluster, loggingEnabled, conditions))
+
+	err = r.Client.Status().Update(ctx, status)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// configHash returns the hash of the logging and events logger configmaps generated for the cluster.
+func (r *CapiClusterReconciler) configHash(ctx context.Context, cluster *capicluster.Cluster) (string, error) {
+	var configmaps []v1.ConfigMap
+	for _, objectMeta := range []metav1.ObjectMeta{loggingconfig.ConfigMeta(cluster), eventsloggerconfig.ConfigMeta(cluster)} {
+		var configmap v1.ConfigMap
+		err := r.Client.Get(ctx, types.NamespacedName{Name: objectMeta.GetName(), Namespace: objectMeta.GetNamespace()}, &configmap)
+		if err != nil {
+			if apimachineryerrors.IsNotFound(err) {
+				continue
+			}
+			return "", errors.WithStack(err)
+		}
+		configmaps = append(configmaps, configmap)
+	}
+
+	if len(configmaps) == 0 {
+		return "", nil
+	}
+	return common.ConfigHash(configmaps...), nil
+}
+
+// statusError returns the error to report for the reconciliation: the reconcile error if any,
+// the status update error otherwise.
+func statusError(ctx context.Context, reconcileErr, statusErr error) error {
+	if statusErr == nil {
+		return reconcileErr
+	}
+	if reconcileErr != nil {
+		log.FromContext(ctx).Error(statusErr, "failed to update cluster logging status")
+		return reconcileErr
+	}
+	return statusErr
+}
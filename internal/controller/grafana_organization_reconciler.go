@@ -23,13 +23,16 @@ import (
 	"github.com/pkg/errors"
 	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
-	capi "sigs.k8s.io/cluster-api/api/core/v1beta1" //nolint:staticcheck // SA1019 deprecated package
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
 	"github.com/giantswarm/logging-operator/pkg/common"
+	"github.com/giantswarm/logging-operator/pkg/config"
+	"github.com/giantswarm/logging-operator/pkg/loggingpolicy"
 	loggingconfig "github.com/giantswarm/logging-operator/pkg/resource/logging-config"
+	"github.com/giantswarm/logging-operator/pkg/rollout"
 )
 
 // GrafanaOrganizationReconciler reconciles grafanaOrganization CRs
@@ -37,6 +40,10 @@ type GrafanaOrganizationReconciler struct {
 	Client   client.Client
 	Scheme   *runtime.Scheme
 	Resource loggingconfig.Resource
+	// CAPIVersion is the version of the CAPI API clusters are read in.
+	CAPIVersion capicluster.Version
+	// Rollout holds configuration updates back until the rollout reaches the cluster, nil when disabled.
+	Rollout *rollout.Rollout
 }
 
 //+kubebuilder:rbac:groups=observability.giantswarm.io,resources=grafanaorganizations,verbs=get;list;watch;update;patch
@@ -56,8 +63,7 @@ func (g *GrafanaOrganizationReconciler) Reconcile(ctx context.Context, req ctrl.
 	logger.Info("Started reconciling Grafana Organization")
 	defer logger.Info("Finished reconciling Grafana Organization")
 
-	clusters := &capi.ClusterList{}
-	err = g.Client.List(ctx, clusters)
+	clusters, err := capicluster.List(ctx, g.Client, g.CAPIVersion)
 	if err != nil {
 		if apimachineryerrors.IsNotFound(err) {
 			return ctrl.Result{}, nil
@@ -65,10 +71,24 @@ func (g *GrafanaOrganizationReconciler) Reconcile(ctx context.Context, req ctrl.
 		return ctrl.Result{}, errors.WithStack(err)
 	}
 
-	for _, cluster := range clusters.Items {
-		if common.IsLoggingEnabled(&cluster, g.Resource.Config.EnableLoggingFlag) {
+	for _, cluster := range clusters {
+		clusterConfig, err := loggingpolicy.Resolve(ctx, g.Client, cluster, g.Resource.Config)
+		if err != nil {
+			return ctrl.Result{}, errors.WithStack(err)
+		}
+
+		if common.IsLoggingEnabled(cluster, clusterConfig.EnableLoggingFlag) {
+			clusterCtx := config.NewContext(ctx, clusterConfig)
+			if g.Rollout != nil {
+				decision, err := g.Rollout.Decide(ctx, cluster)
+				if err != nil {
+					return ctrl.Result{}, errors.WithStack(err)
+				}
+				clusterCtx = rollout.NewContext(clusterCtx, decision)
+			}
+
 			// Reconcile logging config for each cluster
-			result, err := g.Resource.ReconcileCreate(ctx, &cluster)
+			result, err := g.Resource.ReconcileCreate(clusterCtx, cluster)
 			if err != nil {
 				return result, errors.WithStack(err)
 			}
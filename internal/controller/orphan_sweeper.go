@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
+	"github.com/giantswarm/logging-operator/pkg/common"
+	"github.com/giantswarm/logging-operator/pkg/key"
+	"github.com/giantswarm/logging-operator/pkg/metrics"
+)
+
+// OrphanSweeper periodically deletes the configmaps and secrets managed by the operator whose cluster
+// no longer exists, e.g. when the cluster finalizer was removed out of band.
+type OrphanSweeper struct {
+	Client      client.Client
+	CAPIVersion capicluster.Version
+	// Interval is the time between two sweeps.
+	Interval time.Duration
+	// DryRun only reports the orphaned objects, without deleting them.
+	DryRun bool
+}
+
+// Start sweeps orphaned objects every interval until the context is cancelled.
+func (s *OrphanSweeper) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("orphan-sweeper")
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		err := s.Sweep(ctx)
+		if err != nil {
+			logger.Error(err, "failed to sweep orphaned objects")
+		}
+	}, s.Interval)
+	return nil
+}
+
+// NeedLeaderElection makes the sweeper only run on the leader.
+func (s *OrphanSweeper) NeedLeaderElection() bool {
+	return true
+}
+
+// Sweep deletes the managed configmaps and secrets whose cluster no longer exists.
+// The cluster of an object is found from its cluster owner reference, from its cluster label for the objects
+// written before owner references were set, or from its name for the objects written before they were labelled.
+// Objects with none of them are left alone.
+func (s *OrphanSweeper) Sweep(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("orphan-sweeper")
+
+	lists := []struct {
+		kind string
+		list client.ObjectList
+	}{
+		{kind: "ConfigMap", list: &v1.ConfigMapList{}},
+		{kind: "Secret", list: &v1.SecretList{}},
+	}
+	for _, l := range lists {
+		kind, list := l.kind, l.list
+		err := s.Client.List(ctx, list, common.ManagedObjectsSelector())
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		var objects []client.Object
+		switch items := list.(type) {
+		case *v1.ConfigMapList:
+			for i := range items.Items {
+				objects = append(objects, &items.Items[i])
+			}
+		case *v1.SecretList:
+			for i := range items.Items {
+				objects = append(objects, &items.Items[i])
+			}
+		}
+
+		orphans := 0
+		for _, object := range objects {
+			orphaned, err := s.isOrphaned(ctx, object)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			if !orphaned {
+				continue
+			}
+			orphans++
+
+			if s.DryRun {
+				logger.Info("found orphaned object, not deleting it in dry run mode", "kind", kind, "namespace", object.GetNamespace(), "name", object.GetName())
+				continue
+			}
+
+			logger.Info("deleting orphaned object", "kind", kind, "namespace", object.GetNamespace(), "name", object.GetName())
+			err = s.Client.Delete(ctx, object, client.Preconditions{UID: ptr.To(object.GetUID())})
+			if err != nil && !apimachineryerrors.IsNotFound(err) {
+				return errors.WithStack(err)
+			}
+			metrics.ObserveOrphanedObjectDeletion(kind)
+		}
+		metrics.SetOrphanedObjects(kind, orphans)
+	}
+
+	return nil
+}
+
+// isOrphaned returns true if the cluster owning the given object no longer exists.
+func (s *OrphanSweeper) isOrphaned(ctx context.Context, object client.Object) (bool, error) {
+	var clusterName string
+	var clusterUID types.UID
+	for _, ownerReference := range object.GetOwnerReferences() {
+		gv, err := schema.ParseGroupVersion(ownerReference.APIVersion)
+		if err == nil && gv.WithKind(ownerReference.Kind).GroupKind() == capicluster.GroupKind {
+			clusterName = ownerReference.Name
+			clusterUID = ownerReference.UID
+			break
+		}
+	}
+	if clusterName == "" {
+		clusterName = object.GetLabels()[key.ClusterLabel]
+	}
+	if clusterName == "" {
+		var ok bool
+		clusterName, ok = common.ManagedObjectClusterName(object)
+		if !ok {
+			return false, nil
+		}
+	}
+
+	cluster, err := capicluster.Get(ctx, s.Client, s.CAPIVersion, types.NamespacedName{Name: clusterName, Namespace: object.GetNamespace()})
+	if apimachineryerrors.IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	// A cluster recreated with the same name does not own the objects of the previous one.
+	return clusterUID != "" && cluster.GetUID() != clusterUID, nil
+}
@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	capiv1beta2 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
+	"github.com/giantswarm/logging-operator/pkg/common"
+	"github.com/giantswarm/logging-operator/pkg/key"
+)
+
+func TestOrphanSweeper(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = capiv1beta2.AddToScheme(scheme)
+
+	existing := capicluster.New(&capiv1beta2.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "existing", Namespace: "org-test", UID: "existing-uid"}})
+	deleted := capicluster.New(&capiv1beta2.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "deleted", Namespace: "org-test", UID: "deleted-uid"}})
+	recreated := capicluster.New(&capiv1beta2.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "recreated", Namespace: "org-test", UID: "old-uid"}})
+
+	managedMeta := func(name string, cluster *capicluster.Cluster) metav1.ObjectMeta {
+		metadata := metav1.ObjectMeta{Name: name, Namespace: "org-test", Labels: map[string]string{}}
+		common.AddCommonLabels(metadata.Labels)
+		if cluster != nil {
+			common.AddClusterOwner(&metadata, cluster)
+		}
+		return metadata
+	}
+	labelledMeta := managedMeta("deleted-legacy-logging-config", nil)
+	labelledMeta.Labels[key.ClusterLabel] = "deleted-legacy"
+
+	objects := []client.Object{
+		existing.Object,
+		&capiv1beta2.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "recreated", Namespace: "org-test", UID: "new-uid"}},
+		&v1.ConfigMap{ObjectMeta: managedMeta("existing-logging-config", existing)},
+		&v1.Secret{ObjectMeta: managedMeta("existing-logging-secret", existing)},
+		&v1.ConfigMap{ObjectMeta: managedMeta("deleted-logging-config", deleted)},
+		&v1.Secret{ObjectMeta: managedMeta("deleted-logging-secret", deleted)},
+		&v1.ConfigMap{ObjectMeta: managedMeta("recreated-logging-config", recreated)},
+		&v1.ConfigMap{ObjectMeta: labelledMeta},
+		&v1.ConfigMap{ObjectMeta: managedMeta("unknown-cluster", nil)},
+		&v1.ConfigMap{ObjectMeta: managedMeta("existing-events-logger-config", nil)},
+		&v1.Secret{ObjectMeta: managedMeta("deleted-events-logger-secret", nil)},
+		&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "unmanaged", Namespace: "org-test", Labels: map[string]string{key.ClusterLabel: "deleted"}}},
+	}
+
+	orphans := []client.Object{
+		&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "deleted-logging-config", Namespace: "org-test"}},
+		&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "deleted-logging-secret", Namespace: "org-test"}},
+		&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "recreated-logging-config", Namespace: "org-test"}},
+		&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "deleted-legacy-logging-config", Namespace: "org-test"}},
+		&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "deleted-events-logger-secret", Namespace: "org-test"}},
+	}
+	kept := []client.Object{
+		&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "existing-logging-config", Namespace: "org-test"}},
+		&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "existing-logging-secret", Namespace: "org-test"}},
+		&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "unknown-cluster", Namespace: "org-test"}},
+		&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "existing-events-logger-config", Namespace: "org-test"}},
+		&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "unmanaged", Namespace: "org-test"}},
+	}
+
+	exists := func(c client.Client, object client.Object) bool {
+		err := c.Get(ctx, types.NamespacedName{Name: object.GetName(), Namespace: object.GetNamespace()}, object)
+		if err != nil && !apimachineryerrors.IsNotFound(err) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return err == nil
+	}
+
+	for _, dryRun := range []bool{true, false} {
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+		sweeper := &OrphanSweeper{Client: c, CAPIVersion: capicluster.V1Beta2, DryRun: dryRun}
+
+		if err := sweeper.Sweep(ctx); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for _, object := range orphans {
+			if exists(c, object) == !dryRun {
+				t.Errorf("dry run %t: unexpected existence of orphaned %T %s", dryRun, object, object.GetName())
+			}
+		}
+		for _, object := range kept {
+			if !exists(c, object) {
+				t.Errorf("dry run %t: expected %T %s to be kept", dryRun, object, object.GetName())
+			}
+		}
+	}
+}
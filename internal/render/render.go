@@ -0,0 +1,122 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
+	"github.com/giantswarm/logging-operator/pkg/common"
+	"github.com/giantswarm/logging-operator/pkg/config"
+	"github.com/giantswarm/logging-operator/pkg/loggingpolicy"
+	"github.com/giantswarm/logging-operator/pkg/resource"
+)
+
+// Render returns the objects the resources would write for the given cluster, as returned by their Desired method.
+// Nothing is written. Resources failing to render or which can not be rendered are reported in the returned error,
+// the others are still rendered.
+func Render(ctx context.Context, c client.Client, cluster *capicluster.Cluster, defaults config.Config, resources []resource.Interface) ([]client.Object, error) {
+	clusterConfig, err := loggingpolicy.Resolve(ctx, c, cluster, defaults)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if !common.IsLoggingEnabled(cluster, clusterConfig.EnableLoggingFlag) {
+		return nil, errors.Errorf("logging is disabled for cluster %s/%s", cluster.GetNamespace(), cluster.GetName())
+	}
+	ctx = config.NewContext(ctx, clusterConfig)
+
+	var objects []client.Object
+	var errs []error
+	for _, r := range resources {
+		renderer, ok := r.(resource.Renderer)
+		if !ok {
+			errs = append(errs, errors.Errorf("%s can not be rendered", r.Name()))
+			continue
+		}
+		desired, err := renderer.Desired(ctx, cluster)
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "failed to render %s", r.Name()))
+			continue
+		}
+		objects = append(objects, desired...)
+	}
+
+	return objects, kerrors.NewAggregate(errs)
+}
+
+// Write prints the data of the given configmaps and secrets. Secret values are redacted.
+func Write(w io.Writer, objects []client.Object) error {
+	for _, object := range objects {
+		var kind string
+		data := map[string][]byte{}
+		switch o := object.(type) {
+		case *v1.ConfigMap:
+			kind = "ConfigMap"
+			for key, value := range o.Data {
+				data[key] = []byte(value)
+			}
+		case *v1.Secret:
+			kind = "Secret"
+			for key, value := range o.Data {
+				redacted, err := common.RedactSecretValues(value)
+				if err != nil {
+					return errors.Wrapf(err, "failed to redact secret %s/%s", o.GetNamespace(), o.GetName())
+				}
+				data[key] = redacted
+			}
+		default:
+			continue
+		}
+
+		for _, key := range slices.Sorted(maps.Keys(data)) {
+			_, err := fmt.Fprintf(w, "# %s %s/%s, key %s\n%s\n", kind, object.GetNamespace(), object.GetName(), key, data[key])
+			if err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+	return nil
+}
+
+// WriteDiff prints the diff between the live version of the given configmaps and secrets, read with the given reader,
+// and the rendered one. Objects which do not exist yet are diffed against an empty object. Secret values are redacted.
+func WriteDiff(ctx context.Context, w io.Writer, c client.Reader, objects []client.Object) error {
+	for _, object := range objects {
+		var diff string
+		switch o := object.(type) {
+		case *v1.ConfigMap:
+			var current v1.ConfigMap
+			err := c.Get(ctx, client.ObjectKeyFromObject(o), &current)
+			if err != nil && !apimachineryerrors.IsNotFound(err) {
+				return errors.WithStack(err)
+			}
+			diff = common.ConfigMapDiff(current, *o)
+		case *v1.Secret:
+			var current v1.Secret
+			err := c.Get(ctx, client.ObjectKeyFromObject(o), &current)
+			if err != nil && !apimachineryerrors.IsNotFound(err) {
+				return errors.WithStack(err)
+			}
+			diff = common.SecretDiff(current, *o)
+		default:
+			continue
+		}
+
+		if diff == "" {
+			diff = fmt.Sprintf("%s/%s is up to date\n", object.GetNamespace(), object.GetName())
+		}
+		_, err := fmt.Fprint(w, diff)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	capiv1beta2 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	loggingv1alpha1 "github.com/giantswarm/logging-operator/api/v1alpha1"
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
+	"github.com/giantswarm/logging-operator/pkg/config"
+	"github.com/giantswarm/logging-operator/pkg/resource"
+)
+
+// testResource renders the given objects, or fails with the given error.
+type testResource struct {
+	name    string
+	objects []client.Object
+	err     error
+}
+
+func (r *testResource) Name() string        { return r.name }
+func (r *testResource) DependsOn() []string { return nil }
+func (r *testResource) ReconcileCreate(context.Context, *capicluster.Cluster) (ctrl.Result, error) {
+	return ctrl.Result{}, errors.New("not expected to be called")
+}
+func (r *testResource) ReconcileDelete(context.Context, *capicluster.Cluster) (ctrl.Result, error) {
+	return ctrl.Result{}, errors.New("not expected to be called")
+}
+func (r *testResource) Desired(context.Context, *capicluster.Cluster) ([]client.Object, error) {
+	return r.objects, r.err
+}
+
+// notRenderedResource does not implement resource.Renderer.
+type notRenderedResource struct {
+	resource.Interface
+}
+
+func (r *notRenderedResource) Name() string { return "not-rendered" }
+
+func TestRender(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = loggingv1alpha1.AddToScheme(scheme)
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	cluster := capicluster.New(&capiv1beta2.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", Namespace: "org-test"}})
+
+	configmap := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster-logging-config", Namespace: "org-test"}}
+	secret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster-logging-secret", Namespace: "org-test"}}
+	resources := []resource.Interface{
+		&testResource{name: "logging-secret", objects: []client.Object{secret}},
+		&testResource{name: "failing", err: errors.New("failed")},
+		&notRenderedResource{},
+		&testResource{name: "logging-config", objects: []client.Object{configmap}},
+	}
+
+	objects, err := Render(ctx, c, cluster, config.Config{EnableLoggingFlag: true}, resources)
+	if err == nil || !strings.Contains(err.Error(), "failed to render failing") || !strings.Contains(err.Error(), "not-rendered can not be rendered") {
+		t.Errorf("expected the failing and not rendered resources to be reported, got %v", err)
+	}
+	if len(objects) != 2 || objects[0] != secret || objects[1] != configmap {
+		t.Errorf("expected the desired objects of the other resources, got %v", objects)
+	}
+
+	_, err = Render(ctx, c, cluster, config.Config{EnableLoggingFlag: false}, resources)
+	if err == nil {
+		t.Error("expected an error when logging is disabled for the cluster")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	objects := []client.Object{
+		&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster-logging-config", Namespace: "org-test"},
+			Data:       map[string]string{"values": "alloy: {}\n"},
+		},
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster-logging-secret", Namespace: "org-test"},
+			Data: map[string][]byte{"values": []byte(`alloy:
+  alloy:
+    extraSecretEnv:
+    - name: "logging-password"
+      value: "secret"
+    - name: "logging-username"
+      value: "test-cluster"
+`)},
+		},
+	}
+
+	var out bytes.Buffer
+	if err := Write(&out, objects); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `# ConfigMap org-test/test-cluster-logging-config, key values
+alloy: {}
+
+# Secret org-test/test-cluster-logging-secret, key values
+alloy:
+  alloy:
+    extraSecretEnv:
+    - name: logging-password
+      value: <redacted>
+    - name: logging-username
+      value: test-cluster
+
+`
+	if out.String() != expected {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
@@ -18,8 +18,10 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -29,25 +31,37 @@ import (
 	"github.com/giantswarm/observability-operator/api/v1alpha1"
 	"github.com/giantswarm/observability-operator/pkg/auth"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	capiv1beta1 "sigs.k8s.io/cluster-api/api/core/v1beta1" //nolint:staticcheck // SA1019 deprecated package
+	capiv1beta2 "sigs.k8s.io/cluster-api/api/core/v1beta2"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
+	loggingv1alpha1 "github.com/giantswarm/logging-operator/api/v1alpha1"
 	"github.com/giantswarm/logging-operator/internal/controller"
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
+	"github.com/giantswarm/logging-operator/pkg/common"
 	"github.com/giantswarm/logging-operator/pkg/config"
+	"github.com/giantswarm/logging-operator/pkg/metrics"
 	"github.com/giantswarm/logging-operator/pkg/resource"
+	clientcertificate "github.com/giantswarm/logging-operator/pkg/resource/client-certificate"
 	eventsloggerconfig "github.com/giantswarm/logging-operator/pkg/resource/events-logger-config"
 	eventsloggersecret "github.com/giantswarm/logging-operator/pkg/resource/events-logger-secret"
 	loggingconfig "github.com/giantswarm/logging-operator/pkg/resource/logging-config"
 	loggingsecret "github.com/giantswarm/logging-operator/pkg/resource/logging-secret"
+	"github.com/giantswarm/logging-operator/pkg/revision"
+	"github.com/giantswarm/logging-operator/pkg/rollout"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -59,8 +73,10 @@ var (
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(capiv1beta1.AddToScheme(scheme))
+	utilruntime.Must(capiv1beta2.AddToScheme(scheme))
 	utilruntime.Must(appv1.AddToScheme(scheme))
 	utilruntime.Must(v1alpha1.AddToScheme(scheme))
+	utilruntime.Must(loggingv1alpha1.AddToScheme(scheme))
 
 	//+kubebuilder:scaffold:scheme
 }
@@ -76,39 +92,146 @@ func (s *StringSliceVar) Set(value string) error {
 	return nil
 }
 
+// bindConfigFlags registers the flags configuring the generated logging resources on the given flag set.
+func bindConfigFlags(fs *flag.FlagSet, cfg *config.Config) {
+	fs.Var((*StringSliceVar)(&cfg.DefaultNamespaces), "default-namespaces", "List of namespaces to collect logs from by default on workload clusters")
+	fs.BoolVar(&cfg.EnableLoggingFlag, "enable-logging", true, "enable/disable logging for the whole installation")
+	fs.BoolVar(&cfg.LogsReconciliationEnabled, "logs-reconciliation-enabled", true, "enable/disable alloy logs reconcilers")
+	fs.BoolVar(&cfg.EventsReconciliationEnabled, "events-reconciliation-enabled", true, "enable/disable events logging reconcilers")
+	fs.BoolVar(&cfg.EnableNodeFilteringFlag, "enable-node-filtering", false, "enable/disable node filtering in Alloy logging configuration")
+	fs.BoolVar(&cfg.EnableTracingFlag, "enable-tracing", false, "enable/disable tracing support for events logger")
+	fs.BoolVar(&cfg.EnableNetworkMonitoringFlag, "enable-network-monitoring", false, "enable/disable network monitoring for the whole installation")
+	fs.Var((*StringSliceVar)(&cfg.IncludeEventsFromNamespaces), "include-events-from-namespaces", "List of namespaces to collect events from on workload clusters (if empty, collect from all namespaces)")
+	fs.Var((*StringSliceVar)(&cfg.ExcludeEventsFromNamespaces), "exclude-events-from-namespaces", "List of namespaces to exclude events from on workload clusters")
+	fs.StringVar(&cfg.InstallationName, "installation-name", "unknown", "Name of the installation")
+	fs.BoolVar(&cfg.InsecureCA, "insecure-ca", false, "Is the management cluter CA insecure? Deprecated, use --ca-bundle-secret or --ca-bundle-configmap instead")
+	fs.StringVar(&cfg.CABundleSecret, "ca-bundle-secret", "", "Secret holding the CA bundle verifying the Loki and Tempo certificates, as <namespace>/<name>")
+	fs.StringVar(&cfg.CABundleConfigMap, "ca-bundle-configmap", "", "Configmap holding the CA bundle verifying the Loki and Tempo certificates, as <namespace>/<name>")
+	fs.StringVar(&cfg.CABundleKey, "ca-bundle-key", common.DefaultCABundleKey, "Key of the CA bundle in its secret or configmap")
+	fs.StringVar(&cfg.EndpointSource, "endpoint-source", string(common.EndpointSourceAuto), "Where the Loki and Tempo endpoints are read from: auto, ingress, httproute or static")
+	fs.StringVar(&cfg.LokiEndpoint, "loki-endpoint", "", "Static Loki endpoint, as a URL or a host with an optional port, used instead of the discovered one")
+	fs.StringVar(&cfg.TempoEndpoint, "tempo-endpoint", "", "Static Tempo endpoint, as a URL or a host with an optional port, used instead of the discovered one")
+	fs.StringVar(&cfg.LokiPushURL, "loki-push-url", "", "URL the workload clusters push logs to, instead of the one derived from the Loki endpoint")
+	fs.StringVar(&cfg.LokiRulerURL, "loki-ruler-url", "", "URL of the Loki ruler API the workload clusters load rules into, instead of the Loki endpoint")
+	fs.StringVar(&cfg.ManagementClusterLokiPushURL, "management-cluster-loki-push-url", common.DefaultManagementClusterLokiPushURL, "URL the management cluster pushes logs to")
+	fs.StringVar(&cfg.ManagementClusterLokiRulerURL, "management-cluster-loki-ruler-url", common.DefaultManagementClusterLokiRulerURL, "URL of the Loki ruler API the management cluster loads rules into")
+	fs.StringVar(&cfg.ManagementClusterTempoEndpoint, "management-cluster-tempo-endpoint", common.DefaultManagementClusterTempoEndpoint, "Tempo endpoint the management cluster sends traces to, as a URL or a host with an optional port")
+	fs.StringVar(&cfg.AuthMode, "auth-mode", common.AuthModeBasic, "How the clusters authenticate to Loki and Tempo: basic or mtls")
+	fs.StringVar(&cfg.ClientCertificateIssuer, "client-certificate-issuer", "", "cert-manager issuer signing the client certificates of the clusters when the auth mode is mtls")
+	fs.StringVar(&cfg.ClientCertificateIssuerKind, "client-certificate-issuer-kind", common.DefaultClientCertificateIssuerKind, "Kind of the cert-manager issuer signing the client certificates: Issuer or ClusterIssuer")
+	fs.DurationVar(&cfg.ClientCertificateDuration, "client-certificate-duration", common.DefaultClientCertificateDuration, "Validity of the client certificates")
+	fs.DurationVar(&cfg.ClientCertificateRenewBefore, "client-certificate-renew-before", common.DefaultClientCertificateRenewBefore, "How long before their expiry the client certificates are renewed")
+	fs.StringVar(&cfg.ProxyURL, "proxy-url", "", "Proxy the workload clusters send logs and traces through, overridden by the giantswarm.io/logging-proxy cluster annotation")
+	fs.Var((*StringSliceVar)(&cfg.NoProxy), "no-proxy", "List of hosts, domains and CIDR blocks the workload clusters reach without the proxy, in addition to the in-cluster ones")
+	fs.StringVar(&cfg.TemplatesConfigMap, "templates-configmap", "", "Configmap holding replacement templates of the Alloy configurations, rendered from the configuration built by the operator, and of the logging secrets, as <namespace>/<name>")
+	fs.BoolVar(&cfg.RolloutEnabled, "rollout-enabled", false, "Roll configuration changes out to canary clusters first, then to the other clusters in batches")
+	fs.StringVar(&cfg.RolloutCanarySelector, "rollout-canary-selector", "", "Label selector of the canary clusters, used instead of the canary percentage when set")
+	fs.IntVar(&cfg.RolloutCanaryPercentage, "rollout-canary-percentage", rollout.DefaultCanaryPercentage, "Percentage of the clusters used as canaries")
+	fs.IntVar(&cfg.RolloutBatchSize, "rollout-batch-size", rollout.DefaultBatchSize, "Number of clusters updated at the same time once the canaries are healthy")
+	fs.DurationVar(&cfg.RolloutSoakDuration, "rollout-soak-duration", rollout.DefaultSoakDuration, "How long the Alloy workloads of an updated cluster must be ready before it is considered healthy")
+	fs.DurationVar(&cfg.RolloutHealthTimeout, "rollout-health-timeout", rollout.DefaultHealthTimeout, "How long an updated cluster may be unhealthy before the rollout is paused")
+	fs.IntVar(&cfg.RevisionHistoryLimit, "revision-history-limit", revision.DefaultHistoryLimit, "Number of revisions kept for each generated configmap, 0 disables the history")
+}
+
+// newResources returns the resources reconciled for each cluster, according to the feature flags,
+// as well as the logging config resource which is also reconciled on tenant changes.
+func newResources(c client.Client, appConfig config.Config, recorder record.EventRecorder) ([]resource.Interface, loggingconfig.Resource) {
+	// Initialize auth managers for logs and traces
+	// These read cluster passwords from observability-operator managed secrets
+	logsAuthManager := auth.NewAuthManager(
+		c,
+		auth.NewConfig(
+			auth.AuthTypeLogs,
+			"loki",
+			"loki-gateway-ingress-auth",
+			"loki-gateway-httproute-auth",
+		),
+	)
+
+	tracesAuthManager := auth.NewAuthManager(
+		c,
+		auth.NewConfig(
+			auth.AuthTypeTraces,
+			"tempo",
+			"tempo-gateway-ingress-auth",
+			"tempo-gateway-httproute-auth",
+		),
+	)
+
+	clientCertificate := clientcertificate.Resource{
+		Client:   c,
+		Config:   appConfig,
+		Recorder: recorder,
+	}
+
+	loggingSecret := loggingsecret.Resource{
+		Client:            c,
+		Config:            appConfig,
+		Recorder:          recorder,
+		LogsAuthManager:   logsAuthManager,
+		TracesAuthManager: tracesAuthManager,
+	}
+
+	loggingConfig := loggingconfig.Resource{
+		Client:   c,
+		Config:   appConfig,
+		Recorder: recorder,
+	}
+
+	eventsLoggerConfig := eventsloggerconfig.Resource{
+		Client:   c,
+		Config:   appConfig,
+		Recorder: recorder,
+	}
+
+	eventsLoggerSecret := eventsloggersecret.Resource{
+		Client:            c,
+		Config:            appConfig,
+		Recorder:          recorder,
+		LogsAuthManager:   logsAuthManager,
+		TracesAuthManager: tracesAuthManager,
+	}
+
+	// Conditionally add resources based on feature flags
+	var resources []resource.Interface
+	if appConfig.LogsReconciliationEnabled || appConfig.EventsReconciliationEnabled {
+		resources = append(resources, &clientCertificate)
+	}
+	if appConfig.LogsReconciliationEnabled {
+		resources = append(resources, &loggingSecret, &loggingConfig)
+	}
+	if appConfig.EventsReconciliationEnabled {
+		resources = append(resources, &eventsLoggerSecret, &eventsLoggerConfig)
+	}
+
+	return resources, loggingConfig
+}
+
 func main() {
-	var defaultNamespaces StringSliceVar
+	if len(os.Args) > 1 && os.Args[1] == "render" {
+		if err := runRender(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	var appConfig config.Config
 	var enableLeaderElection bool
-	var enableLogging bool
-	var logsReconciliationEnabled bool
-	var eventsReconciliationEnabled bool
-	var enableNodeFiltering bool
-	var enableTracing bool
-	var enableNetworkMonitoring bool
-	var includeEventsFromNamespaces StringSliceVar
-	var excludeEventsFromNamespaces StringSliceVar
-	var installationName string
-	var insecureCA bool
 	var metricsAddr string
 	var profilesAddr string
 	var probeAddr string
-	flag.Var(&defaultNamespaces, "default-namespaces", "List of namespaces to collect logs from by default on workload clusters")
+	var orphanSweepInterval time.Duration
+	var orphanSweepDryRun bool
+	bindConfigFlags(flag.CommandLine, &appConfig)
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
-	flag.BoolVar(&enableLogging, "enable-logging", true, "enable/disable logging for the whole installation")
-	flag.BoolVar(&logsReconciliationEnabled, "logs-reconciliation-enabled", true, "enable/disable alloy logs reconcilers")
-	flag.BoolVar(&eventsReconciliationEnabled, "events-reconciliation-enabled", true, "enable/disable events logging reconcilers")
-	flag.BoolVar(&enableNodeFiltering, "enable-node-filtering", false, "enable/disable node filtering in Alloy logging configuration")
-	flag.BoolVar(&enableTracing, "enable-tracing", false, "enable/disable tracing support for events logger")
-	flag.BoolVar(&enableNetworkMonitoring, "enable-network-monitoring", false, "enable/disable network monitoring for the whole installation")
-	flag.Var(&includeEventsFromNamespaces, "include-events-from-namespaces", "List of namespaces to collect events from on workload clusters (if empty, collect from all namespaces)")
-	flag.Var(&excludeEventsFromNamespaces, "exclude-events-from-namespaces", "List of namespaces to exclude events from on workload clusters")
-	flag.StringVar(&installationName, "installation-name", "unknown", "Name of the installation")
-	flag.BoolVar(&insecureCA, "insecure-ca", false, "Is the management cluter CA insecure?")
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&profilesAddr, "pprof-bind-address", ":6060", "The address the pprof endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
+	flag.DurationVar(&orphanSweepInterval, "orphan-sweep-interval", time.Hour, "Interval between two deletions of the managed objects whose cluster no longer exists, 0 to disable.")
+	flag.BoolVar(&orphanSweepDryRun, "orphan-sweep-dry-run", false, "Only log and count the managed objects whose cluster no longer exists, without deleting them.")
 	opts := zap.Options{
 		Development: false,
 	}
@@ -117,12 +240,44 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	if err := common.ValidateEndpointsConfig(appConfig); err != nil {
+		setupLog.Error(err, "invalid endpoints configuration")
+		os.Exit(1)
+	}
+	if err := common.ValidateCABundleConfig(appConfig); err != nil {
+		setupLog.Error(err, "invalid CA bundle configuration")
+		os.Exit(1)
+	}
+	if err := common.ValidateAuthConfig(appConfig); err != nil {
+		setupLog.Error(err, "invalid auth configuration")
+		os.Exit(1)
+	}
+	if err := common.ValidateProxyConfig(appConfig); err != nil {
+		setupLog.Error(err, "invalid proxy configuration")
+		os.Exit(1)
+	}
+	if err := common.ValidateTemplatesConfig(appConfig); err != nil {
+		setupLog.Error(err, "invalid templates configuration")
+		os.Exit(1)
+	}
+	if err := rollout.ValidateConfig(appConfig); err != nil {
+		setupLog.Error(err, "invalid rollout configuration")
+		os.Exit(1)
+	}
+	if err := revision.ValidateConfig(appConfig); err != nil {
+		setupLog.Error(err, "invalid revision history configuration")
+		os.Exit(1)
+	}
+
 	discardHelmSecretsSelector, err := labels.Parse("owner notin (helm,Helm)")
 	if err != nil {
 		setupLog.Error(err, "failed to parse label selector")
 		os.Exit(1)
 	}
 
+	certificate := &unstructured.Unstructured{}
+	certificate.SetGroupVersionKind(common.CertificateGroupVersionKind)
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme: scheme,
 		Metrics: server.Options{
@@ -143,6 +298,14 @@ func main() {
 		// if you are doing or is intended to do any operation such as perform cleanups
 		// after the manager stops then its usage might be unsafe.
 		// LeaderElectionReleaseOnCancel: true,
+		Client: client.Options{
+			Cache: &client.CacheOptions{
+				// Read the Gateway API HTTPRoutes and Gateways, which are unstructured and watched anyway, from the
+				// cache too. The cert-manager certificates are read directly rather than cached for the whole cluster.
+				Unstructured: true,
+				DisableFor:   []client.Object{certificate},
+			},
+		},
 		Cache: cache.Options{
 			ByObject: map[client.Object]cache.ByObject{
 				&v1.Secret{}: {
@@ -157,81 +320,57 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Create Config for dependency injection
-	appConfig := config.Config{
-		EnableLoggingFlag:           enableLogging,
-		LogsReconciliationEnabled:   logsReconciliationEnabled,
-		EventsReconciliationEnabled: eventsReconciliationEnabled,
-		EnableNodeFilteringFlag:     enableNodeFiltering,
-		EnableTracingFlag:           enableTracing,
-		EnableNetworkMonitoringFlag: enableNetworkMonitoring,
-		InstallationName:            installationName,
-		InsecureCA:                  insecureCA,
-	}
-
-	// Initialize auth managers for logs and traces
-	// These read cluster passwords from observability-operator managed secrets
-	logsAuthManager := auth.NewAuthManager(
-		mgr.GetClient(),
-		auth.NewConfig(
-			auth.AuthTypeLogs,
-			"loki",
-			"loki-gateway-ingress-auth",
-			"loki-gateway-httproute-auth",
-		),
-	)
-
-	tracesAuthManager := auth.NewAuthManager(
-		mgr.GetClient(),
-		auth.NewConfig(
-			auth.AuthTypeTraces,
-			"tempo",
-			"tempo-gateway-ingress-auth",
-			"tempo-gateway-httproute-auth",
-		),
-	)
-
-	loggingSecret := loggingsecret.Resource{
-		Client:            mgr.GetClient(),
-		Config:            appConfig,
-		LogsAuthManager:   logsAuthManager,
-		TracesAuthManager: tracesAuthManager,
+	// Register the logging-operator metrics next to the controller-runtime ones
+	if err := metrics.Register(ctrlmetrics.Registry); err != nil {
+		setupLog.Error(err, "unable to register metrics")
+		os.Exit(1)
 	}
 
-	loggingConfig := loggingconfig.Resource{
-		Client:                           mgr.GetClient(),
-		Config:                           appConfig,
-		DefaultWorkloadClusterNamespaces: defaultNamespaces,
+	// Read clusters in the newest CAPI version served by the API server so that
+	// we do not depend on the CAPI conversion webhooks.
+	capiVersion, err := capicluster.ServedVersion(mgr.GetRESTMapper())
+	if err != nil {
+		setupLog.Error(err, "unable to detect the served CAPI version")
+		os.Exit(1)
 	}
+	capicluster.RegisterConversion(mgr.GetRESTMapper())
+	setupLog.Info("reading CAPI clusters", "version", capiVersion)
 
-	eventsLoggerConfig := eventsloggerconfig.Resource{
-		Client:            mgr.GetClient(),
-		Config:            appConfig,
-		IncludeNamespaces: includeEventsFromNamespaces,
-		ExcludeNamespaces: excludeEventsFromNamespaces,
-	}
+	// Events about the managed objects are emitted on the cluster objects
+	recorder := mgr.GetEventRecorderFor("logging-operator")
 
-	eventsLoggerSecret := eventsloggersecret.Resource{
-		Client:            mgr.GetClient(),
-		Config:            appConfig,
-		LogsAuthManager:   logsAuthManager,
-		TracesAuthManager: tracesAuthManager,
-	}
+	resources, loggingConfig := newResources(mgr.GetClient(), appConfig, recorder)
 
-	// Conditionally add resources based on feature flags
-	var resources []resource.Interface
-	if logsReconciliationEnabled {
-		resources = append(resources, &loggingSecret, &loggingConfig)
-	}
-	if eventsReconciliationEnabled {
-		resources = append(resources, &eventsLoggerSecret, &eventsLoggerConfig)
+	// Roll configuration changes out to the canary clusters first, then to the other clusters in batches
+	var configRollout *rollout.Rollout
+	if appConfig.RolloutEnabled {
+		// The Alloy apps of the reconciled configurations are checked, against the configmap holding their values.
+		apps := map[string]func(*capicluster.Cluster) metav1.ObjectMeta{}
+		if appConfig.LogsReconciliationEnabled {
+			apps[common.AlloyLogAgentAppName] = loggingconfig.ConfigMeta
+		}
+		if appConfig.EventsReconciliationEnabled {
+			apps[common.AlloyEventsLoggerAppName] = eventsloggerconfig.ConfigMeta
+		}
+		configRollout = &rollout.Rollout{
+			Client: mgr.GetClient(),
+			Config: appConfig,
+			Health: &rollout.AlloyHealthChecker{
+				Client: mgr.GetClient(),
+				Config: appConfig,
+				Apps:   apps,
+			},
+		}
 	}
 
 	if err = (&controller.CapiClusterReconciler{
-		Client:    mgr.GetClient(),
-		Scheme:    mgr.GetScheme(),
-		Config:    appConfig,
-		Resources: resources,
+		Client:      mgr.GetClient(),
+		Scheme:      mgr.GetScheme(),
+		Config:      appConfig,
+		Recorder:    recorder,
+		Resources:   resources,
+		CAPIVersion: capiVersion,
+		Rollout:     configRollout,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create CAPI controller", "controller", "Cluster")
 		os.Exit(1)
@@ -239,15 +378,30 @@ func main() {
 
 	// The GrafanaOrganizationReconciler is only used in CAPI mode
 	if err = (&controller.GrafanaOrganizationReconciler{
-		Client:   mgr.GetClient(),
-		Scheme:   mgr.GetScheme(),
-		Resource: loggingConfig,
+		Client:      mgr.GetClient(),
+		Scheme:      mgr.GetScheme(),
+		Resource:    loggingConfig,
+		CAPIVersion: capiVersion,
+		Rollout:     configRollout,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create GrafanaOrganization controller", "controller", "GrafanaOrganization")
 		os.Exit(1)
 	}
 	//+kubebuilder:scaffold:builder
 
+	// Delete the objects left behind when a cluster finalizer is removed out of band
+	if orphanSweepInterval > 0 {
+		if err = mgr.Add(&controller.OrphanSweeper{
+			Client:      mgr.GetClient(),
+			CAPIVersion: capiVersion,
+			Interval:    orphanSweepInterval,
+			DryRun:      orphanSweepDryRun,
+		}); err != nil {
+			setupLog.Error(err, "unable to create orphan sweeper")
+			os.Exit(1)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
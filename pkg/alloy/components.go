@@ -0,0 +1,161 @@
+package alloy
+
+import (
+	"maps"
+	"slices"
+)
+
+// Commented returns the block of the component, or of the stage, preceded by the comment.
+func Commented(comment string, component Component) Block {
+	block := component.Block()
+	block.Comment = comment
+	return block
+}
+
+// ref references an export of the component with the given name and label.
+func ref(name, label, export string) Ref {
+	return Ref{Component: name + "." + label, Export: export}
+}
+
+// Logging configures the logs of Alloy itself.
+type Logging struct {
+	Level  string
+	Format string
+}
+
+func (l Logging) Block() Block {
+	var body Body
+	body.AddString("level", l.Level)
+	body.AddString("format", l.Format)
+	return Block{Name: "logging", Body: body}
+}
+
+// RemoteKubernetesSecret reads a secret of the cluster.
+type RemoteKubernetesSecret struct {
+	Label     string
+	Namespace string
+	Name      string
+}
+
+func (s RemoteKubernetesSecret) Block() Block {
+	var body Body
+	body.AddString("namespace", s.Namespace)
+	body.AddString("name", s.Name)
+	return Block{Name: "remote.kubernetes.secret", Label: s.Label, Body: body}
+}
+
+// Data returns the value of the given key of the secret, which is a secret itself.
+func (s RemoteKubernetesSecret) Data(key string) Expr {
+	return Index{Value: ref("remote.kubernetes.secret", s.Label, "data"), Key: String(key)}
+}
+
+// LocalFileMatch discovers the files matching the path targets.
+type LocalFileMatch struct {
+	Label       string
+	PathTargets []Object
+}
+
+func (m LocalFileMatch) Block() Block {
+	targets := make(List, 0, len(m.PathTargets))
+	for _, target := range m.PathTargets {
+		targets = append(targets, target)
+	}
+
+	var body Body
+	body.Add("path_targets", targets)
+	return Block{Name: "local.file_match", Label: m.Label, Body: body}
+}
+
+// Targets returns the discovered files.
+func (m LocalFileMatch) Targets() Ref {
+	return ref("local.file_match", m.Label, "targets")
+}
+
+// DiscoveryRelabel relabels targets, or only exports its rules to be used by another component when there are none.
+type DiscoveryRelabel struct {
+	Label   string
+	Targets List
+	Rules   []RelabelRule
+}
+
+func (r DiscoveryRelabel) Block() Block {
+	var body Body
+	targets := r.Targets
+	if targets == nil {
+		targets = List{}
+	}
+	body.Add("targets", targets)
+	for _, rule := range r.Rules {
+		body = append(body, rule.block())
+	}
+	return Block{Name: "discovery.relabel", Label: r.Label, Body: body}
+}
+
+// RelabelRules returns the rules of the component.
+func (r DiscoveryRelabel) RelabelRules() Ref {
+	return ref("discovery.relabel", r.Label, "rules")
+}
+
+// RelabelRule is a relabeling rule, shared by the discovery.relabel and loki.relabel components.
+type RelabelRule struct {
+	Comment      string
+	SourceLabels []string
+	Regex        string
+	Replacement  string
+	TargetLabel  string
+	Action       string
+}
+
+func (r RelabelRule) block() Block {
+	var body Body
+	body.AddStrings("source_labels", r.SourceLabels)
+	body.AddString("regex", r.Regex)
+	body.AddString("replacement", r.Replacement)
+	body.AddString("target_label", r.TargetLabel)
+	body.AddString("action", r.Action)
+	return Block{Comment: r.Comment, Name: "rule", Body: body}
+}
+
+// BasicAuth authenticates requests with a username and a password.
+type BasicAuth struct {
+	Username Expr
+	Password Expr
+}
+
+func (a *BasicAuth) addTo(body *Body) {
+	if a == nil {
+		return
+	}
+	var auth Body
+	auth.Add("username", a.Username)
+	auth.Add("password", a.Password)
+	body.AddBlock("basic_auth", auth)
+}
+
+// TLSConfig configures the TLS connections of the Loki clients. The block is omitted when it is empty.
+type TLSConfig struct {
+	InsecureSkipVerify bool
+	CAPEM              Expr
+	CertPEM            Expr
+	KeyPEM             Expr
+}
+
+func (c TLSConfig) addTo(body *Body) {
+	var tls Body
+	tls.AddBool("insecure_skip_verify", c.InsecureSkipVerify)
+	tls.Add("ca_pem", c.CAPEM)
+	tls.Add("cert_pem", c.CertPEM)
+	tls.Add("key_pem", c.KeyPEM)
+	if len(tls) > 0 {
+		body.AddBlock("tls_config", tls)
+	}
+}
+
+// Labels returns an object of the given labels, sorted by name.
+func Labels(labels map[string]string) Object {
+	object := make(Object, 0, len(labels))
+	for _, name := range slices.Sorted(maps.Keys(labels)) {
+		object = append(object, Field{Key: name, Value: String(labels[name])})
+	}
+	return object
+}
@@ -0,0 +1,102 @@
+package alloy
+
+import (
+	"slices"
+
+	"github.com/pkg/errors"
+)
+
+// Component is a top level block of a configuration, usually an Alloy component.
+type Component interface {
+	Block() Block
+}
+
+// Config is an Alloy configuration assembled from components, written in the order they are added.
+type Config struct {
+	blocks Body
+}
+
+// Add appends the components to the configuration.
+func (c *Config) Add(components ...Component) {
+	for _, component := range components {
+		c.blocks = append(c.blocks, component.Block())
+	}
+}
+
+// Build returns the configuration. It fails when a component is declared twice, or references a component
+// which is not declared.
+func (c *Config) Build() (string, error) {
+	declared := map[string]bool{}
+	for _, node := range c.blocks {
+		block := node.(Block)
+		if block.Label == "" {
+			continue
+		}
+		name := block.Name + "." + block.Label
+		if declared[name] {
+			return "", errors.Errorf("component %s is declared twice", name)
+		}
+		declared[name] = true
+	}
+
+	for _, node := range c.blocks {
+		block := node.(Block)
+		for _, ref := range bodyReferences(block.Body) {
+			if !declared[ref] && !slices.Contains(builtinNamespaces, ref) {
+				return "", errors.Errorf("block %s %q references undeclared component %s", block.Name, block.Label, ref)
+			}
+		}
+	}
+
+	var p printer
+	p.body(c.blocks)
+	config := p.String()
+
+	// The printer only writes valid configurations, this catches the invalid identifiers and raw strings.
+	err := Validate(config)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return config, nil
+}
+
+// PrintedComponent is a top level block of a configuration, as written by Build.
+type PrintedComponent struct {
+	// ID is the name of the block followed by its label, like loki.write.default, or its name alone when it has
+	// no label, like logging.
+	ID string
+	// Text is the block and its comment, ending with a newline. Build joins the texts with empty lines.
+	Text string
+}
+
+// Components returns the top level blocks of the configuration in the order they are written, so that a part of the
+// configuration can be replaced. It does not check the configuration, which Build does.
+func (c *Config) Components() []PrintedComponent {
+	components := make([]PrintedComponent, 0, len(c.blocks))
+	for _, node := range c.blocks {
+		block := node.(Block)
+		id := block.Name
+		if block.Label != "" {
+			id += "." + block.Label
+		}
+
+		var p printer
+		p.body(Body{block})
+		components = append(components, PrintedComponent{ID: id, Text: p.String()})
+	}
+	return components
+}
+
+// bodyReferences returns the components referenced by the attributes of the body and its nested blocks.
+func bodyReferences(body Body) []string {
+	var refs []string
+	for _, node := range body {
+		switch n := node.(type) {
+		case Attribute:
+			refs = append(refs, n.Value.references()...)
+		case Block:
+			refs = append(refs, bodyReferences(n.Body)...)
+		}
+	}
+	return refs
+}
@@ -0,0 +1,182 @@
+package alloy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestConfigBuild(t *testing.T) {
+	credentials := RemoteKubernetesSecret{Label: "credentials", Namespace: "kube-system", Name: "alloy-logs"}
+	write := LokiWrite{
+		Label: "default",
+		Endpoints: []LokiEndpoint{{
+			URL:              Nonsensitive(credentials.Data("logging-url")),
+			MaxBackoffPeriod: "10m0s",
+			BasicAuth: &BasicAuth{
+				Username: Nonsensitive(credentials.Data("logging-username")),
+				Password: credentials.Data("logging-password"),
+			},
+		}},
+		ExternalLabels: Labels(map[string]string{"cluster_id": "test", "cluster_type": "workload_cluster"}),
+	}
+	process := LokiProcess{
+		Label:     "kubernetes_pods",
+		ForwardTo: Refs(write.Receiver()),
+		Stages: []Stage{
+			Commented("Parse container runtime interface (CRI) log format", StageCRI{}),
+			StageLabelDrop{Values: []string{"filename", "stream"}},
+		},
+	}
+	source := LokiSourcePodLogs{Label: "kubernetes_pods", ForwardTo: Refs(process.Receiver()), NodeName: Env("NODE_NAME")}
+
+	testCases := []struct {
+		name          string
+		components    []Component
+		expected      string
+		expectedError string
+	}{
+		{
+			name:       "pipeline",
+			components: []Component{Logging{Level: "warn", Format: "logfmt"}, credentials, source, process, Commented("Loki target\nconfiguration", write)},
+			expected: `logging {
+	level  = "warn"
+	format = "logfmt"
+}
+
+remote.kubernetes.secret "credentials" {
+	namespace = "kube-system"
+	name      = "alloy-logs"
+}
+
+loki.source.podlogs "kubernetes_pods" {
+	forward_to = [loki.process.kubernetes_pods.receiver]
+
+	node_filter {
+		enabled   = true
+		node_name = sys.env("NODE_NAME")
+	}
+}
+
+loki.process "kubernetes_pods" {
+	forward_to = [loki.write.default.receiver]
+
+	// Parse container runtime interface (CRI) log format
+	stage.cri { }
+
+	stage.label_drop {
+		values = ["filename", "stream"]
+	}
+}
+
+// Loki target
+// configuration
+loki.write "default" {
+	endpoint {
+		url                = convert.nonsensitive(remote.kubernetes.secret.credentials.data["logging-url"])
+		max_backoff_period = "10m0s"
+
+		basic_auth {
+			username = convert.nonsensitive(remote.kubernetes.secret.credentials.data["logging-username"])
+			password = remote.kubernetes.secret.credentials.data["logging-password"]
+		}
+	}
+
+	external_labels = {
+		cluster_id   = "test",
+		cluster_type = "workload_cluster",
+	}
+}
+`,
+		},
+		{
+			name: "multiline lists",
+			components: []Component{LocalFileMatch{
+				Label:       "files",
+				PathTargets: []Object{{{Key: "__path__", Value: String("/var/log/*.log")}, {Key: "node.name", Value: Env("NODE_NAME")}}},
+			}, Block{
+				Name:  "beyla.ebpf",
+				Label: "default",
+				Body: Body{Attribute{Name: "include", Value: Strings(
+					"k8s.src.namespace", "k8s.src.name", "k8s.src.type", "k8s.dst.namespace", "k8s.dst.name", "k8s.dst.type",
+				)}},
+			}},
+			expected: `local.file_match "files" {
+	path_targets = [{
+		__path__    = "/var/log/*.log",
+		"node.name" = sys.env("NODE_NAME"),
+	}]
+}
+
+beyla.ebpf "default" {
+	include = [
+		"k8s.src.namespace",
+		"k8s.src.name",
+		"k8s.src.type",
+		"k8s.dst.namespace",
+		"k8s.dst.name",
+		"k8s.dst.type",
+	]
+}
+`,
+		},
+		{
+			name:          "undeclared component",
+			components:    []Component{credentials, source, process},
+			expectedError: `block loki.process "kubernetes_pods" references undeclared component loki.write.default`,
+		},
+		{
+			name:          "component declared twice",
+			components:    []Component{credentials, credentials},
+			expectedError: "component remote.kubernetes.secret.credentials is declared twice",
+		},
+		{
+			name:          "invalid label",
+			components:    []Component{RemoteKubernetesSecret{Label: "alloy-logs"}},
+			expectedError: `invalid alloy config at line 1, column 1: block "remote.kubernetes.secret" label "alloy-logs" must be an identifier`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var config Config
+			config.Add(tc.components...)
+
+			out, err := config.Build()
+			if tc.expectedError != "" {
+				if err == nil || err.Error() != tc.expectedError {
+					t.Errorf("expected error %q, got %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, out); diff != "" {
+				t.Errorf("unexpected config, diff:\n%s", diff)
+			}
+
+			var texts []string
+			for _, component := range config.Components() {
+				texts = append(texts, component.Text)
+			}
+			if diff := cmp.Diff(out, strings.Join(texts, "\n")); diff != "" {
+				t.Errorf("components do not join into the config, diff:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConfigComponents(t *testing.T) {
+	var config Config
+	config.Add(Logging{Level: "warn", Format: "logfmt"}, Commented("credentials", RemoteKubernetesSecret{Label: "credentials", Namespace: "kube-system", Name: "alloy-logs"}))
+
+	expected := []PrintedComponent{
+		{ID: "logging", Text: "logging {\n\tlevel  = \"warn\"\n\tformat = \"logfmt\"\n}\n"},
+		{ID: "remote.kubernetes.secret.credentials", Text: "// credentials\nremote.kubernetes.secret \"credentials\" {\n\tnamespace = \"kube-system\"\n\tname      = \"alloy-logs\"\n}\n"},
+	}
+	if diff := cmp.Diff(expected, config.Components()); diff != "" {
+		t.Errorf("unexpected components, diff:\n%s", diff)
+	}
+}
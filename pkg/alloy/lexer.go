@@ -0,0 +1,178 @@
+package alloy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenPunct
+)
+
+func (k tokenKind) String() string {
+	switch k {
+	case tokenEOF:
+		return "end of file"
+	case tokenIdent:
+		return "identifier"
+	case tokenNumber:
+		return "number"
+	case tokenString:
+		return "string"
+	default:
+		return "punctuation"
+	}
+}
+
+// position is a line and column in the configuration, both starting at 1.
+type position struct {
+	line   int
+	column int
+}
+
+func (p position) String() string {
+	return fmt.Sprintf("line %d, column %d", p.line, p.column)
+}
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value string // unquoted value of string tokens
+	pos   position
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return t.kind.String()
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// punctuations are the operators and delimiters of the syntax, longest first.
+var punctuations = []string{
+	"==", "!=", "<=", ">=", "&&", "||",
+	"{", "}", "[", "]", "(", ")", ",", ".", "=", "<", ">", "+", "-", "*", "/", "%", "^", "!",
+}
+
+// lex splits the configuration into tokens, skipping whitespaces and comments.
+func lex(config string) ([]token, error) {
+	var tokens []token
+	pos := position{line: 1, column: 1}
+
+	advance := func(text string) {
+		for _, r := range text {
+			if r == '\n' {
+				pos.line++
+				pos.column = 1
+			} else {
+				pos.column++
+			}
+		}
+		config = config[len(text):]
+	}
+
+	for len(config) > 0 {
+		r, size := utf8.DecodeRuneInString(config)
+		start := pos
+
+		switch {
+		case unicode.IsSpace(r):
+			advance(config[:size])
+
+		case strings.HasPrefix(config, "//"):
+			end := strings.IndexByte(config, '\n')
+			if end < 0 {
+				end = len(config)
+			}
+			advance(config[:end])
+
+		case strings.HasPrefix(config, "/*"):
+			end := strings.Index(config, "*/")
+			if end < 0 {
+				return nil, newError(start, "unterminated block comment")
+			}
+			advance(config[:end+2])
+
+		case r == '_' || unicode.IsLetter(r):
+			end := strings.IndexFunc(config, func(r rune) bool {
+				return r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+			})
+			if end < 0 {
+				end = len(config)
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: config[:end], pos: start})
+			advance(config[:end])
+
+		case unicode.IsDigit(r):
+			end := strings.IndexFunc(config, func(r rune) bool {
+				return r != '.' && r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+			})
+			if end < 0 {
+				end = len(config)
+			}
+			text := config[:end]
+			if _, err := strconv.ParseFloat(strings.ReplaceAll(text, "_", ""), 64); err != nil {
+				return nil, newError(start, "invalid number %q", text)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: text, pos: start})
+			advance(text)
+
+		case r == '"':
+			end := 1
+			for {
+				if end >= len(config) || config[end] == '\n' {
+					return nil, newError(start, "unterminated string")
+				}
+				if config[end] == '\\' {
+					end += 2
+					continue
+				}
+				if config[end] == '"' {
+					break
+				}
+				end++
+			}
+			text := config[:end+1]
+			value, err := strconv.Unquote(text)
+			if err != nil {
+				return nil, newError(start, "invalid string %s", text)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text, value: value, pos: start})
+			advance(text)
+
+		case r == '`':
+			end := strings.IndexByte(config[1:], '`')
+			if end < 0 {
+				return nil, newError(start, "unterminated raw string")
+			}
+			text := config[:end+2]
+			tokens = append(tokens, token{kind: tokenString, text: text, value: text[1 : len(text)-1], pos: start})
+			advance(text)
+
+		default:
+			var punct string
+			for _, p := range punctuations {
+				if strings.HasPrefix(config, p) {
+					punct = p
+					break
+				}
+			}
+			if punct == "" {
+				return nil, newError(start, "unexpected character %q", r)
+			}
+			tokens = append(tokens, token{kind: tokenPunct, text: punct, pos: start})
+			advance(punct)
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: pos}), nil
+}
@@ -0,0 +1,299 @@
+package alloy
+
+// LokiSourcePodLogs collects the logs of the pods selected by the PodLogs resources.
+type LokiSourcePodLogs struct {
+	Label     string
+	ForwardTo List
+	// NodeName restricts the collection to the pods of the node when set. The pods are distributed
+	// between the clustered Alloy instances otherwise.
+	NodeName Expr
+}
+
+func (s LokiSourcePodLogs) Block() Block {
+	var body Body
+	body.Add("forward_to", s.ForwardTo)
+	if s.NodeName != nil {
+		var nodeFilter Body
+		nodeFilter.AddBool("enabled", true)
+		nodeFilter.Add("node_name", s.NodeName)
+		body.AddBlock("node_filter", nodeFilter)
+	} else {
+		var clustering Body
+		clustering.AddBool("enabled", true)
+		body.AddBlock("clustering", clustering)
+	}
+	return Block{Name: "loki.source.podlogs", Label: s.Label, Body: body}
+}
+
+// LokiSourceKubernetesEvents collects the events of the given namespaces, of all namespaces when there are none.
+type LokiSourceKubernetesEvents struct {
+	Label      string
+	Namespaces []string
+	ForwardTo  List
+}
+
+func (s LokiSourceKubernetesEvents) Block() Block {
+	var body Body
+	body.Add("namespaces", Strings(s.Namespaces...))
+	body.Add("forward_to", s.ForwardTo)
+	return Block{Name: "loki.source.kubernetes_events", Label: s.Label, Body: body}
+}
+
+// LokiSourceJournal collects the logs of the systemd journal.
+type LokiSourceJournal struct {
+	Label        string
+	FormatAsJSON bool
+	MaxAge       string
+	Path         string
+	RelabelRules Expr
+	ForwardTo    List
+	Labels       Object
+}
+
+func (s LokiSourceJournal) Block() Block {
+	var body Body
+	body.AddBool("format_as_json", s.FormatAsJSON)
+	body.AddString("max_age", s.MaxAge)
+	body.AddString("path", s.Path)
+	body.Add("relabel_rules", s.RelabelRules)
+	body.Add("forward_to", s.ForwardTo)
+	if len(s.Labels) > 0 {
+		body.Add("labels", s.Labels)
+	}
+	return Block{Name: "loki.source.journal", Label: s.Label, Body: body}
+}
+
+// LokiSourceFile collects the logs of the target files.
+type LokiSourceFile struct {
+	Label               string
+	Targets             Expr
+	ForwardTo           List
+	LegacyPositionsFile string
+}
+
+func (s LokiSourceFile) Block() Block {
+	var body Body
+	body.Add("targets", s.Targets)
+	body.Add("forward_to", s.ForwardTo)
+	body.AddString("legacy_positions_file", s.LegacyPositionsFile)
+	return Block{Name: "loki.source.file", Label: s.Label, Body: body}
+}
+
+// LokiRelabel relabels log entries.
+type LokiRelabel struct {
+	Label     string
+	ForwardTo List
+	Rules     []RelabelRule
+}
+
+func (r LokiRelabel) Block() Block {
+	var body Body
+	body.Add("forward_to", r.ForwardTo)
+	for _, rule := range r.Rules {
+		body = append(body, rule.block())
+	}
+	return Block{Name: "loki.relabel", Label: r.Label, Body: body}
+}
+
+// Receiver returns the receiver log entries are forwarded to.
+func (r LokiRelabel) Receiver() Ref {
+	return ref("loki.relabel", r.Label, "receiver")
+}
+
+// LokiProcess runs log entries through a pipeline of stages.
+type LokiProcess struct {
+	Label     string
+	ForwardTo List
+	Stages    []Stage
+}
+
+func (p LokiProcess) Block() Block {
+	var body Body
+	body.Add("forward_to", p.ForwardTo)
+	for _, stage := range p.Stages {
+		body = append(body, stage.Block())
+	}
+	return Block{Name: "loki.process", Label: p.Label, Body: body}
+}
+
+// Receiver returns the receiver log entries are forwarded to.
+func (p LokiProcess) Receiver() Ref {
+	return ref("loki.process", p.Label, "receiver")
+}
+
+// Stage is a stage of a loki.process pipeline. Stages without a dedicated type are added as blocks.
+type Stage interface {
+	Block() Block
+}
+
+// StageCRI parses the container runtime interface log format.
+type StageCRI struct{}
+
+func (StageCRI) Block() Block {
+	return Block{Name: "stage.cri"}
+}
+
+// StageDrop drops the log entries whose source matches the expression, or equals the value.
+type StageDrop struct {
+	DropCounterReason string
+	Source            string
+	Expression        string
+	Value             string
+}
+
+func (s StageDrop) Block() Block {
+	var body Body
+	body.AddString("drop_counter_reason", s.DropCounterReason)
+	body.AddString("source", s.Source)
+	body.AddString("expression", s.Expression)
+	body.AddString("value", s.Value)
+	return Block{Name: "stage.drop", Body: body}
+}
+
+// StageJSON extracts values from the JSON log line, or from the given extracted value.
+type StageJSON struct {
+	Expressions Object
+	Source      string
+}
+
+func (s StageJSON) Block() Block {
+	var body Body
+	body.Add("expressions", s.Expressions)
+	body.AddString("source", s.Source)
+	return Block{Name: "stage.json", Body: body}
+}
+
+// StageStructuredMetadata moves extracted values or labels to the structured metadata.
+type StageStructuredMetadata struct {
+	Values Object
+}
+
+func (s StageStructuredMetadata) Block() Block {
+	var body Body
+	body.Add("values", s.Values)
+	return Block{Name: "stage.structured_metadata", Body: body}
+}
+
+// StageLabelDrop drops labels.
+type StageLabelDrop struct {
+	Values []string
+}
+
+func (s StageLabelDrop) Block() Block {
+	var body Body
+	body.AddStrings("values", s.Values)
+	return Block{Name: "stage.label_drop", Body: body}
+}
+
+// StageLabels sets labels from extracted values.
+type StageLabels struct {
+	Values Object
+}
+
+func (s StageLabels) Block() Block {
+	var body Body
+	body.Add("values", s.Values)
+	return Block{Name: "stage.labels", Body: body}
+}
+
+// LokiWrite sends log entries to Loki.
+type LokiWrite struct {
+	Label          string
+	Endpoints      []LokiEndpoint
+	ExternalLabels Object
+}
+
+func (w LokiWrite) Block() Block {
+	var body Body
+	for _, endpoint := range w.Endpoints {
+		body.AddBlock("endpoint", endpoint.body())
+	}
+	if len(w.ExternalLabels) > 0 {
+		body.Add("external_labels", w.ExternalLabels)
+	}
+	return Block{Name: "loki.write", Label: w.Label, Body: body}
+}
+
+// Receiver returns the receiver log entries are forwarded to.
+func (w LokiWrite) Receiver() Ref {
+	return ref("loki.write", w.Label, "receiver")
+}
+
+// LokiEndpoint is a Loki log entries are sent to.
+type LokiEndpoint struct {
+	URL              Expr
+	MaxBackoffPeriod string
+	RemoteTimeout    string
+	TenantID         Expr
+	ProxyURL         string
+	NoProxy          string
+	BasicAuth        *BasicAuth
+	TLSConfig        TLSConfig
+}
+
+func (e LokiEndpoint) body() Body {
+	var body Body
+	body.Add("url", e.URL)
+	body.AddString("max_backoff_period", e.MaxBackoffPeriod)
+	body.AddString("remote_timeout", e.RemoteTimeout)
+	body.Add("tenant_id", e.TenantID)
+	body.AddString("proxy_url", e.ProxyURL)
+	body.AddString("no_proxy", e.NoProxy)
+	e.BasicAuth.addTo(&body)
+	e.TLSConfig.addTo(&body)
+	return body
+}
+
+// LokiRulesKubernetes loads the Loki rules of the PrometheusRule resources matching the selector into the ruler.
+type LokiRulesKubernetes struct {
+	Label               string
+	Address             Expr
+	ProxyURL            string
+	NoProxy             string
+	LokiNamespacePrefix string
+	TenantID            string
+	BasicAuth           *BasicAuth
+	TLSConfig           TLSConfig
+	RuleSelector        LabelSelector
+}
+
+func (r LokiRulesKubernetes) Block() Block {
+	var body Body
+	body.Add("address", r.Address)
+	body.AddString("proxy_url", r.ProxyURL)
+	body.AddString("no_proxy", r.NoProxy)
+	body.AddString("loki_namespace_prefix", r.LokiNamespacePrefix)
+	body.AddString("tenant_id", r.TenantID)
+	r.BasicAuth.addTo(&body)
+	r.TLSConfig.addTo(&body)
+	body.AddBlock("rule_selector", r.RuleSelector.body())
+	return Block{Name: "loki.rules.kubernetes", Label: r.Label, Body: body}
+}
+
+// LabelSelector selects Kubernetes resources by labels.
+type LabelSelector struct {
+	MatchLabels      map[string]string
+	MatchExpressions []LabelSelectorRequirement
+}
+
+// LabelSelectorRequirement is a label selector expression.
+type LabelSelectorRequirement struct {
+	Key      string
+	Operator string
+	Values   []string
+}
+
+func (s LabelSelector) body() Body {
+	var body Body
+	if len(s.MatchLabels) > 0 {
+		body.Add("match_labels", Labels(s.MatchLabels))
+	}
+	for _, expression := range s.MatchExpressions {
+		var match Body
+		match.AddString("key", expression.Key)
+		match.AddString("operator", expression.Operator)
+		match.AddStrings("values", expression.Values)
+		body.AddBlock("match_expression", match)
+	}
+	return body
+}
@@ -0,0 +1,215 @@
+package alloy
+
+// OtelcolAuthBasic authenticates the requests of OpenTelemetry exporters with a username and a password.
+type OtelcolAuthBasic struct {
+	Label    string
+	Username Expr
+	Password Expr
+}
+
+func (a OtelcolAuthBasic) Block() Block {
+	var body Body
+	body.Add("username", a.Username)
+	body.Add("password", a.Password)
+	return Block{Name: "otelcol.auth.basic", Label: a.Label, Body: body}
+}
+
+// Handler returns the handler authenticating the requests.
+func (a OtelcolAuthBasic) Handler() Ref {
+	return ref("otelcol.auth.basic", a.Label, "handler")
+}
+
+// OtelcolOutput lists the components the logs, metrics and traces are sent to.
+type OtelcolOutput struct {
+	Logs    List
+	Metrics List
+	Traces  List
+}
+
+func (o OtelcolOutput) addTo(body *Body) {
+	var output Body
+	for _, signal := range []struct {
+		name      string
+		consumers List
+	}{{"logs", o.Logs}, {"metrics", o.Metrics}, {"traces", o.Traces}} {
+		if len(signal.consumers) > 0 {
+			output.Add(signal.name, signal.consumers)
+		}
+	}
+	body.AddBlock("output", output)
+}
+
+// OtelcolReceiverOTLP receives telemetry over OTLP, with gRPC and HTTP.
+type OtelcolReceiverOTLP struct {
+	Label        string
+	GRPCEndpoint string
+	HTTPEndpoint string
+	Output       OtelcolOutput
+}
+
+func (r OtelcolReceiverOTLP) Block() Block {
+	var body Body
+	if r.GRPCEndpoint != "" {
+		var grpc Body
+		grpc.AddString("endpoint", r.GRPCEndpoint)
+		body.AddBlock("grpc", grpc)
+	}
+	if r.HTTPEndpoint != "" {
+		var http Body
+		http.AddString("endpoint", r.HTTPEndpoint)
+		body.AddBlock("http", http)
+	}
+	r.Output.addTo(&body)
+	return Block{Name: "otelcol.receiver.otlp", Label: r.Label, Body: body}
+}
+
+// OtelcolProcessorK8sAttributes adds the Kubernetes metadata of the pod sending the telemetry.
+type OtelcolProcessorK8sAttributes struct {
+	Label           string
+	Metadata        []string
+	Labels          []OtelcolK8sLabel
+	OtelAnnotations bool
+	Output          OtelcolOutput
+}
+
+// OtelcolK8sLabel extracts a label of the pod as the given attribute.
+type OtelcolK8sLabel struct {
+	Key     string
+	TagName string
+}
+
+func (p OtelcolProcessorK8sAttributes) Block() Block {
+	var extract Body
+	extract.AddStrings("metadata", p.Metadata)
+	for _, label := range p.Labels {
+		var body Body
+		body.AddString("key", label.Key)
+		body.AddString("tag_name", label.TagName)
+		extract.AddBlock("label", body)
+	}
+	extract.AddBool("otel_annotations", p.OtelAnnotations)
+
+	var body Body
+	body.AddBlock("extract", extract)
+	p.Output.addTo(&body)
+	return Block{Name: "otelcol.processor.k8sattributes", Label: p.Label, Body: body}
+}
+
+// Input returns the input telemetry is sent to.
+func (p OtelcolProcessorK8sAttributes) Input() Ref {
+	return ref("otelcol.processor.k8sattributes", p.Label, "input")
+}
+
+// OtelcolProcessorTransform modifies telemetry with OpenTelemetry Transformation Language statements.
+type OtelcolProcessorTransform struct {
+	Label           string
+	ErrorMode       string
+	TraceStatements []OtelcolStatements
+	Output          OtelcolOutput
+}
+
+// OtelcolStatements are statements run in the given context, like resource or span.
+type OtelcolStatements struct {
+	Context    string
+	Statements []string
+}
+
+func (p OtelcolProcessorTransform) Block() Block {
+	var body Body
+	body.AddString("error_mode", p.ErrorMode)
+	for _, statements := range p.TraceStatements {
+		var trace Body
+		trace.AddString("context", statements.Context)
+		trace.Add("statements", rawStrings(statements.Statements))
+		body.AddBlock("trace_statements", trace)
+	}
+	p.Output.addTo(&body)
+	return Block{Name: "otelcol.processor.transform", Label: p.Label, Body: body}
+}
+
+// Input returns the input telemetry is sent to.
+func (p OtelcolProcessorTransform) Input() Ref {
+	return ref("otelcol.processor.transform", p.Label, "input")
+}
+
+// OtelcolProcessorFilter drops the spans matching any of the OpenTelemetry Transformation Language conditions.
+type OtelcolProcessorFilter struct {
+	Label  string
+	Spans  []string
+	Output OtelcolOutput
+}
+
+func (p OtelcolProcessorFilter) Block() Block {
+	var traces Body
+	traces.Add("span", rawStrings(p.Spans))
+
+	var body Body
+	body.AddBlock("traces", traces)
+	p.Output.addTo(&body)
+	return Block{Name: "otelcol.processor.filter", Label: p.Label, Body: body}
+}
+
+// Input returns the input telemetry is sent to.
+func (p OtelcolProcessorFilter) Input() Ref {
+	return ref("otelcol.processor.filter", p.Label, "input")
+}
+
+// OtelcolExporterOTLP sends telemetry over OTLP with gRPC.
+type OtelcolExporterOTLP struct {
+	Label  string
+	Client OtelcolClient
+}
+
+// OtelcolClient configures the gRPC client of an exporter.
+type OtelcolClient struct {
+	Endpoint string
+	Auth     Expr
+	Headers  Object
+	TLS      OtelcolTLS
+}
+
+// OtelcolTLS configures the TLS connection of an exporter. The block is omitted when it is empty.
+type OtelcolTLS struct {
+	// Insecure disables TLS.
+	Insecure           bool
+	InsecureSkipVerify bool
+	CAPEM              Expr
+	CertPEM            Expr
+	KeyPEM             Expr
+}
+
+func (e OtelcolExporterOTLP) Block() Block {
+	var client Body
+	client.AddString("endpoint", e.Client.Endpoint)
+	client.Add("auth", e.Client.Auth)
+	if len(e.Client.Headers) > 0 {
+		client.Add("headers", e.Client.Headers)
+	}
+
+	var tls Body
+	tls.AddBool("insecure", e.Client.TLS.Insecure)
+	tls.AddBool("insecure_skip_verify", e.Client.TLS.InsecureSkipVerify)
+	tls.Add("ca_pem", e.Client.TLS.CAPEM)
+	tls.Add("cert_pem", e.Client.TLS.CertPEM)
+	tls.Add("key_pem", e.Client.TLS.KeyPEM)
+	if len(tls) > 0 {
+		client.AddBlock("tls", tls)
+	}
+
+	var body Body
+	body.AddBlock("client", client)
+	return Block{Name: "otelcol.exporter.otlp", Label: e.Label, Body: body}
+}
+
+// Input returns the input telemetry is sent to.
+func (e OtelcolExporterOTLP) Input() Ref {
+	return ref("otelcol.exporter.otlp", e.Label, "input")
+}
+
+func rawStrings(values []string) List {
+	list := make(List, 0, len(values))
+	for _, value := range values {
+		list = append(list, RawString(value))
+	}
+	return list
+}
@@ -0,0 +1,101 @@
+package alloy
+
+import (
+	"strconv"
+	"strings"
+)
+
+// printer writes the configuration with tabs indentation. The equal signs of consecutive attributes are aligned,
+// and nested blocks are separated from the other nodes by an empty line, so that the output only depends
+// on the nodes and not on how they were built.
+type printer struct {
+	out    strings.Builder
+	indent int
+}
+
+func (p *printer) String() string {
+	return p.out.String()
+}
+
+func (p *printer) write(s string) {
+	p.out.WriteString(s)
+}
+
+// newline starts a new line at the current indentation.
+func (p *printer) newline() {
+	p.write("\n" + strings.Repeat("\t", p.indent))
+}
+
+// comment writes the comment above the next node, one // comment per line.
+func (p *printer) comment(comment string) {
+	if comment == "" {
+		return
+	}
+	for line := range strings.SplitSeq(comment, "\n") {
+		p.write(strings.TrimRight("// "+line, " "))
+		p.newline()
+	}
+}
+
+// body writes the nodes, each one followed by a new line.
+func (p *printer) body(body Body) {
+	widths := attributeWidths(body)
+
+	p.write(strings.Repeat("\t", p.indent))
+	for i, node := range body {
+		if i > 0 {
+			_, isBlock := node.(Block)
+			_, previousIsBlock := body[i-1].(Block)
+			if isBlock || previousIsBlock {
+				p.write("\n")
+			}
+			p.write(strings.Repeat("\t", p.indent))
+		}
+
+		switch n := node.(type) {
+		case Attribute:
+			p.comment(n.Comment)
+			p.write(n.Name + strings.Repeat(" ", widths[i]-len(n.Name)) + " = ")
+			n.Value.format(p)
+
+		case Block:
+			p.comment(n.Comment)
+			p.write(n.Name)
+			if n.Label != "" {
+				p.write(" " + strconv.Quote(n.Label))
+			}
+			if len(n.Body) == 0 {
+				p.write(" { }")
+				break
+			}
+			p.write(" {\n")
+			p.indent++
+			p.body(n.Body)
+			p.indent--
+			p.write(strings.Repeat("\t", p.indent) + "}")
+		}
+		p.write("\n")
+	}
+}
+
+// attributeWidths returns, for each attribute of the body, the width of the longest name among its consecutive
+// attributes, which its name is padded to.
+func attributeWidths(body Body) []int {
+	widths := make([]int, len(body))
+	for start := 0; start < len(body); {
+		end := start
+		width := 0
+		for ; end < len(body); end++ {
+			attribute, ok := body[end].(Attribute)
+			if !ok {
+				break
+			}
+			width = max(width, len(attribute.Name))
+		}
+		for i := start; i < end; i++ {
+			widths[i] = width
+		}
+		start = max(end, start+1)
+	}
+	return widths
+}
@@ -0,0 +1,312 @@
+package alloy
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Node is an attribute or a block of a body.
+type Node interface {
+	node()
+}
+
+// Body is the content of a block: attributes and nested blocks, printed in order.
+type Body []Node
+
+// Attribute assigns a value to a name, like url = "https://loki.example.com".
+type Attribute struct {
+	// Comment is printed above the attribute, one // comment per line.
+	Comment string
+	Name    string
+	Value   Expr
+}
+
+// Block is a named block, with a label for components, like loki.write "default" { ... }.
+type Block struct {
+	// Comment is printed above the block, one // comment per line.
+	Comment string
+	Name    string
+	Label   string
+	Body    Body
+}
+
+func (Attribute) node() {}
+func (Block) node()     {}
+
+// Block returns the block itself, so that blocks without a dedicated type can be added to a configuration.
+func (b Block) Block() Block {
+	return b
+}
+
+// Add appends the attribute to the body, unless the value is nil.
+func (b *Body) Add(name string, value Expr) {
+	if value == nil {
+		return
+	}
+	*b = append(*b, Attribute{Name: name, Value: value})
+}
+
+// AddString appends the string attribute to the body, unless the value is empty.
+func (b *Body) AddString(name string, value string) {
+	if value == "" {
+		return
+	}
+	*b = append(*b, Attribute{Name: name, Value: String(value)})
+}
+
+// AddStrings appends the list of strings attribute to the body, unless the list is empty.
+func (b *Body) AddStrings(name string, values []string) {
+	if len(values) == 0 {
+		return
+	}
+	*b = append(*b, Attribute{Name: name, Value: Strings(values...)})
+}
+
+// AddBool appends the boolean attribute to the body, unless it is false.
+func (b *Body) AddBool(name string, value bool) {
+	if !value {
+		return
+	}
+	*b = append(*b, Attribute{Name: name, Value: Bool(value)})
+}
+
+// AddBlock appends the block to the body.
+func (b *Body) AddBlock(name string, body Body) {
+	*b = append(*b, Block{Name: name, Body: body})
+}
+
+// Expr is an expression of the Alloy syntax.
+type Expr interface {
+	// format writes the expression at the current indentation of the printer.
+	format(p *printer)
+	// references returns the names of the components the expression references.
+	references() []string
+}
+
+// String is a double quoted string.
+type String string
+
+// RawString is a backtick quoted string, written as is. It can not contain backticks.
+type RawString string
+
+// Bool is a boolean.
+type Bool bool
+
+// Number is an integer.
+type Number int
+
+// List is a list of expressions, written on one line when short enough.
+type List []Expr
+
+// Object is a list of key and value pairs, written in order.
+type Object []Field
+
+// Field is a key and value pair of an object. Keys which are not identifiers are quoted.
+type Field struct {
+	Key   string
+	Value Expr
+}
+
+// Ref references an export of a component, like loki.write.default.receiver.
+type Ref struct {
+	// Component is the name of the component, followed by its label.
+	Component string
+	Export    string
+}
+
+// Call calls a function of the standard library, like sys.env("NODE_NAME").
+type Call struct {
+	Func string
+	Args []Expr
+}
+
+// Index accesses a key of a map, like remote.kubernetes.secret.credentials.data["logging-url"].
+type Index struct {
+	Value Expr
+	Key   Expr
+}
+
+// Strings returns a list of strings.
+func Strings(values ...string) List {
+	list := make(List, 0, len(values))
+	for _, value := range values {
+		list = append(list, String(value))
+	}
+	return list
+}
+
+// Refs returns a list of references, like the forward_to list of a component.
+func Refs(refs ...Ref) List {
+	list := make(List, 0, len(refs))
+	for _, ref := range refs {
+		list = append(list, ref)
+	}
+	return list
+}
+
+// Nonsensitive converts a secret to a string, for the attributes which do not accept secrets.
+func Nonsensitive(value Expr) Expr {
+	return Call{Func: "convert.nonsensitive", Args: []Expr{value}}
+}
+
+// Env returns the value of an environment variable of the Alloy container.
+func Env(name string) Expr {
+	return Call{Func: "sys.env", Args: []Expr{String(name)}}
+}
+
+func (s String) format(p *printer) {
+	p.write(strconv.Quote(string(s)))
+}
+
+func (s RawString) format(p *printer) {
+	p.write("`" + string(s) + "`")
+}
+
+func (b Bool) format(p *printer) {
+	p.write(strconv.FormatBool(bool(b)))
+}
+
+func (n Number) format(p *printer) {
+	p.write(strconv.Itoa(int(n)))
+}
+
+func (l List) format(p *printer) {
+	switch {
+	case len(l) == 0:
+		p.write("[]")
+
+	// A single object is written without a line for the brackets.
+	case len(l) == 1 && isObject(l[0]):
+		p.write("[")
+		l[0].format(p)
+		p.write("]")
+
+	case fitsOnLine(l):
+		p.write("[")
+		for i, item := range l {
+			if i > 0 {
+				p.write(", ")
+			}
+			item.format(p)
+		}
+		p.write("]")
+
+	default:
+		p.write("[")
+		p.indent++
+		for _, item := range l {
+			p.newline()
+			item.format(p)
+			p.write(",")
+		}
+		p.indent--
+		p.newline()
+		p.write("]")
+	}
+}
+
+func (o Object) format(p *printer) {
+	if len(o) == 0 {
+		p.write("{}")
+		return
+	}
+
+	keys := make([]string, 0, len(o))
+	width := 0
+	for _, field := range o {
+		key := field.Key
+		if !isIdentifier(key) {
+			key = strconv.Quote(key)
+		}
+		keys = append(keys, key)
+		width = max(width, len(key))
+	}
+
+	p.write("{")
+	p.indent++
+	for i, field := range o {
+		p.newline()
+		p.write(keys[i] + strings.Repeat(" ", width-len(keys[i])) + " = ")
+		field.Value.format(p)
+		p.write(",")
+	}
+	p.indent--
+	p.newline()
+	p.write("}")
+}
+
+func (r Ref) format(p *printer) {
+	p.write(r.Component + "." + r.Export)
+}
+
+func (c Call) format(p *printer) {
+	p.write(c.Func + "(")
+	for i, arg := range c.Args {
+		if i > 0 {
+			p.write(", ")
+		}
+		arg.format(p)
+	}
+	p.write(")")
+}
+
+func (i Index) format(p *printer) {
+	i.Value.format(p)
+	p.write("[")
+	i.Key.format(p)
+	p.write("]")
+}
+
+func (String) references() []string    { return nil }
+func (RawString) references() []string { return nil }
+func (Bool) references() []string      { return nil }
+func (Number) references() []string    { return nil }
+
+func (l List) references() []string {
+	var refs []string
+	for _, item := range l {
+		refs = append(refs, item.references()...)
+	}
+	return refs
+}
+
+func (o Object) references() []string {
+	var refs []string
+	for _, field := range o {
+		refs = append(refs, field.Value.references()...)
+	}
+	return refs
+}
+
+func (r Ref) references() []string {
+	return []string{r.Component}
+}
+
+func (c Call) references() []string {
+	return List(c.Args).references()
+}
+
+func (i Index) references() []string {
+	return append(i.Value.references(), i.Key.references()...)
+}
+
+func isObject(e Expr) bool {
+	o, ok := e.(Object)
+	return ok && len(o) > 0
+}
+
+// maxLineListWidth is the width above which lists are written one item per line.
+const maxLineListWidth = 100
+
+// fitsOnLine returns true if the list is short enough to be written on one line.
+func fitsOnLine(l List) bool {
+	var p printer
+	for i, item := range l {
+		if i > 0 {
+			p.write(", ")
+		}
+		item.format(&p)
+	}
+	out := p.String()
+	return !strings.Contains(out, "\n") && len(out) <= maxLineListWidth
+}
@@ -0,0 +1,329 @@
+package alloy
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Error is a syntax or reference error in an Alloy configuration.
+type Error struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid alloy config at line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+func newError(pos position, format string, args ...any) *Error {
+	return &Error{Line: pos.line, Column: pos.column, Message: fmt.Sprintf(format, args...)}
+}
+
+// builtinNamespaces are the identifiers which can be referenced without declaring a component.
+var builtinNamespaces = []string{"argument", "constants", "sys"}
+
+// literals are the keywords evaluating to a value.
+var literals = []string{"true", "false", "null"}
+
+// Validate checks the syntax of the given Alloy configuration: block structure, attributes,
+// quoting and expressions. It also checks that component references, like loki.write.default.receiver,
+// resolve to components declared in the configuration.
+func Validate(config string) error {
+	tokens, err := lex(config)
+	if err != nil {
+		return err
+	}
+
+	p := &parser{tokens: tokens, components: map[string]position{}}
+	err = p.parseBody(true)
+	if err != nil {
+		return err
+	}
+
+	for _, ref := range p.references {
+		if !p.resolves(ref.parts) {
+			return newError(ref.pos, "reference to undeclared component %q", strings.Join(ref.parts, "."))
+		}
+	}
+	return nil
+}
+
+type reference struct {
+	parts []string
+	pos   position
+}
+
+type parser struct {
+	tokens     []token
+	current    int
+	components map[string]position
+	references []reference
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.current]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.current]
+	if t.kind != tokenEOF {
+		p.current++
+	}
+	return t
+}
+
+func (p *parser) isPunct(text string) bool {
+	t := p.peek()
+	return t.kind == tokenPunct && t.text == text
+}
+
+func (p *parser) expectPunct(text string) error {
+	t := p.next()
+	if t.kind != tokenPunct || t.text != text {
+		return newError(t.pos, "expected %q, got %s", text, t)
+	}
+	return nil
+}
+
+// parseBody parses attributes and blocks until the end of the file for the top level body,
+// or until the closing brace of a block.
+func (p *parser) parseBody(topLevel bool) error {
+	for {
+		t := p.peek()
+		switch {
+		case t.kind == tokenEOF && topLevel:
+			return nil
+		case t.kind == tokenEOF:
+			return newError(t.pos, "unexpected end of file, missing \"}\"")
+		case p.isPunct("}") && !topLevel:
+			return nil
+		case t.kind != tokenIdent:
+			return newError(t.pos, "expected attribute or block name, got %s", t)
+		}
+
+		name := []string{p.next().text}
+		for p.isPunct(".") {
+			p.next()
+			part := p.next()
+			if part.kind != tokenIdent {
+				return newError(part.pos, "expected identifier after \".\" in block name, got %s", part)
+			}
+			name = append(name, part.text)
+		}
+
+		switch {
+		case p.isPunct("="):
+			if len(name) > 1 {
+				return newError(t.pos, "attribute name %q must be a single identifier", strings.Join(name, "."))
+			}
+			p.next()
+			err := p.parseExpression()
+			if err != nil {
+				return err
+			}
+
+		case p.peek().kind == tokenString || p.isPunct("{"):
+			var label string
+			if p.peek().kind == tokenString {
+				label = p.next().value
+				if label == "" {
+					return newError(t.pos, "block %q has an empty label", strings.Join(name, "."))
+				}
+				if !isIdentifier(label) {
+					return newError(t.pos, "block %q label %q must be an identifier", strings.Join(name, "."), label)
+				}
+			}
+			if err := p.expectPunct("{"); err != nil {
+				return err
+			}
+			if err := p.parseBody(false); err != nil {
+				return err
+			}
+			if err := p.expectPunct("}"); err != nil {
+				return err
+			}
+
+			if topLevel && label != "" {
+				component := strings.Join(append(name, label), ".")
+				if declared, ok := p.components[component]; ok {
+					return newError(t.pos, "component %q is already declared at %s", component, declared)
+				}
+				p.components[component] = t.pos
+			}
+
+		default:
+			return newError(p.peek().pos, "expected \"=\" or \"{\" after %q, got %s", strings.Join(name, "."), p.peek())
+		}
+	}
+}
+
+// binaryOperators lists the binary operators by increasing precedence.
+var binaryOperators = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+	{"^"},
+}
+
+func (p *parser) parseExpression() error {
+	return p.parseBinary(0)
+}
+
+func (p *parser) parseBinary(level int) error {
+	if level == len(binaryOperators) {
+		return p.parseUnary()
+	}
+
+	err := p.parseBinary(level + 1)
+	if err != nil {
+		return err
+	}
+	for p.peek().kind == tokenPunct && slices.Contains(binaryOperators[level], p.peek().text) {
+		p.next()
+		err = p.parseBinary(level + 1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *parser) parseUnary() error {
+	if p.isPunct("!") || p.isPunct("-") {
+		p.next()
+		return p.parseUnary()
+	}
+	return p.parsePostfix()
+}
+
+// parsePostfix parses a primary expression followed by field accesses, indexes and calls.
+// Identifier chains which are not called are recorded as component references.
+func (p *parser) parsePostfix() error {
+	t := p.peek()
+	var chain []string
+	if t.kind == tokenIdent && !slices.Contains(literals, t.text) {
+		chain = []string{t.text}
+	}
+
+	err := p.parsePrimary()
+	if err != nil {
+		return err
+	}
+
+	// Only the identifiers before the first index or call are part of the reference.
+	inChain := chain != nil
+	called := false
+	for {
+		switch {
+		case p.isPunct("."):
+			p.next()
+			field := p.next()
+			if field.kind != tokenIdent {
+				return newError(field.pos, "expected field name after \".\", got %s", field)
+			}
+			if inChain {
+				chain = append(chain, field.text)
+			}
+		case p.isPunct("["):
+			p.next()
+			if err := p.parseExpression(); err != nil {
+				return err
+			}
+			if err := p.expectPunct("]"); err != nil {
+				return err
+			}
+			inChain = false
+		case p.isPunct("("):
+			p.next()
+			if err := p.parseList(")"); err != nil {
+				return err
+			}
+			if inChain {
+				called = true
+			}
+			inChain = false
+		default:
+			if chain != nil && !called {
+				p.references = append(p.references, reference{parts: chain, pos: t.pos})
+			}
+			return nil
+		}
+	}
+}
+
+func (p *parser) parsePrimary() error {
+	t := p.next()
+	switch {
+	case t.kind == tokenIdent, t.kind == tokenNumber, t.kind == tokenString:
+		return nil
+	case t.kind == tokenPunct && t.text == "(":
+		if err := p.parseExpression(); err != nil {
+			return err
+		}
+		return p.expectPunct(")")
+	case t.kind == tokenPunct && t.text == "[":
+		return p.parseList("]")
+	case t.kind == tokenPunct && t.text == "{":
+		return p.parseObject()
+	default:
+		return newError(t.pos, "expected expression, got %s", t)
+	}
+}
+
+// parseList parses comma separated expressions, with an optional trailing comma, until the given closing delimiter.
+func (p *parser) parseList(closing string) error {
+	for !p.isPunct(closing) {
+		if err := p.parseExpression(); err != nil {
+			return err
+		}
+		if !p.isPunct(",") {
+			break
+		}
+		p.next()
+	}
+	return p.expectPunct(closing)
+}
+
+// parseObject parses the comma separated key = value pairs of an object, until the closing brace.
+func (p *parser) parseObject() error {
+	for !p.isPunct("}") {
+		key := p.next()
+		if key.kind != tokenIdent && key.kind != tokenString {
+			return newError(key.pos, "expected object key, got %s", key)
+		}
+		if err := p.expectPunct("="); err != nil {
+			return err
+		}
+		if err := p.parseExpression(); err != nil {
+			return err
+		}
+		if !p.isPunct(",") {
+			break
+		}
+		p.next()
+	}
+	return p.expectPunct("}")
+}
+
+// isIdentifier returns true if the given text is a valid identifier: a letter or underscore followed by letters, digits or underscores.
+func isIdentifier(text string) bool {
+	tokens, err := lex(text)
+	return err == nil && len(tokens) == 2 && tokens[0].kind == tokenIdent && tokens[0].text == text
+}
+
+// resolves returns true if the reference targets a builtin or a declared component.
+func (p *parser) resolves(parts []string) bool {
+	if slices.Contains(builtinNamespaces, parts[0]) {
+		return true
+	}
+	for i := len(parts); i > 0; i-- {
+		if _, ok := p.components[strings.Join(parts[:i], ".")]; ok {
+			return true
+		}
+	}
+	return false
+}
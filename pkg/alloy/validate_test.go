@@ -0,0 +1,152 @@
+package alloy
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name          string
+		config        string
+		expectedError string
+	}{
+		{
+			name: "valid config",
+			config: `// comment
+logging {
+	level  = "warn"
+	format = "logfmt"
+}
+
+/* block
+   comment */
+remote.kubernetes.secret "credentials" {
+	namespace = "kube-system"
+	name = "alloy-logs"
+}
+
+loki.source.podlogs "kubernetes_pods" {
+	forward_to = [loki.write.default.receiver]
+	node_filter {
+		enabled = true
+		node_name = sys.env("NODE_NAME")
+	}
+}
+
+loki.write "default" {
+	endpoint {
+		url = convert.nonsensitive(remote.kubernetes.secret.credentials.data["logging-url"])
+		max_backoff_period = "10m0s"
+	}
+	external_labels = {
+		cluster_id = "test",
+		"quoted.key" = constants.hostname,
+	}
+}
+
+otelcol.processor.transform "default" {
+	trace_statements {
+		statements = [
+			` + "`set(attributes[\"giantswarm.cluster.id\"], \"test\")`" + `,
+		]
+	}
+	output {
+		traces = [otelcol.exporter.otlp.giantswarm.input]
+	}
+}
+
+otelcol.exporter.otlp "giantswarm" {
+	client {
+		endpoint = "tempo:4317"
+		retries = -1 + 2 * 3
+		enabled = !false && (1 < 2 || 3 >= 4)
+	}
+}
+`,
+		},
+		{
+			name:          "unterminated string",
+			config:        "logging {\n\tlevel = \"warn\n}\n",
+			expectedError: "invalid alloy config at line 2, column 10: unterminated string",
+		},
+		{
+			name:          "quote in label",
+			config:        "loki.rules.kubernetes \"tenant\"a\" {\n}\n",
+			expectedError: "invalid alloy config at line 1, column 32: unterminated string",
+		},
+		{
+			name:          "invalid escape",
+			config:        "logging {\n\tlevel = \"\\q\"\n}\n",
+			expectedError: "invalid alloy config at line 2, column 10: invalid string \"\\q\"",
+		},
+		{
+			name:          "missing closing brace",
+			config:        "logging {\n\tlevel = \"warn\"\n",
+			expectedError: "invalid alloy config at line 3, column 1: unexpected end of file, missing \"}\"",
+		},
+		{
+			name:          "unexpected closing brace",
+			config:        "logging {\n}\n}\n",
+			expectedError: "invalid alloy config at line 3, column 1: expected attribute or block name, got \"}\"",
+		},
+		{
+			name:          "missing value",
+			config:        "logging {\n\tlevel =\n}\n",
+			expectedError: "invalid alloy config at line 3, column 1: expected expression, got \"}\"",
+		},
+		{
+			name:          "dotted attribute name",
+			config:        "logging {\n\tlog.level = \"warn\"\n}\n",
+			expectedError: "invalid alloy config at line 2, column 2: attribute name \"log.level\" must be a single identifier",
+		},
+		{
+			name:          "empty label",
+			config:        "loki.write \"\" {\n}\n",
+			expectedError: "invalid alloy config at line 1, column 1: block \"loki.write\" has an empty label",
+		},
+		{
+			name:          "label not an identifier",
+			config:        "loki.write \"test-tenant\" {\n}\n",
+			expectedError: "invalid alloy config at line 1, column 1: block \"loki.write\" label \"test-tenant\" must be an identifier",
+		},
+		{
+			name:          "undeclared component",
+			config:        "loki.source.podlogs \"pods\" {\n\tforward_to = [loki.write.default.receiver]\n}\n",
+			expectedError: "invalid alloy config at line 2, column 16: reference to undeclared component \"loki.write.default.receiver\"",
+		},
+		{
+			name:          "duplicate component",
+			config:        "loki.write \"default\" {\n}\nloki.write \"default\" {\n}\n",
+			expectedError: "invalid alloy config at line 3, column 1: component \"loki.write.default\" is already declared at line 1, column 1",
+		},
+		{
+			name:          "unterminated array",
+			config:        "loki.write \"default\" {\n}\nloki.source.podlogs \"pods\" {\n\tforward_to = [loki.write.default.receiver\n}\n",
+			expectedError: "invalid alloy config at line 5, column 1: expected \"]\", got \"}\"",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(tc.config)
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("expected error %q, got none", tc.expectedError)
+			}
+			var alloyErr *Error
+			if !errors.As(err, &alloyErr) {
+				t.Errorf("expected an *Error, got %T", err)
+			}
+			if err.Error() != tc.expectedError {
+				t.Errorf("expected error %q, got %q", tc.expectedError, err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,349 @@
+// Package alloyvalues models the values of the Alloy Helm chart the logs agent and the events logger are
+// deployed with, so that they are built as Go structs and marshalled rather than templated as text.
+package alloyvalues
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.yaml.in/yaml/v3"
+)
+
+// ConfigHashAnnotation is the annotation of the Alloy pods holding the hash of their Alloy configuration. Alloy
+// reloads its configuration without restarting: the annotation rolls the pods out when the configuration changes,
+// so that the daemonset or deployment tells which configuration its pods run.
+const ConfigHashAnnotation = "logging.giantswarm.io/config-hash"
+
+// Values are the values of the Alloy Helm chart, as wrapped by the Giant Swarm alloy app.
+type Values struct {
+	NetworkPolicy           *NetworkPolicy           `yaml:"networkPolicy,omitempty"`
+	Alloy                   Chart                    `yaml:"alloy"`
+	VerticalPodAutoscaler   *VerticalPodAutoscaler   `yaml:"verticalPodAutoscaler,omitempty"`
+	PodLogs                 []PodLogs                `yaml:"podLogs,omitempty"`
+	KyvernoPolicyExceptions *KyvernoPolicyExceptions `yaml:"kyvernoPolicyExceptions,omitempty"`
+}
+
+// NetworkPolicy is the Cilium network policy of the Alloy pods.
+type NetworkPolicy struct {
+	Cilium           CiliumNetworkPolicy `yaml:"cilium"`
+	EndpointSelector *LabelSelector      `yaml:"endpointSelector,omitempty"`
+}
+
+// CiliumNetworkPolicy lists the egress and ingress rules of the network policy.
+type CiliumNetworkPolicy struct {
+	Egress  []CiliumRule `yaml:"egress,omitempty"`
+	Ingress []CiliumRule `yaml:"ingress,omitempty"`
+}
+
+// CiliumRule allows traffic to the given entities, endpoints, CIDR blocks or FQDNs on the given ports.
+type CiliumRule struct {
+	ToEntities  []string        `yaml:"toEntities,omitempty"`
+	ToEndpoints []LabelSelector `yaml:"toEndpoints,omitempty"`
+	ToCIDR      []string        `yaml:"toCIDR,omitempty"`
+	ToFQDNs     []FQDNSelector  `yaml:"toFQDNs,omitempty"`
+	ToPorts     []PortRule      `yaml:"toPorts,omitempty"`
+}
+
+// FQDNSelector selects a host by name.
+type FQDNSelector struct {
+	MatchName string `yaml:"matchName"`
+}
+
+// PortRule lists ports, and the L7 rules applied to their traffic.
+type PortRule struct {
+	Ports []PortProtocol `yaml:"ports"`
+	Rules *L7Rules       `yaml:"rules,omitempty"`
+}
+
+// PortProtocol is a port and its protocol: TCP, UDP or ANY.
+type PortProtocol struct {
+	Port     string `yaml:"port"`
+	Protocol string `yaml:"protocol"`
+}
+
+// L7Rules are the L7 rules of a port rule.
+type L7Rules struct {
+	DNS []DNSRule `yaml:"dns"`
+}
+
+// DNSRule allows the DNS queries matching the pattern, and gives Cilium visibility on them.
+type DNSRule struct {
+	MatchPattern string `yaml:"matchPattern"`
+}
+
+// LabelSelector selects objects by labels. An empty selector selects all objects.
+type LabelSelector struct {
+	MatchLabels      map[string]string          `yaml:"matchLabels,omitempty"`
+	MatchExpressions []LabelSelectorRequirement `yaml:"matchExpressions,omitempty"`
+}
+
+// LabelSelectorRequirement is a label selector expression.
+type LabelSelectorRequirement struct {
+	Key      string   `yaml:"key"`
+	Operator string   `yaml:"operator"`
+	Values   []string `yaml:"values,omitempty"`
+}
+
+// Chart are the values of the upstream Alloy chart.
+type Chart struct {
+	Alloy        Alloy            `yaml:"alloy"`
+	Controller   Controller       `yaml:"controller"`
+	CRDs         *CRDs            `yaml:"crds,omitempty"`
+	Image        *Image           `yaml:"image,omitempty"`
+	ExtraObjects []map[string]any `yaml:"extraObjects,omitempty"`
+}
+
+// Alloy configures the Alloy container.
+type Alloy struct {
+	ConfigMap       ConfigMap       `yaml:"configMap"`
+	Clustering      *Clustering     `yaml:"clustering,omitempty"`
+	ExtraEnv        []EnvVar        `yaml:"extraEnv,omitempty"`
+	Mounts          *Mounts         `yaml:"mounts,omitempty"`
+	Resources       Resources       `yaml:"resources"`
+	SecurityContext SecurityContext `yaml:"securityContext"`
+}
+
+// ConfigMap is the configmap holding the Alloy configuration, created by the chart.
+type ConfigMap struct {
+	Create  bool   `yaml:"create"`
+	Content string `yaml:"content"`
+}
+
+// NewConfigMap returns a configmap created by the chart with the given Alloy configuration.
+func NewConfigMap(config string) ConfigMap {
+	return ConfigMap{
+		Create:  true,
+		Content: strings.TrimSpace(config),
+	}
+}
+
+// Clustering configures the clustering of the Alloy instances.
+type Clustering struct {
+	Enabled bool   `yaml:"enabled"`
+	Name    string `yaml:"name"`
+}
+
+// EnvVar is an environment variable of the Alloy container.
+type EnvVar struct {
+	Name      string        `yaml:"name"`
+	Value     string        `yaml:"value,omitempty"`
+	ValueFrom *EnvVarSource `yaml:"valueFrom,omitempty"`
+}
+
+// EnvVarSource is the source of the value of an environment variable.
+type EnvVarSource struct {
+	FieldRef *ObjectFieldSelector `yaml:"fieldRef,omitempty"`
+}
+
+// ObjectFieldSelector selects a field of the pod.
+type ObjectFieldSelector struct {
+	FieldPath string `yaml:"fieldPath"`
+}
+
+// Mounts lists the host directories mounted in the Alloy container.
+type Mounts struct {
+	Varlog           bool          `yaml:"varlog"`
+	DockerContainers bool          `yaml:"dockercontainers"`
+	Extra            []VolumeMount `yaml:"extra,omitempty"`
+}
+
+// VolumeMount mounts an extra volume in the Alloy container.
+type VolumeMount struct {
+	Name      string `yaml:"name"`
+	MountPath string `yaml:"mountPath"`
+	ReadOnly  bool   `yaml:"readOnly,omitempty"`
+}
+
+// Resources are the resource requests and limits of the Alloy container.
+type Resources struct {
+	Limits   map[string]string `yaml:"limits"`
+	Requests map[string]string `yaml:"requests"`
+}
+
+// SecurityContext is the security context of the Alloy container.
+type SecurityContext struct {
+	AllowPrivilegeEscalation bool          `yaml:"allowPrivilegeEscalation"`
+	AppArmorProfile          *Profile      `yaml:"appArmorProfile,omitempty"`
+	Capabilities             *Capabilities `yaml:"capabilities,omitempty"`
+	Privileged               bool          `yaml:"privileged,omitempty"`
+	ReadOnlyRootFilesystem   bool          `yaml:"readOnlyRootFilesystem"`
+	RunAsUser                int64         `yaml:"runAsUser"`
+	RunAsGroup               int64         `yaml:"runAsGroup"`
+	RunAsNonRoot             bool          `yaml:"runAsNonRoot"`
+	SeccompProfile           *Profile      `yaml:"seccompProfile,omitempty"`
+}
+
+// Profile is an AppArmor or seccomp profile.
+type Profile struct {
+	Type string `yaml:"type"`
+}
+
+// Capabilities are the capabilities added to and dropped from the Alloy container.
+type Capabilities struct {
+	Add  []string `yaml:"add,omitempty"`
+	Drop []string `yaml:"drop"`
+}
+
+// Controller configures the daemonset or deployment running Alloy.
+type Controller struct {
+	Type              string            `yaml:"type"`
+	Replicas          int               `yaml:"replicas,omitempty"`
+	HostPID           bool              `yaml:"hostPID,omitempty"`
+	HostNetwork       bool              `yaml:"hostNetwork,omitempty"`
+	PriorityClassName string            `yaml:"priorityClassName,omitempty"`
+	Tolerations       []Toleration      `yaml:"tolerations,omitempty"`
+	Volumes           *Volumes          `yaml:"volumes,omitempty"`
+	PodAnnotations    map[string]string `yaml:"podAnnotations,omitempty"`
+}
+
+// ConfigPodAnnotations returns the pod annotations holding the hash of the given Alloy configuration.
+func ConfigPodAnnotations(config string) map[string]string {
+	hash := sha256.Sum256([]byte(strings.TrimSpace(config)))
+	return map[string]string{ConfigHashAnnotation: hex.EncodeToString(hash[:])}
+}
+
+// ConfigHash returns the hash of the Alloy configuration the given YAML values annotate the pods with,
+// empty when they do not.
+func ConfigHash(values string) (string, error) {
+	var v struct {
+		Alloy struct {
+			Controller struct {
+				PodAnnotations map[string]string `yaml:"podAnnotations"`
+			} `yaml:"controller"`
+		} `yaml:"alloy"`
+	}
+	err := yaml.Unmarshal([]byte(values), &v)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return v.Alloy.Controller.PodAnnotations[ConfigHashAnnotation], nil
+}
+
+// Toleration is a toleration of the Alloy pods.
+type Toleration struct {
+	Effect   string `yaml:"effect"`
+	Key      string `yaml:"key"`
+	Operator string `yaml:"operator"`
+}
+
+// Volumes lists the extra volumes of the Alloy pods.
+type Volumes struct {
+	Extra []Volume `yaml:"extra"`
+}
+
+// Volume is a host path or empty dir volume.
+type Volume struct {
+	Name     string    `yaml:"name"`
+	HostPath *HostPath `yaml:"hostPath,omitempty"`
+	EmptyDir *EmptyDir `yaml:"emptyDir,omitempty"`
+}
+
+// HostPath is a directory of the host.
+type HostPath struct {
+	Path string `yaml:"path"`
+}
+
+// EmptyDir is an empty directory sharing the lifetime of the pod.
+type EmptyDir struct{}
+
+// CRDs configures the installation of the Alloy CRDs by the chart.
+type CRDs struct {
+	Create bool `yaml:"create"`
+}
+
+// Image overrides the Alloy image.
+type Image struct {
+	Tag string `yaml:"tag"`
+}
+
+// VerticalPodAutoscaler configures the vertical pod autoscaler of the Alloy pods.
+type VerticalPodAutoscaler struct {
+	Enabled        bool           `yaml:"enabled"`
+	ResourcePolicy ResourcePolicy `yaml:"resourcePolicy"`
+}
+
+// ResourcePolicy lists the resource policies of the containers.
+type ResourcePolicy struct {
+	ContainerPolicies []ContainerPolicy `yaml:"containerPolicies"`
+}
+
+// ContainerPolicy bounds the resources the vertical pod autoscaler sets on a container.
+type ContainerPolicy struct {
+	ContainerName       string            `yaml:"containerName"`
+	ControlledResources []string          `yaml:"controlledResources"`
+	ControlledValues    string            `yaml:"controlledValues"`
+	MaxAllowed          map[string]string `yaml:"maxAllowed,omitempty"`
+}
+
+// PodLogs selects the pods whose logs are collected, and relabels them.
+type PodLogs struct {
+	Name      string      `yaml:"name"`
+	Namespace string      `yaml:"namespace"`
+	Spec      PodLogsSpec `yaml:"spec"`
+}
+
+// PodLogsSpec is the spec of a PodLogs.
+type PodLogsSpec struct {
+	Selector          LabelSelector   `yaml:"selector"`
+	NamespaceSelector LabelSelector   `yaml:"namespaceSelector"`
+	Relabelings       []RelabelConfig `yaml:"relabelings"`
+}
+
+// RelabelConfig is a relabeling rule applied to the collected logs.
+type RelabelConfig struct {
+	Action       string   `yaml:"action"`
+	SourceLabels []string `yaml:"sourceLabels,omitempty"`
+	TargetLabel  string   `yaml:"targetLabel"`
+	Replacement  string   `yaml:"replacement,omitempty"`
+}
+
+// KyvernoPolicyExceptions configures the Kyverno policy exceptions of the Alloy pods.
+type KyvernoPolicyExceptions struct {
+	Enabled    bool              `yaml:"enabled"`
+	Namespace  string            `yaml:"namespace"`
+	Exceptions []PolicyException `yaml:"exceptions"`
+}
+
+// PolicyException excepts the Alloy pods from the rules of a Kyverno policy.
+type PolicyException struct {
+	PolicyName string   `yaml:"policyName"`
+	RuleNames  []string `yaml:"ruleNames"`
+}
+
+// Marshal returns the values as YAML, preceded by the given header comment.
+func (v Values) Marshal(header string) (string, error) {
+	out, err := encode(v)
+	if err != nil {
+		return "", err
+	}
+	return header + out, nil
+}
+
+// encode marshals the values with 2 spaces indentation and sequences not indented in maps, like Kubernetes objects.
+func encode(v Values) (string, error) {
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	encoder.CompactSeqIndent()
+	err := encoder.Encode(v)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	err = encoder.Close()
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return out.String(), nil
+}
+
+// ProxyEnv returns the environment variables sending the traffic of the components without proxy settings
+// through the given proxy, except to the given comma separated hosts, domains and CIDR blocks.
+func ProxyEnv(proxyURL, noProxy string) []EnvVar {
+	return []EnvVar{
+		{Name: "HTTPS_PROXY", Value: proxyURL},
+		{Name: "NO_PROXY", Value: noProxy},
+	}
+}
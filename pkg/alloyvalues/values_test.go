@@ -0,0 +1,138 @@
+package alloyvalues
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewConfigMap(t *testing.T) {
+	configMap := NewConfigMap("logging {\n\tlevel = \"info\"\n}\n\nloki.write \"default\" { }\n")
+
+	expected := ConfigMap{Create: true, Content: "logging {\n\tlevel = \"info\"\n}\n\nloki.write \"default\" { }"}
+	if diff := cmp.Diff(expected, configMap); diff != "" {
+		t.Errorf("unexpected configmap, diff:\n%s", diff)
+	}
+}
+
+func TestConfigHash(t *testing.T) {
+	annotations := ConfigPodAnnotations("logging {}\n")
+	if diff := cmp.Diff(ConfigPodAnnotations("logging {}"), annotations); diff != "" {
+		t.Errorf("expected surrounding spaces to be ignored, diff:\n%s", diff)
+	}
+	if cmp.Equal(ConfigPodAnnotations("loki.write \"default\" { }"), annotations) {
+		t.Error("expected the hash to change with the configuration")
+	}
+
+	values, err := Values{Alloy: Chart{Controller: Controller{PodAnnotations: annotations}}}.Marshal("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testCases := []struct {
+		name     string
+		values   string
+		expected string
+	}{
+		{name: "annotated", values: values, expected: annotations[ConfigHashAnnotation]},
+		{name: "not annotated", values: "alloy:\n  controller:\n    type: daemonset\n"},
+		{name: "empty", values: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			configHash, err := ConfigHash(tc.values)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if configHash != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, configHash)
+			}
+		})
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	testCases := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name:    "literal content",
+			content: "logging {\n\tlevel = \"info\"\n}",
+			expected: `# header
+alloy:
+  alloy:
+    configMap:
+      create: true
+      content: |-
+        logging {
+        	level = "info"
+        }
+    resources:
+      limits:
+        memory: 300Mi
+      requests: {}
+    securityContext:
+      allowPrivilegeEscalation: false
+      readOnlyRootFilesystem: true
+      runAsUser: 0
+      runAsGroup: 0
+      runAsNonRoot: false
+  controller:
+    type: daemonset
+`,
+		},
+		{
+			name:    "indented content",
+			content: " logging {\n\tlevel = \"info\"\n}",
+			expected: `# header
+alloy:
+  alloy:
+    configMap:
+      create: true
+      content: |2-
+         logging {
+        	level = "info"
+        }
+    resources:
+      limits:
+        memory: 300Mi
+      requests: {}
+    securityContext:
+      allowPrivilegeEscalation: false
+      readOnlyRootFilesystem: true
+      runAsUser: 0
+      runAsGroup: 0
+      runAsNonRoot: false
+  controller:
+    type: daemonset
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			values := Values{
+				Alloy: Chart{
+					Alloy: Alloy{
+						ConfigMap: ConfigMap{Create: true, Content: tc.content},
+						Resources: Resources{Limits: map[string]string{"memory": "300Mi"}, Requests: map[string]string{}},
+						SecurityContext: SecurityContext{
+							ReadOnlyRootFilesystem: true,
+						},
+					},
+					Controller: Controller{Type: "daemonset"},
+				},
+			}
+
+			out, err := values.Marshal("# header\n")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, out); diff != "" {
+				t.Errorf("unexpected values, diff:\n%s", diff)
+			}
+		})
+	}
+}
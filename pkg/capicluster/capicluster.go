@@ -0,0 +1,180 @@
+package capicluster
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	capiv1beta1 "sigs.k8s.io/cluster-api/api/core/v1beta1" //nolint:staticcheck // SA1019 deprecated package
+	capiv1beta2 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Version is a version of the CAPI API clusters can be read in.
+type Version string
+
+const (
+	// V1Beta1 is the deprecated v1beta1 CAPI API.
+	V1Beta1 Version = "v1beta1"
+	// V1Beta2 is the v1beta2 CAPI API.
+	V1Beta2 Version = "v1beta2"
+)
+
+// GroupKind is the group and kind of CAPI clusters.
+var GroupKind = schema.GroupKind{Group: capiv1beta2.GroupVersion.Group, Kind: "Cluster"}
+
+// Cluster is a view over a CAPI Cluster which does not depend on the version of the CAPI API
+// it was read in. Resources mostly rely on the object metadata, which is the same in all versions,
+// and read the spec through the methods below.
+type Cluster struct {
+	// Object is the underlying *v1beta1.Cluster or *v1beta2.Cluster.
+	client.Object
+}
+
+// New returns a view over the given v1beta1 or v1beta2 cluster.
+func New(object client.Object) *Cluster {
+	return &Cluster{Object: object}
+}
+
+// V1Beta1 returns the cluster as a v1beta1 Cluster, for the libraries which still expect it.
+// v1beta2 clusters are converted, which requires RegisterConversion to have been called
+// when the cluster references infrastructure or control plane objects.
+func (c *Cluster) V1Beta1() (*capiv1beta1.Cluster, error) {
+	switch cluster := c.Object.(type) {
+	case *capiv1beta1.Cluster:
+		return cluster, nil
+	case *capiv1beta2.Cluster:
+		converted := &capiv1beta1.Cluster{}
+		err := converted.ConvertFrom(cluster)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return converted, nil
+	default:
+		return nil, errors.Errorf("unsupported cluster type %T", c.Object)
+	}
+}
+
+// Version returns the version of the CAPI API the cluster was read in.
+func (c *Cluster) Version() Version {
+	if _, ok := c.Object.(*capiv1beta2.Cluster); ok {
+		return V1Beta2
+	}
+	return V1Beta1
+}
+
+// NetworkCIDRs returns the pods and services CIDR blocks of the cluster network,
+// or nothing when the cluster does not set them.
+func (c *Cluster) NetworkCIDRs() []string {
+	var cidrs []string
+	switch cluster := c.Object.(type) {
+	case *capiv1beta1.Cluster:
+		network := cluster.Spec.ClusterNetwork
+		if network == nil {
+			return nil
+		}
+		if network.Pods != nil {
+			cidrs = append(cidrs, network.Pods.CIDRBlocks...)
+		}
+		if network.Services != nil {
+			cidrs = append(cidrs, network.Services.CIDRBlocks...)
+		}
+	case *capiv1beta2.Cluster:
+		network := cluster.Spec.ClusterNetwork
+		cidrs = append(cidrs, network.Pods.CIDRBlocks...)
+		cidrs = append(cidrs, network.Services.CIDRBlocks...)
+	}
+	return cidrs
+}
+
+// OwnerReference returns an owner reference to the cluster. It is not a controller reference,
+// and it blocks the deletion of the cluster in the foreground until the owned object is deleted.
+func (c *Cluster) OwnerReference() metav1.OwnerReference {
+	return metav1.OwnerReference{
+		APIVersion:         GroupKind.WithVersion(string(c.Version())).GroupVersion().String(),
+		Kind:               GroupKind.Kind,
+		Name:               c.GetName(),
+		UID:                c.GetUID(),
+		Controller:         ptr.To(false),
+		BlockOwnerDeletion: ptr.To(true),
+	}
+}
+
+// NewObject returns an empty cluster object in the given version.
+// The deprecated v1beta1 API is used when the version is not set.
+func (v Version) NewObject() client.Object {
+	if v == V1Beta2 {
+		return &capiv1beta2.Cluster{}
+	}
+	return &capiv1beta1.Cluster{}
+}
+
+// Get reads the cluster with the given name in the given version.
+func Get(ctx context.Context, c client.Reader, version Version, key types.NamespacedName) (*Cluster, error) {
+	object := version.NewObject()
+	err := c.Get(ctx, key, object)
+	if err != nil {
+		return nil, err
+	}
+	return New(object), nil
+}
+
+// List reads the clusters matching the given options in the given version.
+func List(ctx context.Context, c client.Reader, version Version, opts ...client.ListOption) ([]*Cluster, error) {
+	var clusters []*Cluster
+	if version == V1Beta2 {
+		list := &capiv1beta2.ClusterList{}
+		err := c.List(ctx, list, opts...)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		for i := range list.Items {
+			clusters = append(clusters, New(&list.Items[i]))
+		}
+		return clusters, nil
+	}
+
+	list := &capiv1beta1.ClusterList{}
+	err := c.List(ctx, list, opts...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for i := range list.Items {
+		clusters = append(clusters, New(&list.Items[i]))
+	}
+	return clusters, nil
+}
+
+// ServedVersion returns the version clusters should be read in: v1beta2 when the API server serves it,
+// v1beta1 otherwise.
+func ServedVersion(mapper meta.RESTMapper) (Version, error) {
+	_, err := mapper.RESTMapping(GroupKind, string(V1Beta2))
+	if err == nil {
+		return V1Beta2, nil
+	}
+	if !meta.IsNoMatchError(err) {
+		return "", errors.WithStack(err)
+	}
+
+	_, err = mapper.RESTMapping(GroupKind, string(V1Beta1))
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return V1Beta1, nil
+}
+
+// RegisterConversion allows v1beta2 clusters to be converted to v1beta1 by resolving the API version
+// of their infrastructure and control plane references with the given mapper.
+func RegisterConversion(mapper meta.RESTMapper) {
+	capiv1beta1.SetAPIVersionGetter(func(gk schema.GroupKind) (string, error) {
+		mapping, err := mapper.RESTMapping(gk)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		return mapping.GroupVersionKind.GroupVersion().String(), nil
+	})
+}
@@ -0,0 +1,165 @@
+package capicluster
+
+import (
+	"slices"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	capiv1beta1 "sigs.k8s.io/cluster-api/api/core/v1beta1" //nolint:staticcheck // SA1019 deprecated package
+	capiv1beta2 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+)
+
+func TestServedVersion(t *testing.T) {
+	testCases := []struct {
+		name     string
+		versions []string
+		expected Version
+	}{
+		{
+			name:     "v1beta1 only",
+			versions: []string{"v1beta1"},
+			expected: V1Beta1,
+		},
+		{
+			name:     "v1beta1 and v1beta2",
+			versions: []string{"v1beta1", "v1beta2"},
+			expected: V1Beta2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mapper := meta.NewDefaultRESTMapper(nil)
+			for _, version := range tc.versions {
+				mapper.Add(GroupKind.WithVersion(version), meta.RESTScopeNamespace)
+			}
+
+			version, err := ServedVersion(mapper)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if version != tc.expected {
+				t.Errorf("expected version %s, got %s", tc.expected, version)
+			}
+		})
+	}
+
+	t.Run("not served", func(t *testing.T) {
+		_, err := ServedVersion(meta.NewDefaultRESTMapper(nil))
+		if err == nil {
+			t.Error("expected an error when clusters are not served")
+		}
+	})
+}
+
+func TestV1Beta1(t *testing.T) {
+	infrastructureVersion := schema.GroupVersion{Group: "infrastructure.cluster.x-k8s.io", Version: "v1beta2"}
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{infrastructureVersion})
+	mapper.Add(infrastructureVersion.WithKind("AWSCluster"), meta.RESTScopeNamespace)
+	RegisterConversion(mapper)
+
+	cluster := New(&capiv1beta2.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-cluster",
+			Namespace: "org-test",
+			Labels:    map[string]string{"giantswarm.io/logging": "true"},
+		},
+		Spec: capiv1beta2.ClusterSpec{
+			InfrastructureRef: capiv1beta2.ContractVersionedObjectReference{
+				APIGroup: "infrastructure.cluster.x-k8s.io",
+				Kind:     "AWSCluster",
+				Name:     "test-cluster",
+			},
+		},
+	})
+
+	converted, err := cluster.V1Beta1()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if converted.GetName() != "test-cluster" || converted.GetNamespace() != "org-test" {
+		t.Errorf("unexpected metadata %s/%s", converted.GetNamespace(), converted.GetName())
+	}
+	if converted.GetLabels()["giantswarm.io/logging"] != "true" {
+		t.Errorf("expected labels to be kept, got %v", converted.GetLabels())
+	}
+	if converted.Spec.InfrastructureRef == nil || converted.Spec.InfrastructureRef.APIVersion != "infrastructure.cluster.x-k8s.io/v1beta2" {
+		t.Errorf("unexpected infrastructure reference %v", converted.Spec.InfrastructureRef)
+	}
+}
+
+func TestOwnerReference(t *testing.T) {
+	testCases := []struct {
+		name               string
+		cluster            *Cluster
+		expectedAPIVersion string
+	}{
+		{
+			name:               "v1beta1",
+			cluster:            New(&capiv1beta1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", UID: "test-uid"}}),
+			expectedAPIVersion: "cluster.x-k8s.io/v1beta1",
+		},
+		{
+			name:               "v1beta2",
+			cluster:            New(&capiv1beta2.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", UID: "test-uid"}}),
+			expectedAPIVersion: "cluster.x-k8s.io/v1beta2",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ownerReference := tc.cluster.OwnerReference()
+			if ownerReference.APIVersion != tc.expectedAPIVersion || ownerReference.Kind != "Cluster" {
+				t.Errorf("unexpected owner type %s %s", ownerReference.APIVersion, ownerReference.Kind)
+			}
+			if ownerReference.Name != "test-cluster" || ownerReference.UID != "test-uid" {
+				t.Errorf("unexpected owner %s %s", ownerReference.Name, ownerReference.UID)
+			}
+			if ownerReference.Controller == nil || *ownerReference.Controller {
+				t.Error("expected a non-controller owner reference")
+			}
+			if ownerReference.BlockOwnerDeletion == nil || !*ownerReference.BlockOwnerDeletion {
+				t.Error("expected the owner reference to block the cluster deletion")
+			}
+		})
+	}
+}
+
+func TestNetworkCIDRs(t *testing.T) {
+	testCases := []struct {
+		name     string
+		cluster  *Cluster
+		expected []string
+	}{
+		{
+			name:    "v1beta1 without cluster network",
+			cluster: New(&capiv1beta1.Cluster{}),
+		},
+		{
+			name: "v1beta1",
+			cluster: New(&capiv1beta1.Cluster{Spec: capiv1beta1.ClusterSpec{ClusterNetwork: &capiv1beta1.ClusterNetwork{
+				Pods:     &capiv1beta1.NetworkRanges{CIDRBlocks: []string{"100.64.0.0/12"}},
+				Services: &capiv1beta1.NetworkRanges{CIDRBlocks: []string{"172.31.0.0/16"}},
+			}}}),
+			expected: []string{"100.64.0.0/12", "172.31.0.0/16"},
+		},
+		{
+			name: "v1beta2",
+			cluster: New(&capiv1beta2.Cluster{Spec: capiv1beta2.ClusterSpec{ClusterNetwork: capiv1beta2.ClusterNetwork{
+				Services: capiv1beta2.NetworkRanges{CIDRBlocks: []string{"172.31.0.0/16"}},
+			}}}),
+			expected: []string{"172.31.0.0/16"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cidrs := tc.cluster.NetworkCIDRs()
+			if !slices.Equal(cidrs, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, cidrs)
+			}
+		})
+	}
+}
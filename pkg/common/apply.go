@@ -0,0 +1,103 @@
+package common
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+	metav1ac "k8s.io/client-go/applyconfigurations/meta/v1"
+	"k8s.io/client-go/util/csaupgrade"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// FieldManager is the field manager owning the fields applied by the operator with server-side apply.
+const FieldManager = "logging-operator"
+
+// legacyFieldManagers are the field managers of the fields written with Create and Update,
+// before the operator moved to server-side apply.
+var legacyFieldManagers = sets.New(FieldManager)
+
+// ConfigMapApplyConfiguration returns the fields of the given configmap owned by the operator: name, labels,
+// annotations, owner references and data.
+func ConfigMapApplyConfiguration(configmap v1.ConfigMap) *corev1ac.ConfigMapApplyConfiguration {
+	return corev1ac.ConfigMap(configmap.GetName(), configmap.GetNamespace()).
+		WithLabels(configmap.GetLabels()).
+		WithAnnotations(configmap.GetAnnotations()).
+		WithOwnerReferences(ownerReferencesApplyConfiguration(configmap.GetOwnerReferences())...).
+		WithData(configmap.Data)
+}
+
+// SecretApplyConfiguration returns the fields of the given secret owned by the operator: name, labels,
+// annotations, owner references and data.
+func SecretApplyConfiguration(secret v1.Secret) *corev1ac.SecretApplyConfiguration {
+	return corev1ac.Secret(secret.GetName(), secret.GetNamespace()).
+		WithLabels(secret.GetLabels()).
+		WithAnnotations(secret.GetAnnotations()).
+		WithOwnerReferences(ownerReferencesApplyConfiguration(secret.GetOwnerReferences())...).
+		WithData(secret.Data)
+}
+
+func ownerReferencesApplyConfiguration(ownerReferences []metav1.OwnerReference) []*metav1ac.OwnerReferenceApplyConfiguration {
+	applyConfigurations := make([]*metav1ac.OwnerReferenceApplyConfiguration, 0, len(ownerReferences))
+	for _, ownerReference := range ownerReferences {
+		applyConfiguration := metav1ac.OwnerReference().
+			WithAPIVersion(ownerReference.APIVersion).
+			WithKind(ownerReference.Kind).
+			WithName(ownerReference.Name).
+			WithUID(ownerReference.UID)
+		if ownerReference.Controller != nil {
+			applyConfiguration.WithController(*ownerReference.Controller)
+		}
+		if ownerReference.BlockOwnerDeletion != nil {
+			applyConfiguration.WithBlockOwnerDeletion(*ownerReference.BlockOwnerDeletion)
+		}
+		applyConfigurations = append(applyConfigurations, applyConfiguration)
+	}
+	return applyConfigurations
+}
+
+// ConfigMapUpToDate returns true if the fields of the current configmap owned by the operator match the desired ones.
+// Fields owned by other managers, like labels added by other controllers, are ignored.
+func ConfigMapUpToDate(current v1.ConfigMap, desired *corev1ac.ConfigMapApplyConfiguration) (bool, error) {
+	owned, err := corev1ac.ExtractConfigMap(&current, FieldManager)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return equality.Semantic.DeepEqual(owned, desired), nil
+}
+
+// SecretUpToDate returns true if the fields of the current secret owned by the operator match the desired ones.
+// Fields owned by other managers, like labels added by other controllers, are ignored.
+func SecretUpToDate(current v1.Secret, desired *corev1ac.SecretApplyConfiguration) (bool, error) {
+	owned, err := corev1ac.ExtractSecret(&current, FieldManager)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return equality.Semantic.DeepEqual(owned, desired), nil
+}
+
+// UpgradeManagedFields transfers the ownership of the fields the operator wrote with Create and Update to its
+// server-side apply field manager, so that applying does not conflict with the operator's own previous writes.
+// It does nothing when the object has no such fields.
+func UpgradeManagedFields(ctx context.Context, c client.Client, obj client.Object) error {
+	patch, err := csaupgrade.UpgradeManagedFieldsPatch(obj, legacyFieldManagers, FieldManager)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if patch == nil {
+		return nil
+	}
+	return errors.WithStack(c.Patch(ctx, obj, client.RawPatch(types.JSONPatchType, patch)))
+}
+
+// Apply applies the given configuration with the operator field manager. Ownership is not forced:
+// when another manager owns one of the applied fields with a different value, a conflict error is returned.
+func Apply(ctx context.Context, c client.Client, obj runtime.ApplyConfiguration) error {
+	return errors.WithStack(c.Apply(ctx, obj, client.FieldOwner(FieldManager)))
+}
@@ -0,0 +1,117 @@
+package common
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestApplyConfigMap(t *testing.T) {
+	ctx := context.Background()
+	c := fake.NewClientBuilder().WithReturnManagedFields().Build()
+
+	desired := v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-cluster-logging-config", Namespace: "org-test", Labels: map[string]string{"giantswarm.io/managed-by": "logging-operator"}},
+		Data:       map[string]string{"values": "a: 1\n"},
+	}
+	if err := Apply(ctx, c, ConfigMapApplyConfiguration(desired)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	get := func() v1.ConfigMap {
+		var current v1.ConfigMap
+		if err := c.Get(ctx, client.ObjectKeyFromObject(&desired), &current); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return current
+	}
+	upToDate := func(current v1.ConfigMap, desired v1.ConfigMap) bool {
+		upToDate, err := ConfigMapUpToDate(current, ConfigMapApplyConfiguration(desired))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return upToDate
+	}
+
+	if !upToDate(get(), desired) {
+		t.Error("expected applied configmap to be up to date")
+	}
+
+	// Metadata added by other managers is kept and ignored.
+	other := corev1ac.ConfigMap(desired.GetName(), desired.GetNamespace()).WithLabels(map[string]string{"app.kubernetes.io/name": "other"})
+	if err := c.Apply(ctx, other, client.FieldOwner("other")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	current := get()
+	if current.GetLabels()["app.kubernetes.io/name"] != "other" {
+		t.Errorf("expected label of the other manager to be kept, got %v", current.GetLabels())
+	}
+	if !upToDate(current, desired) {
+		t.Error("expected configmap to be up to date despite the other manager label")
+	}
+
+	// Changed data is detected and applied.
+	changed := *desired.DeepCopy()
+	changed.Data["values"] = "a: 2\n"
+	if upToDate(current, changed) {
+		t.Error("expected configmap with changed data not to be up to date")
+	}
+	if err := Apply(ctx, c, ConfigMapApplyConfiguration(changed)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	current = get()
+	if current.Data["values"] != "a: 2\n" || current.GetLabels()["app.kubernetes.io/name"] != "other" {
+		t.Errorf("unexpected configmap after apply: %v", current)
+	}
+
+	// Data owned by another manager conflicts.
+	conflicting := corev1ac.ConfigMap(desired.GetName(), desired.GetNamespace()).WithData(map[string]string{"values": "a: 3\n"})
+	if err := c.Apply(ctx, conflicting, client.FieldOwner("other"), client.ForceOwnership); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err := Apply(ctx, c, ConfigMapApplyConfiguration(changed))
+	if !apimachineryerrors.IsConflict(err) {
+		t.Errorf("expected conflict error, got %v", err)
+	}
+}
+
+func TestUpgradeManagedFields(t *testing.T) {
+	ctx := context.Background()
+	c := fake.NewClientBuilder().WithReturnManagedFields().Build()
+
+	// Secret written with Create, before the operator used server-side apply.
+	secret := v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-cluster-logging-secret", Namespace: "org-test"},
+		Data:       map[string][]byte{"values": []byte("a: 1\n")},
+	}
+	if err := c.Create(ctx, secret.DeepCopy(), client.FieldOwner(FieldManager)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var current v1.Secret
+	if err := c.Get(ctx, client.ObjectKeyFromObject(&secret), &current); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := UpgradeManagedFields(ctx, c, &current); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	upToDate, err := SecretUpToDate(current, SecretApplyConfiguration(secret))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !upToDate {
+		t.Error("expected fields written before server-side apply to be owned by the operator")
+	}
+
+	secret.Data["values"] = []byte("a: 2\n")
+	if err := Apply(ctx, c, SecretApplyConfiguration(secret)); err != nil {
+		t.Errorf("expected no conflict with the fields written before server-side apply, got %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package common
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/logging-operator/pkg/config"
+)
+
+// DefaultCABundleKey is the default key of the CA bundle in its secret or configmap.
+const DefaultCABundleKey = "ca.crt"
+
+// HasCABundle returns true if a CA bundle is configured to verify the Loki and Tempo certificates.
+func HasCABundle(cfg config.Config) bool {
+	return cfg.CABundleSecret != "" || cfg.CABundleConfigMap != ""
+}
+
+// parseObjectReference parses an object reference given as <namespace>/<name>.
+func parseObjectReference(reference string) (types.NamespacedName, error) {
+	namespace, name, ok := strings.Cut(reference, "/")
+	if !ok || namespace == "" || name == "" || strings.Contains(name, "/") {
+		return types.NamespacedName{}, errors.Errorf("invalid object reference %q, must be <namespace>/<name>", reference)
+	}
+	return types.NamespacedName{Namespace: namespace, Name: name}, nil
+}
+
+// ValidateCABundleConfig checks that at most one CA bundle source is configured, as <namespace>/<name>, and that
+// it is not combined with the insecure CA switch.
+func ValidateCABundleConfig(cfg config.Config) error {
+	if cfg.CABundleSecret != "" && cfg.CABundleConfigMap != "" {
+		return errors.New("the CA bundle can be read from a secret or from a configmap, not both")
+	}
+	if HasCABundle(cfg) && cfg.InsecureCA {
+		return errors.New("the CA bundle can not be used when the CA is insecure")
+	}
+	for _, reference := range []string{cfg.CABundleSecret, cfg.CABundleConfigMap} {
+		if reference == "" {
+			continue
+		}
+		if _, err := parseObjectReference(reference); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsCABundle returns true if the given secret or configmap holds the configured CA bundle.
+func IsCABundle(cfg config.Config, object client.Object) bool {
+	reference := cfg.CABundleConfigMap
+	if _, ok := object.(*v1.Secret); ok {
+		reference = cfg.CABundleSecret
+	}
+	return reference != "" && reference == object.GetNamespace()+"/"+object.GetName()
+}
+
+// ReadCABundle returns the PEM encoded CA bundle configured for the installation, or an empty string when
+// none is configured. The bundle must hold at least one certificate.
+func ReadCABundle(ctx context.Context, c client.Client, cfg config.Config) (string, error) {
+	key := cfg.CABundleKey
+	if key == "" {
+		key = DefaultCABundleKey
+	}
+
+	var bundle string
+	var found bool
+	switch {
+	case cfg.CABundleSecret != "":
+		name, err := parseObjectReference(cfg.CABundleSecret)
+		if err != nil {
+			return "", err
+		}
+		var secret v1.Secret
+		if err := c.Get(ctx, name, &secret); err != nil {
+			return "", errors.WithStack(err)
+		}
+		var data []byte
+		data, found = secret.Data[key]
+		bundle = string(data)
+	case cfg.CABundleConfigMap != "":
+		name, err := parseObjectReference(cfg.CABundleConfigMap)
+		if err != nil {
+			return "", err
+		}
+		var configmap v1.ConfigMap
+		if err := c.Get(ctx, name, &configmap); err != nil {
+			return "", errors.WithStack(err)
+		}
+		bundle, found = configmap.Data[key]
+	default:
+		return "", nil
+	}
+	if !found {
+		return "", errors.Errorf("CA bundle key %q not found", key)
+	}
+
+	if err := validateCABundle(bundle); err != nil {
+		return "", err
+	}
+	return bundle, nil
+}
+
+// validateCABundle checks that the given bundle only holds PEM encoded certificates, and at least one.
+func validateCABundle(bundle string) error {
+	rest := []byte(bundle)
+	certificates := 0
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return errors.Errorf("invalid CA bundle: unexpected %s PEM block", block.Type)
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return errors.Wrap(err, "invalid CA bundle")
+		}
+		certificates++
+	}
+	if strings.TrimSpace(string(rest)) != "" {
+		return errors.New("invalid CA bundle: trailing data after the PEM blocks")
+	}
+	if certificates == 0 {
+		return errors.New("invalid CA bundle: no certificate found")
+	}
+	return nil
+}
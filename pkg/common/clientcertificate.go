@@ -0,0 +1,130 @@
+package common
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
+	"github.com/giantswarm/logging-operator/pkg/config"
+)
+
+const (
+	// AuthModeBasic authenticates the clusters to Loki and Tempo with the basic auth credentials
+	// managed by the observability-operator.
+	AuthModeBasic = "basic"
+	// AuthModeMTLS authenticates the clusters to Loki and Tempo with a client certificate issued by cert-manager.
+	AuthModeMTLS = "mtls"
+
+	// DefaultClientCertificateIssuerKind is the default kind of the cert-manager issuer signing the client certificates.
+	DefaultClientCertificateIssuerKind = "ClusterIssuer"
+	// DefaultClientCertificateDuration is the default validity of the client certificates.
+	DefaultClientCertificateDuration = 90 * 24 * time.Hour
+	// DefaultClientCertificateRenewBefore is how long before their expiry the client certificates are renewed by default.
+	DefaultClientCertificateRenewBefore = 30 * 24 * time.Hour
+
+	clientCertificateSuffix = "-logging-client-certificate"
+)
+
+var (
+	// AuthModes are the supported ways for the clusters to authenticate to Loki and Tempo.
+	AuthModes = []string{AuthModeBasic, AuthModeMTLS}
+
+	// CertificateGroupVersionKind is the cert-manager Certificate requested for the client certificate of each cluster.
+	CertificateGroupVersionKind = schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"}
+
+	clientCertificateIssuerKinds = []string{"Issuer", "ClusterIssuer"}
+)
+
+// ClientCertificate is the client certificate issued to a cluster.
+type ClientCertificate struct {
+	// CertPEM is the PEM encoded certificate chain.
+	CertPEM string
+	// KeyPEM is the PEM encoded private key.
+	KeyPEM string
+	// NotAfter is the expiry date of the certificate.
+	NotAfter time.Time
+}
+
+// IsMTLSAuth returns true if the clusters authenticate to Loki and Tempo with a client certificate.
+func IsMTLSAuth(cfg config.Config) bool {
+	return cfg.AuthMode == AuthModeMTLS
+}
+
+// ValidateAuthConfig checks the auth mode and the client certificate settings.
+// The issuer is only required when mutual TLS is the default auth mode, as it can be enabled per cluster.
+func ValidateAuthConfig(cfg config.Config) error {
+	if !slices.Contains(AuthModes, cfg.AuthMode) {
+		return errors.Errorf("invalid auth mode %q, must be one of %s", cfg.AuthMode, strings.Join(AuthModes, ", "))
+	}
+	if IsMTLSAuth(cfg) && cfg.ClientCertificateIssuer == "" {
+		return errors.New("a client certificate issuer is required when the auth mode is mtls")
+	}
+	if !slices.Contains(clientCertificateIssuerKinds, cfg.ClientCertificateIssuerKind) {
+		return errors.Errorf("invalid client certificate issuer kind %q, must be one of %s", cfg.ClientCertificateIssuerKind, strings.Join(clientCertificateIssuerKinds, ", "))
+	}
+	if cfg.ClientCertificateRenewBefore <= 0 || cfg.ClientCertificateRenewBefore >= cfg.ClientCertificateDuration {
+		return errors.Errorf("the client certificate renewal (%s) must be positive and shorter than its duration (%s)", cfg.ClientCertificateRenewBefore, cfg.ClientCertificateDuration)
+	}
+	return nil
+}
+
+// ClientCertificateName returns the name of the Certificate requesting the client certificate of the given cluster,
+// which is also the name of the secret the certificate is issued in.
+func ClientCertificateName(clusterName string) string {
+	return fmt.Sprintf("%s%s", clusterName, clientCertificateSuffix)
+}
+
+// ClientCertificateClusterName returns the name of the cluster whose client certificate is stored in the given secret,
+// or false if the secret does not hold a client certificate.
+func ClientCertificateClusterName(secret client.Object) (string, bool) {
+	clusterName, ok := strings.CutSuffix(secret.GetName(), clientCertificateSuffix)
+	if !ok || clusterName == "" {
+		return "", false
+	}
+	return clusterName, true
+}
+
+// ReadClientCertificate returns the client certificate issued to the given cluster. A NotFound error is returned
+// while the certificate is not issued yet, and an error when the issued certificate is invalid or expired.
+func ReadClientCertificate(ctx context.Context, c client.Client, cluster *capicluster.Cluster) (*ClientCertificate, error) {
+	var secret v1.Secret
+	err := c.Get(ctx, types.NamespacedName{Name: ClientCertificateName(cluster.GetName()), Namespace: cluster.GetNamespace()}, &secret)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	certPEM := secret.Data[v1.TLSCertKey]
+	keyPEM := secret.Data[v1.TLSPrivateKeyKey]
+	if len(certPEM) == 0 || len(keyPEM) == 0 {
+		return nil, errors.Errorf("client certificate secret %s/%s does not hold a certificate and a private key", secret.GetNamespace(), secret.GetName())
+	}
+
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.Errorf("client certificate secret %s/%s does not hold a PEM encoded certificate", secret.GetNamespace(), secret.GetName())
+	}
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid client certificate in secret %s/%s", secret.GetNamespace(), secret.GetName())
+	}
+	if time.Now().After(certificate.NotAfter) {
+		return nil, errors.Errorf("client certificate in secret %s/%s expired at %s", secret.GetNamespace(), secret.GetName(), certificate.NotAfter.UTC().Format(time.RFC3339))
+	}
+
+	return &ClientCertificate{
+		CertPEM:  string(certPEM),
+		KeyPEM:   string(keyPEM),
+		NotAfter: certificate.NotAfter,
+	}, nil
+}
@@ -2,19 +2,25 @@ package common
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"maps"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
-	netv1 "k8s.io/api/networking/v1"
-	"k8s.io/apimachinery/pkg/types"
-	capi "sigs.k8s.io/cluster-api/api/core/v1beta1" //nolint:staticcheck // SA1019 deprecated package
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/giantswarm/observability-operator/pkg/common/organization"
 	obsconfig "github.com/giantswarm/observability-operator/pkg/config"
 
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
 	"github.com/giantswarm/logging-operator/pkg/config"
 	"github.com/giantswarm/logging-operator/pkg/key"
 )
@@ -23,16 +29,11 @@ const (
 	// LoggingEnabledDefault defines if WCs logs are collected by default
 	LoggingEnabledDefault = true
 
+	// managedBy is the value of the managed-by label of the objects managed by the operator
+	managedBy = "logging-operator"
+
 	// DefaultWriteTenant is the default tenant for writing logs
 	DefaultWriteTenant = "giantswarm"
-	// Loki Gateway Ingress
-	lokiGatewayIngressNamespace = "loki"
-	lokiGatewayIngressName      = "loki-gateway"
-	// Tempo Ingress used to send traces to Tempo
-	// Ingress resources are defined here: https://github.com/giantswarm/tempo-app/blob/main/helm/tempo/templates/ingress.yaml
-	// Configuration for ingresses are here: https://github.com/giantswarm/shared-configs/blob/main/default/apps/tempo/configmap-values.yaml.template#L144-L157
-	tempoIngressNamespace = "tempo"
-	tempoIngressName      = "tempo"
 
 	// App name keys in the observability bundle
 	AlloyLogsObservabilityBundleAppName   = "alloyLogs"
@@ -51,9 +52,9 @@ const (
 	// LokiRemoteTimeout configures the write timeout for remote Loki endpoints.
 	LokiRemoteTimeout = 60 * time.Second
 
-	LokiBaseURLFormat = "https://%s"
 	lokiAPIV1PushPath = "/loki/api/v1/push"
-	LokiPushURLFormat = LokiBaseURLFormat + lokiAPIV1PushPath
+	// LokiPushURLFormat formats the push URL from the Loki base URL.
+	LokiPushURLFormat = "%s" + lokiAPIV1PushPath
 
 	LoggingURL      = "logging-url"
 	LoggingTenantID = "logging-tenant-id"
@@ -77,7 +78,7 @@ type ClusterLabels struct {
 	Provider     string
 }
 
-func IsLoggingEnabled(cluster *capi.Cluster, enableLoggingFlag bool) bool {
+func IsLoggingEnabled(cluster *capicluster.Cluster, enableLoggingFlag bool) bool {
 	// Logging should be enabled when all conditions are met:
 	//   - logging label is set and true on the cluster
 	//   - cluster is not being deleted
@@ -109,10 +110,48 @@ func IsLoggingEnabled(cluster *capi.Cluster, enableLoggingFlag bool) bool {
 }
 
 func AddCommonLabels(labels map[string]string) {
-	labels["giantswarm.io/managed-by"] = "logging-operator"
+	labels[key.ManagedByLabel] = managedBy
+}
+
+// ManagedObjectsSelector selects the objects labelled by AddCommonLabels.
+func ManagedObjectsSelector() client.MatchingLabels {
+	return client.MatchingLabels{key.ManagedByLabel: managedBy}
+}
+
+// legacyManagedObjectSuffixes are the name suffixes of the configmaps and secrets managed for each cluster, named
+// <cluster>-<suffix>, which were written before they were labelled with their cluster.
+var legacyManagedObjectSuffixes = []string{
+	"-logging-config",
+	"-logging-secret",
+	"-events-logger-config",
+	"-events-logger-secret",
+	"-" + observabilityBundleConfigMapName,
 }
 
-func IsNetworkMonitoringEnabled(cluster *capi.Cluster, enableNetworkMonitoringFlag bool) bool {
+// ManagedObjectClusterName returns the name of the cluster the given managed object was written for, derived from
+// its name for the objects written before they were labelled with their cluster, or false if the name is not the
+// name of an object managed for a cluster.
+func ManagedObjectClusterName(object client.Object) (string, bool) {
+	for _, suffix := range legacyManagedObjectSuffixes {
+		clusterName, ok := strings.CutSuffix(object.GetName(), suffix)
+		if ok && clusterName != "" {
+			return clusterName, true
+		}
+	}
+	return "", false
+}
+
+// AddClusterOwner labels the given object metadata with the cluster name and sets the cluster as its owner,
+// so that the object is garbage collected with the cluster.
+func AddClusterOwner(metadata *metav1.ObjectMeta, cluster *capicluster.Cluster) {
+	if metadata.Labels == nil {
+		metadata.Labels = map[string]string{}
+	}
+	metadata.Labels[key.ClusterLabel] = cluster.GetName()
+	metadata.OwnerReferences = append(metadata.OwnerReferences, cluster.OwnerReference())
+}
+
+func IsNetworkMonitoringEnabled(cluster *capicluster.Cluster, enableNetworkMonitoringFlag bool) bool {
 	// Network monitoring should be enabled when all conditions are met:
 	//   - network monitoring label is set and true on the cluster
 	//   - cluster is not being deleted
@@ -143,58 +182,82 @@ func IsNetworkMonitoringEnabled(cluster *capi.Cluster, enableNetworkMonitoringFl
 	return networkMonitoringEnabled
 }
 
+func IsTracingEnabled(cluster *capicluster.Cluster, enableTracingFlag bool) bool {
+	// Tracing is enabled when the cluster is not being deleted and:
+	//   - tracing label is set and true on the cluster, regardless of the global tracing flag
+	//   - or tracing label is not set and the global tracing flag is enabled
+
+	// If the cluster is being deleted, always return false
+	if !cluster.GetDeletionTimestamp().IsZero() {
+		return false
+	}
+
+	// Check cluster-specific tracing label, the installation setting is the default
+	labels := cluster.GetLabels()
+	tracingLabelValue, ok := labels[key.TracingLabel]
+	if !ok {
+		return enableTracingFlag
+	}
+
+	tracingEnabled, err := strconv.ParseBool(tracingLabelValue)
+	if err != nil {
+		return enableTracingFlag
+	}
+	return tracingEnabled
+}
+
 func IsWorkloadCluster(installationName, clusterName string) bool {
 	return installationName != clusterName
 }
 
 // AppConfigName generates an app config name for the given cluster and app.
 // This function can work with any cluster object.
-func AppConfigName(cluster *capi.Cluster, app string) string {
+func AppConfigName(cluster *capicluster.Cluster, app string) string {
 	return fmt.Sprintf("%s-%s", cluster.GetName(), app)
 }
 
-// Read Loki URL from ingress
-func ReadLokiIngressURL(ctx context.Context, cluster *capi.Cluster, client client.Client) (string, error) {
-	var lokiIngress netv1.Ingress
-
-	var objectKey = types.NamespacedName{Name: lokiGatewayIngressName, Namespace: lokiGatewayIngressNamespace}
-	if err := client.Get(ctx, objectKey, &lokiIngress); err != nil {
-		return "", errors.WithStack(err)
+// ConfigHash returns a hash of the data of the given configmaps.
+// Keys are sorted so that the hash only changes when the content does.
+func ConfigHash(configmaps ...v1.ConfigMap) string {
+	hash := sha256.New()
+	for _, configmap := range configmaps {
+		for _, key := range slices.Sorted(maps.Keys(configmap.Data)) {
+			fmt.Fprintf(hash, "%s/%s\x00%s\x00", configmap.GetName(), key, configmap.Data[key])
+		}
 	}
-
-	// We consider there's only one rule with one URL, because that's how the helm chart does it for the moment.
-	if len(lokiIngress.Spec.Rules) <= 0 {
-		return "", fmt.Errorf("loki ingress host not found")
-	}
-	return lokiIngress.Spec.Rules[0].Host, nil
+	return hex.EncodeToString(hash.Sum(nil))
 }
 
-// Read Tempo URL from ingress
-func ReadTempoIngressURL(ctx context.Context, cluster *capi.Cluster, client client.Client) (string, error) {
-	var tempoIngress netv1.Ingress
-
-	var objectKey = types.NamespacedName{Name: tempoIngressName, Namespace: tempoIngressNamespace}
-	if err := client.Get(ctx, objectKey, &tempoIngress); err != nil {
-		return "", errors.WithStack(err)
-	}
+// authSecretName matches the secrets the observability-operator auth managers store the logs and traces
+// credentials of a cluster in: <cluster>-observability-<logs|traces>-auth, in the cluster namespace.
+var authSecretName = regexp.MustCompile(`^(.+)-observability-(logs|traces)-auth$`)
 
-	// We consider there's only one rule with one URL, because that's how the helm chart does it for the moment.
-	if len(tempoIngress.Spec.Rules) <= 0 {
-		return "", fmt.Errorf("tempo ingress host not found")
+// AuthSecretClusterName returns the name of the cluster whose credentials are stored in the given secret,
+// or false if the secret is not a cluster auth secret.
+func AuthSecretClusterName(secret client.Object) (string, bool) {
+	matches := authSecretName.FindStringSubmatch(secret.GetName())
+	if matches == nil {
+		return "", false
 	}
-	return tempoIngress.Spec.Rules[0].Host, nil
+	return matches[1], true
 }
 
 // ExtractClusterLabels extracts all the cluster labels used in templates
-func ExtractClusterLabels(ctx context.Context, k8sClient client.Client, cluster *capi.Cluster, appConfig config.Config) (ClusterLabels, error) {
+func ExtractClusterLabels(ctx context.Context, k8sClient client.Client, cluster *capicluster.Cluster, appConfig config.Config) (ClusterLabels, error) {
+	// observability-operator helpers still expect v1beta1 clusters.
+	v1beta1Cluster, err := cluster.V1Beta1()
+	if err != nil {
+		return ClusterLabels{}, errors.WithStack(err)
+	}
+
 	// Import observability-operator packages here to avoid import cycle
 	orgRepo := organization.NewNamespaceRepository(k8sClient)
-	organizationName, err := orgRepo.Read(ctx, cluster)
+	organizationName, err := orgRepo.Read(ctx, v1beta1Cluster)
 	if err != nil {
 		return ClusterLabels{}, errors.WithStack(err)
 	}
 
-	provider, err := obsconfig.ClusterConfig{}.GetClusterProvider(cluster)
+	provider, err := obsconfig.ClusterConfig{}.GetClusterProvider(v1beta1Cluster)
 	if err != nil {
 		return ClusterLabels{}, errors.WithStack(err)
 	}
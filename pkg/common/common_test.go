@@ -0,0 +1,181 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capiv1beta2 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
+	"github.com/giantswarm/logging-operator/pkg/key"
+)
+
+func TestIsTracingEnabled(t *testing.T) {
+	deletionTimestamp := metav1.NewTime(time.Now())
+
+	testCases := []struct {
+		name              string
+		labels            map[string]string
+		deletionTimestamp *metav1.Time
+		enableTracingFlag bool
+		expected          bool
+	}{
+		{
+			name:              "label true",
+			labels:            map[string]string{key.TracingLabel: "true"},
+			enableTracingFlag: true,
+			expected:          true,
+		},
+		{
+			name:              "label false",
+			labels:            map[string]string{key.TracingLabel: "false"},
+			enableTracingFlag: true,
+			expected:          false,
+		},
+		{
+			name:              "label missing",
+			enableTracingFlag: true,
+			expected:          true,
+		},
+		{
+			name:              "label invalid",
+			labels:            map[string]string{key.TracingLabel: "yes please"},
+			enableTracingFlag: true,
+			expected:          true,
+		},
+		{
+			name:              "label missing, disabled for the installation",
+			enableTracingFlag: false,
+			expected:          false,
+		},
+		{
+			name:              "label true, disabled for the installation",
+			labels:            map[string]string{key.TracingLabel: "true"},
+			enableTracingFlag: false,
+			expected:          true,
+		},
+		{
+			name:              "cluster being deleted",
+			labels:            map[string]string{key.TracingLabel: "true"},
+			deletionTimestamp: &deletionTimestamp,
+			enableTracingFlag: true,
+			expected:          false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := capicluster.New(&capiv1beta2.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "test-cluster",
+					Labels:            tc.labels,
+					DeletionTimestamp: tc.deletionTimestamp,
+				},
+			})
+
+			if enabled := IsTracingEnabled(cluster, tc.enableTracingFlag); enabled != tc.expected {
+				t.Errorf("expected tracing enabled to be %t, got %t", tc.expected, enabled)
+			}
+		})
+	}
+}
+
+func TestAuthSecretClusterName(t *testing.T) {
+	testCases := []struct {
+		name                string
+		secretName          string
+		expectedClusterName string
+		expectedOK          bool
+	}{
+		{
+			name:                "logs auth secret",
+			secretName:          "test-cluster-observability-logs-auth",
+			expectedClusterName: "test-cluster",
+			expectedOK:          true,
+		},
+		{
+			name:                "traces auth secret",
+			secretName:          "test-cluster-observability-traces-auth",
+			expectedClusterName: "test-cluster",
+			expectedOK:          true,
+		},
+		{
+			name:       "other secret",
+			secretName: "test-cluster-logging-secret",
+		},
+		{
+			name:       "gateway auth secret",
+			secretName: "loki-gateway-ingress-auth",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			clusterName, ok := AuthSecretClusterName(&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: tc.secretName, Namespace: "org-test"}})
+			if clusterName != tc.expectedClusterName || ok != tc.expectedOK {
+				t.Errorf("expected %q, %t, got %q, %t", tc.expectedClusterName, tc.expectedOK, clusterName, ok)
+			}
+		})
+	}
+}
+
+func TestManagedObjectClusterName(t *testing.T) {
+	testCases := []struct {
+		name                string
+		objectName          string
+		expectedClusterName string
+		expectedOK          bool
+	}{
+		{
+			name:                "logging config",
+			objectName:          "test-cluster-logging-config",
+			expectedClusterName: "test-cluster",
+			expectedOK:          true,
+		},
+		{
+			name:                "events logger secret",
+			objectName:          "test-cluster-events-logger-secret",
+			expectedClusterName: "test-cluster",
+			expectedOK:          true,
+		},
+		{
+			name:                "observability bundle extra config",
+			objectName:          "test-cluster-observability-bundle-logging-extraconfig",
+			expectedClusterName: "test-cluster",
+			expectedOK:          true,
+		},
+		{
+			name:       "revision",
+			objectName: "test-cluster-logging-config-rev-1",
+		},
+		{
+			name:       "suffix only",
+			objectName: "-logging-secret",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			clusterName, ok := ManagedObjectClusterName(&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: tc.objectName, Namespace: "org-test"}})
+			if clusterName != tc.expectedClusterName || ok != tc.expectedOK {
+				t.Errorf("expected %q, %t, got %q, %t", tc.expectedClusterName, tc.expectedOK, clusterName, ok)
+			}
+		})
+	}
+}
+
+func TestIsGatewayEndpoint(t *testing.T) {
+	ingress := func(namespace, name string) *netv1.Ingress {
+		return &netv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	}
+
+	if !IsGatewayEndpoint(ingress("loki", "loki-gateway")) || !IsGatewayEndpoint(ingress("tempo", "tempo")) {
+		t.Error("expected the Loki and Tempo ingresses to be gateway ingresses")
+	}
+	if IsGatewayEndpoint(ingress("loki", "tempo")) || IsGatewayEndpoint(ingress("default", "loki-gateway")) {
+		t.Error("expected other ingresses not to be gateway ingresses")
+	}
+}
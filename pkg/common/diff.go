@@ -0,0 +1,89 @@
+package common
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	v1 "k8s.io/api/core/v1"
+)
+
+// maxEventDiffLength is the maximum length of a diff attached to a Kubernetes event.
+const maxEventDiffLength = 1024
+
+// ConfigMapDiff returns a unified diff of the data of the current and desired configmaps.
+func ConfigMapDiff(current, desired v1.ConfigMap) string {
+	return dataDiff(fmt.Sprintf("%s/%s", desired.GetNamespace(), desired.GetName()), current.Data, desired.Data)
+}
+
+// SecretDiff returns a unified diff of the data of the current and desired secrets.
+// Credentials are redacted, values which can not be parsed are redacted entirely.
+func SecretDiff(current, desired v1.Secret) string {
+	name := fmt.Sprintf("%s/%s", desired.GetNamespace(), desired.GetName())
+	diff := dataDiff(name, redactSecretData(current.Data), redactSecretData(desired.Data))
+	if diff == "" && !maps.EqualFunc(current.Data, desired.Data, func(a, b []byte) bool { return string(a) == string(b) }) {
+		return fmt.Sprintf("%s: credentials changed (%s)\n", name, RedactedValue)
+	}
+	return diff
+}
+
+// EventDiff returns the given diff truncated to fit in a Kubernetes event.
+func EventDiff(diff string) string {
+	if len(diff) <= maxEventDiffLength {
+		return diff
+	}
+	return diff[:maxEventDiffLength] + "\n... (truncated)"
+}
+
+func redactSecretData(data map[string][]byte) map[string]string {
+	redacted := make(map[string]string, len(data))
+	for key, value := range data {
+		values, err := RedactSecretValues(value)
+		if err != nil {
+			redacted[key] = RedactedValue
+			continue
+		}
+		redacted[key] = string(values)
+	}
+	return redacted
+}
+
+func dataDiff(name string, current, desired map[string]string) string {
+	keys := slices.Sorted(maps.Keys(current))
+	for key := range desired {
+		if _, ok := current[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	var diff strings.Builder
+	for _, key := range keys {
+		keyDiff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        splitLines(current[key]),
+			B:        splitLines(desired[key]),
+			FromFile: fmt.Sprintf("%s/%s (current)", name, key),
+			ToFile:   fmt.Sprintf("%s/%s (desired)", name, key),
+			Context:  3,
+		})
+		if err != nil {
+			// Writing to a strings.Builder does not fail.
+			continue
+		}
+		diff.WriteString(keyDiff)
+	}
+	return diff.String()
+}
+
+// splitLines splits the given text in lines, each ending with a newline.
+// Unlike difflib.SplitLines, it does not add an empty line after a trailing newline.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(strings.TrimSuffix(text, "\n"), "\n")
+	lines[len(lines)-1] += "\n"
+	return lines
+}
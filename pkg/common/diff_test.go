@@ -0,0 +1,68 @@
+package common
+
+import (
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConfigMapDiff(t *testing.T) {
+	objectMeta := metav1.ObjectMeta{Name: "test-cluster-logging-config", Namespace: "org-test"}
+	current := v1.ConfigMap{ObjectMeta: objectMeta, Data: map[string]string{"values": "a: 1\nb: 2\n"}}
+	desired := v1.ConfigMap{ObjectMeta: objectMeta, Data: map[string]string{"values": "a: 1\nb: 3\n"}}
+
+	expected := `--- org-test/test-cluster-logging-config/values (current)
++++ org-test/test-cluster-logging-config/values (desired)
+@@ -1,2 +1,2 @@
+ a: 1
+-b: 2
++b: 3
+`
+	if diff := ConfigMapDiff(current, desired); diff != expected {
+		t.Errorf("unexpected diff:\n%s", diff)
+	}
+
+	if diff := ConfigMapDiff(current, current); diff != "" {
+		t.Errorf("expected no diff, got:\n%s", diff)
+	}
+}
+
+func TestSecretDiff(t *testing.T) {
+	objectMeta := metav1.ObjectMeta{Name: "test-cluster-logging-secret", Namespace: "org-test"}
+	secret := func(url, password string) v1.Secret {
+		return v1.Secret{ObjectMeta: objectMeta, Data: map[string][]byte{"values": []byte(`alloy:
+  alloy:
+    extraSecretEnv:
+    - name: "logging-password"
+      value: "` + password + `"
+    - name: "logging-url"
+      value: "` + url + `"
+`)}}
+	}
+
+	diff := SecretDiff(secret("https://old", "old-password"), secret("https://new", "new-password"))
+	if strings.Contains(diff, "old-password") || strings.Contains(diff, "new-password") {
+		t.Errorf("expected credentials to be redacted, got:\n%s", diff)
+	}
+	if !strings.Contains(diff, "-      value: https://old") || !strings.Contains(diff, "+      value: https://new") {
+		t.Errorf("expected url change in diff, got:\n%s", diff)
+	}
+
+	diff = SecretDiff(secret("https://url", "old-password"), secret("https://url", "new-password"))
+	if diff != "org-test/test-cluster-logging-secret: credentials changed (<redacted>)\n" {
+		t.Errorf("unexpected diff for credentials change:\n%s", diff)
+	}
+}
+
+func TestEventDiff(t *testing.T) {
+	if diff := EventDiff("short"); diff != "short" {
+		t.Errorf("expected short diff to be kept, got %q", diff)
+	}
+
+	diff := EventDiff(strings.Repeat("x", 2*maxEventDiffLength))
+	if !strings.HasSuffix(diff, "... (truncated)") || len(diff) > maxEventDiffLength+len("\n... (truncated)") {
+		t.Errorf("expected long diff to be truncated, got %d characters", len(diff))
+	}
+}
@@ -0,0 +1,415 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	netv1 "k8s.io/api/networking/v1"
+	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
+	"github.com/giantswarm/logging-operator/pkg/config"
+)
+
+// The Loki and Tempo gateways are exposed by an Ingress or an HTTPRoute of the same name.
+// Ingress resources are defined here: https://github.com/giantswarm/tempo-app/blob/main/helm/tempo/templates/ingress.yaml
+// Configuration for ingresses are here: https://github.com/giantswarm/shared-configs/blob/main/default/apps/tempo/configmap-values.yaml.template#L144-L157
+var (
+	lokiGateway  = types.NamespacedName{Name: "loki-gateway", Namespace: "loki"}
+	tempoGateway = types.NamespacedName{Name: "tempo", Namespace: "tempo"}
+)
+
+const gatewayAPIGroup = "gateway.networking.k8s.io"
+
+var (
+	// HTTPRouteGroupVersionKind is the Gateway API HTTPRoute kind the endpoints are discovered from.
+	HTTPRouteGroupVersionKind = schema.GroupVersionKind{Group: gatewayAPIGroup, Version: "v1", Kind: "HTTPRoute"}
+	// GatewayGroupVersionKind is the Gateway API Gateway kind the HTTPRoutes attach to.
+	GatewayGroupVersionKind = schema.GroupVersionKind{Group: gatewayAPIGroup, Version: "v1", Kind: "Gateway"}
+)
+
+// EndpointSource is where the Loki and Tempo endpoints are read from.
+type EndpointSource string
+
+const (
+	// EndpointSourceAuto uses the static endpoint when set, then the HTTPRoute when it exists, then the Ingress.
+	EndpointSourceAuto EndpointSource = "auto"
+	// EndpointSourceIngress reads the endpoints from the Loki and Tempo ingresses.
+	EndpointSourceIngress EndpointSource = "ingress"
+	// EndpointSourceHTTPRoute reads the endpoints from the Loki and Tempo HTTPRoutes and their parent gateway.
+	EndpointSourceHTTPRoute EndpointSource = "httproute"
+	// EndpointSourceStatic uses the endpoints given in the configuration.
+	EndpointSourceStatic EndpointSource = "static"
+)
+
+// EndpointSources lists the valid endpoint sources.
+var EndpointSources = []EndpointSource{EndpointSourceAuto, EndpointSourceIngress, EndpointSourceHTTPRoute, EndpointSourceStatic}
+
+// Default endpoints of the management cluster, which sends logs and traces to the in-cluster services.
+const (
+	DefaultManagementClusterLokiPushURL   = "http://loki-gateway.loki.svc:80" + lokiAPIV1PushPath
+	DefaultManagementClusterLokiRulerURL  = "http://loki-backend.loki.svc:3100/"
+	DefaultManagementClusterTempoEndpoint = "http://tempo-distributor.tempo.svc:4317"
+)
+
+// Endpoint is the address Loki or Tempo is reachable at from the clusters.
+type Endpoint struct {
+	Host string
+	Port int32
+	// Path is the path prefix the API is served under, without trailing slash.
+	Path string
+	// TLS is true when the endpoint is served over TLS.
+	TLS bool
+}
+
+// URL returns the base URL of the endpoint, without the port when it is the default one of the scheme.
+func (e Endpoint) URL() string {
+	scheme, defaultPort := "https", int32(443)
+	if !e.TLS {
+		scheme, defaultPort = "http", 80
+	}
+	if e.Port == defaultPort {
+		return scheme + "://" + e.Host + e.Path
+	}
+	return scheme + "://" + e.Address() + e.Path
+}
+
+// Address returns the endpoint in host:port format, as required by gRPC clients.
+func (e Endpoint) Address() string {
+	return net.JoinHostPort(e.Host, strconv.Itoa(int(e.Port)))
+}
+
+// ParseEndpoint parses a static endpoint given as a URL, like http://loki.example.com:8080/loki, or as a host with an
+// optional port. Endpoints given without scheme are served over TLS.
+func ParseEndpoint(endpoint string) (Endpoint, error) {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return Endpoint{}, errors.WithStack(err)
+	}
+
+	var e Endpoint
+	switch u.Scheme {
+	case "https":
+		e = Endpoint{TLS: true, Port: 443}
+	case "http":
+		e = Endpoint{Port: 80}
+	default:
+		return Endpoint{}, errors.Errorf("invalid endpoint %q: scheme must be http or https", endpoint)
+	}
+	if u.Hostname() == "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return Endpoint{}, errors.Errorf("invalid endpoint %q: must be a host with an optional scheme, port and path", endpoint)
+	}
+	e.Host = u.Hostname()
+	e.Path = strings.TrimSuffix(u.Path, "/")
+	if u.Port() != "" {
+		port, err := strconv.ParseUint(u.Port(), 10, 16)
+		if err != nil {
+			return Endpoint{}, errors.Errorf("invalid endpoint %q: invalid port", endpoint)
+		}
+		e.Port = int32(port)
+	}
+	return e, nil
+}
+
+// parseTempoEndpoint parses a Tempo OTLP gRPC endpoint, which cannot have a path.
+func parseTempoEndpoint(endpoint string) (Endpoint, error) {
+	e, err := ParseEndpoint(endpoint)
+	if err != nil {
+		return Endpoint{}, err
+	}
+	if e.Path != "" {
+		return Endpoint{}, errors.Errorf("invalid Tempo endpoint %q: gRPC endpoints cannot have a path", endpoint)
+	}
+	return e, nil
+}
+
+// ValidateEndpointsConfig checks the endpoint source, the static endpoints and the URLs of the configuration.
+func ValidateEndpointsConfig(cfg config.Config) error {
+	source := EndpointSource(cfg.EndpointSource)
+	if !slices.Contains(EndpointSources, source) {
+		return errors.Errorf("invalid endpoint source %q, must be one of %v", cfg.EndpointSource, EndpointSources)
+	}
+	if cfg.LokiEndpoint != "" {
+		if _, err := ParseEndpoint(cfg.LokiEndpoint); err != nil {
+			return err
+		}
+	}
+	for _, endpoint := range []string{cfg.TempoEndpoint, cfg.ManagementClusterTempoEndpoint} {
+		if endpoint == "" {
+			continue
+		}
+		if _, err := parseTempoEndpoint(endpoint); err != nil {
+			return err
+		}
+	}
+	for _, lokiURL := range []string{cfg.LokiPushURL, cfg.LokiRulerURL, cfg.ManagementClusterLokiPushURL, cfg.ManagementClusterLokiRulerURL} {
+		if lokiURL == "" {
+			continue
+		}
+		u, err := url.Parse(lokiURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.Errorf("invalid Loki URL %q: must be an absolute http or https URL", lokiURL)
+		}
+	}
+	if source == EndpointSourceStatic && cfg.LokiEndpoint == "" && (cfg.LokiPushURL == "" || cfg.LokiRulerURL == "") {
+		return errors.New("the Loki endpoint, or the Loki push and ruler URLs, must be set when the endpoint source is static")
+	}
+	return nil
+}
+
+// LokiURLs are the URLs of the Loki API a cluster pushes logs to and loads rules into.
+type LokiURLs struct {
+	Push  string
+	Ruler string
+}
+
+// ReadLokiURLs returns the Loki URLs used by the clusters sending logs through the Loki gateway.
+// The configured push and ruler URLs are used when set, the others are derived from the Loki endpoint.
+func ReadLokiURLs(ctx context.Context, c client.Client, cfg config.Config) (LokiURLs, error) {
+	urls := LokiURLs{Push: cfg.LokiPushURL, Ruler: cfg.LokiRulerURL}
+	if urls.Push != "" && urls.Ruler != "" {
+		return urls, nil
+	}
+
+	endpoint, err := ReadLokiEndpoint(ctx, c, cfg)
+	if err != nil {
+		return LokiURLs{}, err
+	}
+	if urls.Push == "" {
+		urls.Push = fmt.Sprintf(LokiPushURLFormat, endpoint.URL())
+	}
+	if urls.Ruler == "" {
+		urls.Ruler = endpoint.URL()
+	}
+	return urls, nil
+}
+
+// ManagementClusterLokiURLs returns the Loki URLs the management cluster sends logs to, the in-cluster services by default.
+func ManagementClusterLokiURLs(cfg config.Config) LokiURLs {
+	urls := LokiURLs{Push: cfg.ManagementClusterLokiPushURL, Ruler: cfg.ManagementClusterLokiRulerURL}
+	if urls.Push == "" {
+		urls.Push = DefaultManagementClusterLokiPushURL
+	}
+	if urls.Ruler == "" {
+		urls.Ruler = DefaultManagementClusterLokiRulerURL
+	}
+	return urls
+}
+
+// ReadClusterTempoEndpoint returns the endpoint the given cluster sends traces to: the Tempo endpoint for
+// the workload clusters, the in-cluster Tempo distributor by default for the management cluster.
+func ReadClusterTempoEndpoint(ctx context.Context, c client.Client, cfg config.Config, cluster *capicluster.Cluster) (Endpoint, error) {
+	if IsWorkloadCluster(cfg.InstallationName, cluster.GetName()) {
+		return ReadTempoEndpoint(ctx, c, cfg)
+	}
+	endpoint := cfg.ManagementClusterTempoEndpoint
+	if endpoint == "" {
+		endpoint = DefaultManagementClusterTempoEndpoint
+	}
+	return parseTempoEndpoint(endpoint)
+}
+
+// ReadLokiEndpoint returns the endpoint the clusters send logs to.
+func ReadLokiEndpoint(ctx context.Context, c client.Client, cfg config.Config) (Endpoint, error) {
+	return readEndpoint(ctx, c, EndpointSource(cfg.EndpointSource), cfg.LokiEndpoint, lokiGateway)
+}
+
+// ReadTempoEndpoint returns the endpoint the clusters send traces to.
+func ReadTempoEndpoint(ctx context.Context, c client.Client, cfg config.Config) (Endpoint, error) {
+	return readEndpoint(ctx, c, EndpointSource(cfg.EndpointSource), cfg.TempoEndpoint, tempoGateway)
+}
+
+func readEndpoint(ctx context.Context, c client.Client, source EndpointSource, static string, gateway types.NamespacedName) (Endpoint, error) {
+	switch source {
+	case EndpointSourceStatic:
+		if static == "" {
+			return Endpoint{}, errors.Errorf("no static endpoint configured for %s", gateway)
+		}
+		return parseStaticEndpoint(static, gateway)
+	case EndpointSourceIngress:
+		return readIngressEndpoint(ctx, c, gateway)
+	case EndpointSourceHTTPRoute:
+		return readHTTPRouteEndpoint(ctx, c, gateway)
+	case EndpointSourceAuto, "":
+		if static != "" {
+			return parseStaticEndpoint(static, gateway)
+		}
+		// Installations migrating to Gateway API may still have the ingress, the HTTPRoute wins.
+		endpoint, err := readHTTPRouteEndpoint(ctx, c, gateway)
+		if err == nil {
+			return endpoint, nil
+		}
+		if !apimachineryerrors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+			return Endpoint{}, err
+		}
+		return readIngressEndpoint(ctx, c, gateway)
+	default:
+		return Endpoint{}, errors.Errorf("invalid endpoint source %q", source)
+	}
+}
+
+func parseStaticEndpoint(endpoint string, gateway types.NamespacedName) (Endpoint, error) {
+	if gateway == tempoGateway {
+		return parseTempoEndpoint(endpoint)
+	}
+	return ParseEndpoint(endpoint)
+}
+
+// readIngressEndpoint returns the host of the first rule of the given ingress. Ingresses are served over TLS on port 443.
+func readIngressEndpoint(ctx context.Context, c client.Client, name types.NamespacedName) (Endpoint, error) {
+	var ingress netv1.Ingress
+	if err := c.Get(ctx, name, &ingress); err != nil {
+		return Endpoint{}, errors.WithStack(err)
+	}
+
+	// We consider there's only one rule with one URL, because that's how the helm chart does it for the moment.
+	if len(ingress.Spec.Rules) <= 0 || ingress.Spec.Rules[0].Host == "" {
+		return Endpoint{}, errors.Errorf("ingress %s host not found", name)
+	}
+	return Endpoint{Host: ingress.Spec.Rules[0].Host, Port: 443, TLS: true}, nil
+}
+
+// readHTTPRouteEndpoint returns the first hostname of the given HTTPRoute, with the port and protocol of the
+// listener of its parent gateway. When the route has no hostname, the listener hostname is used.
+// Gateway API objects are read as unstructured objects so that the operator runs on clusters without the CRDs,
+// they are served from the cache of the manager client.
+func readHTTPRouteEndpoint(ctx context.Context, c client.Client, name types.NamespacedName) (Endpoint, error) {
+	route := &unstructured.Unstructured{}
+	route.SetGroupVersionKind(HTTPRouteGroupVersionKind)
+	if err := c.Get(ctx, name, route); err != nil {
+		return Endpoint{}, errors.WithStack(err)
+	}
+
+	parentRef, gatewayName, err := parentGateway(route)
+	if err != nil {
+		return Endpoint{}, err
+	}
+	gateway := &unstructured.Unstructured{}
+	gateway.SetGroupVersionKind(GatewayGroupVersionKind)
+	if err := c.Get(ctx, gatewayName, gateway); err != nil {
+		return Endpoint{}, errors.WithStack(err)
+	}
+
+	listener, err := parentListener(gateway, parentRef)
+	if err != nil {
+		return Endpoint{}, errors.Wrapf(err, "httproute %s", name)
+	}
+
+	hostnames, _, _ := unstructured.NestedStringSlice(route.Object, "spec", "hostnames")
+	listenerHostname, _, _ := unstructured.NestedString(listener, "hostname")
+	hostnames = append(hostnames, listenerHostname)
+	index := slices.IndexFunc(hostnames, func(hostname string) bool {
+		return hostname != "" && !strings.HasPrefix(hostname, "*")
+	})
+	if index < 0 {
+		return Endpoint{}, errors.Errorf("httproute %s host not found", name)
+	}
+
+	port, _, _ := unstructured.NestedInt64(listener, "port")
+	protocol, _, _ := unstructured.NestedString(listener, "protocol")
+	return Endpoint{Host: hostnames[index], Port: int32(port), TLS: protocol == "HTTPS"}, nil
+}
+
+// parentGateway returns the reference to the gateway the given HTTPRoute attaches to, and the name of the gateway.
+func parentGateway(route *unstructured.Unstructured) (map[string]any, types.NamespacedName, error) {
+	parentRefs, _, err := unstructured.NestedSlice(route.Object, "spec", "parentRefs")
+	if err != nil {
+		return nil, types.NamespacedName{}, errors.WithStack(err)
+	}
+	for _, ref := range parentRefs {
+		ref, ok := ref.(map[string]any)
+		if !ok {
+			continue
+		}
+		group, _, _ := unstructured.NestedString(ref, "group")
+		kind, _, _ := unstructured.NestedString(ref, "kind")
+		if (group != "" && group != gatewayAPIGroup) || (kind != "" && kind != GatewayGroupVersionKind.Kind) {
+			continue
+		}
+
+		name, _, _ := unstructured.NestedString(ref, "name")
+		namespace, _, _ := unstructured.NestedString(ref, "namespace")
+		if namespace == "" {
+			namespace = route.GetNamespace()
+		}
+		return ref, types.NamespacedName{Name: name, Namespace: namespace}, nil
+	}
+	return nil, types.NamespacedName{}, errors.Errorf("httproute %s/%s has no parent gateway", route.GetNamespace(), route.GetName())
+}
+
+// parentListener returns the HTTP or HTTPS listener of the gateway the given parent reference attaches to,
+// selected by the section name or port of the reference when set.
+func parentListener(gateway *unstructured.Unstructured, parentRef map[string]any) (map[string]any, error) {
+	sectionName, _, _ := unstructured.NestedString(parentRef, "sectionName")
+	port, _, _ := unstructured.NestedInt64(parentRef, "port")
+
+	listeners, _, err := unstructured.NestedSlice(gateway.Object, "spec", "listeners")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for _, listener := range listeners {
+		listener, ok := listener.(map[string]any)
+		if !ok {
+			continue
+		}
+		listenerName, _, _ := unstructured.NestedString(listener, "name")
+		listenerPort, _, _ := unstructured.NestedInt64(listener, "port")
+		protocol, _, _ := unstructured.NestedString(listener, "protocol")
+		if (sectionName != "" && listenerName != sectionName) || (port != 0 && listenerPort != port) {
+			continue
+		}
+		if protocol == "HTTP" || protocol == "HTTPS" {
+			return listener, nil
+		}
+	}
+	return nil, errors.Errorf("no HTTP or HTTPS listener found in gateway %s/%s", gateway.GetNamespace(), gateway.GetName())
+}
+
+// IsEndpointGateway returns true if the given gateway is the parent of the Loki or Tempo HTTPRoute the endpoints
+// are read from, as its listeners give the port and protocol of the endpoints.
+func IsEndpointGateway(ctx context.Context, c client.Client, gateway client.Object) (bool, error) {
+	for _, name := range []types.NamespacedName{lokiGateway, tempoGateway} {
+		route := &unstructured.Unstructured{}
+		route.SetGroupVersionKind(HTTPRouteGroupVersionKind)
+		err := c.Get(ctx, name, route)
+		if apimachineryerrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return false, errors.WithStack(err)
+		}
+
+		_, gatewayName, err := parentGateway(route)
+		if err != nil {
+			continue
+		}
+		if gatewayName.Name == gateway.GetName() && gatewayName.Namespace == gateway.GetNamespace() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// IsGatewayEndpoint returns true if the given object is the Loki or Tempo ingress or HTTPRoute the endpoints are read from.
+func IsGatewayEndpoint(object client.Object) bool {
+	switch (types.NamespacedName{Name: object.GetName(), Namespace: object.GetNamespace()}) {
+	case lokiGateway, tempoGateway:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,335 @@
+package common
+
+import (
+	"context"
+	"testing"
+
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	capiv1beta2 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
+	"github.com/giantswarm/logging-operator/pkg/config"
+)
+
+func TestParseEndpoint(t *testing.T) {
+	testCases := []struct {
+		endpoint    string
+		expected    Endpoint
+		expectedURL string
+		expectError bool
+	}{
+		{endpoint: "loki.example.com", expected: Endpoint{Host: "loki.example.com", Port: 443, TLS: true}, expectedURL: "https://loki.example.com"},
+		{endpoint: "loki.example.com:8443", expected: Endpoint{Host: "loki.example.com", Port: 8443, TLS: true}, expectedURL: "https://loki.example.com:8443"},
+		{endpoint: "http://loki.example.com", expected: Endpoint{Host: "loki.example.com", Port: 80}, expectedURL: "http://loki.example.com"},
+		{endpoint: "http://loki.example.com:3100/", expected: Endpoint{Host: "loki.example.com", Port: 3100}, expectedURL: "http://loki.example.com:3100"},
+		{endpoint: "grpc://tempo.example.com", expectError: true},
+		{endpoint: "https://loki.example.com/hosted/", expected: Endpoint{Host: "loki.example.com", Port: 443, Path: "/hosted", TLS: true}, expectedURL: "https://loki.example.com/hosted"},
+		{endpoint: "https://loki.example.com?tenant=a", expectError: true},
+		{endpoint: "loki.example.com:99999", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.endpoint, func(t *testing.T) {
+			endpoint, err := ParseEndpoint(tc.endpoint)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("expected error, got %v", endpoint)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if endpoint != tc.expected || endpoint.URL() != tc.expectedURL {
+				t.Errorf("expected %v (%s), got %v (%s)", tc.expected, tc.expectedURL, endpoint, endpoint.URL())
+			}
+		})
+	}
+}
+
+func TestReadEndpoint(t *testing.T) {
+	ingress := &netv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "loki-gateway", Namespace: "loki"},
+		Spec:       netv1.IngressSpec{Rules: []netv1.IngressRule{{Host: "loki.ingress.example.com"}}},
+	}
+	httpRoute := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "gateway.networking.k8s.io/v1",
+		"kind":       "HTTPRoute",
+		"metadata":   map[string]any{"name": "loki-gateway", "namespace": "loki"},
+		"spec": map[string]any{
+			"hostnames":  []any{"loki.gateway.example.com"},
+			"parentRefs": []any{map[string]any{"name": "giantswarm-default", "namespace": "envoy-gateway-system", "sectionName": "https"}},
+		},
+	}}
+	gateway := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "gateway.networking.k8s.io/v1",
+		"kind":       "Gateway",
+		"metadata":   map[string]any{"name": "giantswarm-default", "namespace": "envoy-gateway-system"},
+		"spec": map[string]any{
+			"listeners": []any{
+				map[string]any{"name": "http", "port": int64(80), "protocol": "HTTP"},
+				map[string]any{"name": "https", "port": int64(8443), "protocol": "HTTPS"},
+			},
+		},
+	}}
+
+	testCases := []struct {
+		name        string
+		objects     []client.Object
+		config      config.Config
+		expected    Endpoint
+		expectError bool
+	}{
+		{
+			name:     "auto with ingress only",
+			objects:  []client.Object{ingress},
+			config:   config.Config{EndpointSource: "auto"},
+			expected: Endpoint{Host: "loki.ingress.example.com", Port: 443, TLS: true},
+		},
+		{
+			name:     "auto prefers httproute",
+			objects:  []client.Object{ingress, httpRoute, gateway},
+			config:   config.Config{EndpointSource: "auto"},
+			expected: Endpoint{Host: "loki.gateway.example.com", Port: 8443, TLS: true},
+		},
+		{
+			name:     "auto prefers static endpoint",
+			objects:  []client.Object{ingress, httpRoute, gateway},
+			config:   config.Config{EndpointSource: "auto", LokiEndpoint: "http://loki.local:3100"},
+			expected: Endpoint{Host: "loki.local", Port: 3100},
+		},
+		{
+			name:     "ingress",
+			objects:  []client.Object{ingress, httpRoute, gateway},
+			config:   config.Config{EndpointSource: "ingress"},
+			expected: Endpoint{Host: "loki.ingress.example.com", Port: 443, TLS: true},
+		},
+		{
+			name:        "httproute without parent gateway",
+			objects:     []client.Object{ingress, httpRoute},
+			config:      config.Config{EndpointSource: "httproute"},
+			expectError: true,
+		},
+		{
+			name:        "httproute without httproute",
+			objects:     []client.Object{ingress},
+			config:      config.Config{EndpointSource: "httproute"},
+			expectError: true,
+		},
+		{
+			name:        "static without endpoint",
+			objects:     []client.Object{ingress},
+			config:      config.Config{EndpointSource: "static"},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			objects := make([]client.Object, 0, len(tc.objects))
+			for _, object := range tc.objects {
+				objects = append(objects, object.DeepCopyObject().(client.Object))
+			}
+			c := fake.NewClientBuilder().WithObjects(objects...).Build()
+
+			endpoint, err := ReadLokiEndpoint(context.Background(), c, tc.config)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("expected error, got %v", endpoint)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if endpoint != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, endpoint)
+			}
+		})
+	}
+}
+
+func TestHTTPRouteListenerHostname(t *testing.T) {
+	httpRoute := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "gateway.networking.k8s.io/v1",
+		"kind":       "HTTPRoute",
+		"metadata":   map[string]any{"name": "tempo", "namespace": "tempo"},
+		"spec": map[string]any{
+			"hostnames":  []any{"*.example.com"},
+			"parentRefs": []any{map[string]any{"name": "tempo", "port": int64(4317)}},
+		},
+	}}
+	gateway := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "gateway.networking.k8s.io/v1",
+		"kind":       "Gateway",
+		"metadata":   map[string]any{"name": "tempo", "namespace": "tempo"},
+		"spec": map[string]any{
+			"listeners": []any{
+				map[string]any{"name": "https", "port": int64(443), "protocol": "HTTPS", "hostname": "tempo.example.com"},
+				map[string]any{"name": "grpc", "port": int64(4317), "protocol": "HTTP", "hostname": "tempo-grpc.example.com"},
+			},
+		},
+	}}
+	c := fake.NewClientBuilder().WithObjects(httpRoute, gateway).Build()
+
+	endpoint, err := ReadTempoEndpoint(context.Background(), c, config.Config{EndpointSource: "httproute"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := Endpoint{Host: "tempo-grpc.example.com", Port: 4317}
+	if endpoint != expected || endpoint.Address() != "tempo-grpc.example.com:4317" {
+		t.Errorf("expected %v, got %v", expected, endpoint)
+	}
+}
+
+func TestIsEndpointGateway(t *testing.T) {
+	httpRoute := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "gateway.networking.k8s.io/v1",
+		"kind":       "HTTPRoute",
+		"metadata":   map[string]any{"name": "loki-gateway", "namespace": "loki"},
+		"spec": map[string]any{
+			"parentRefs": []any{map[string]any{"name": "giantswarm-default", "namespace": "envoy-gateway-system"}},
+		},
+	}}
+	c := fake.NewClientBuilder().WithObjects(httpRoute).Build()
+
+	testCases := []struct {
+		name      string
+		namespace string
+		expected  bool
+	}{
+		{name: "giantswarm-default", namespace: "envoy-gateway-system", expected: true},
+		{name: "giantswarm-default", namespace: "loki"},
+		{name: "other", namespace: "envoy-gateway-system"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.namespace+"/"+tc.name, func(t *testing.T) {
+			gateway := &unstructured.Unstructured{}
+			gateway.SetGroupVersionKind(GatewayGroupVersionKind)
+			gateway.SetName(tc.name)
+			gateway.SetNamespace(tc.namespace)
+
+			isEndpointGateway, err := IsEndpointGateway(context.Background(), c, gateway)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if isEndpointGateway != tc.expected {
+				t.Errorf("expected %t, got %t", tc.expected, isEndpointGateway)
+			}
+		})
+	}
+}
+
+func TestValidateEndpointsConfig(t *testing.T) {
+	if err := ValidateEndpointsConfig(config.Config{EndpointSource: "auto"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ValidateEndpointsConfig(config.Config{EndpointSource: "static", LokiEndpoint: "loki.example.com"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ValidateEndpointsConfig(config.Config{EndpointSource: "nginx"}); err == nil {
+		t.Error("expected error for an invalid endpoint source")
+	}
+	if err := ValidateEndpointsConfig(config.Config{EndpointSource: "static"}); err == nil {
+		t.Error("expected error for a static endpoint source without Loki endpoint")
+	}
+	if err := ValidateEndpointsConfig(config.Config{EndpointSource: "static", LokiPushURL: "https://logs.example.com/push", LokiRulerURL: "https://logs.example.com"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ValidateEndpointsConfig(config.Config{EndpointSource: "auto", TempoEndpoint: "grpc://tempo"}); err == nil {
+		t.Error("expected error for an invalid Tempo endpoint")
+	}
+	if err := ValidateEndpointsConfig(config.Config{EndpointSource: "auto", ManagementClusterTempoEndpoint: "http://tempo:4317/otlp"}); err == nil {
+		t.Error("expected error for a Tempo endpoint with a path")
+	}
+	if err := ValidateEndpointsConfig(config.Config{EndpointSource: "auto", ManagementClusterLokiPushURL: "loki-gateway.loki.svc/loki/api/v1/push"}); err == nil {
+		t.Error("expected error for a relative Loki URL")
+	}
+}
+
+func TestReadLokiURLs(t *testing.T) {
+	ingress := &netv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "loki-gateway", Namespace: "loki"},
+		Spec:       netv1.IngressSpec{Rules: []netv1.IngressRule{{Host: "loki.ingress.example.com"}}},
+	}
+
+	testCases := []struct {
+		name     string
+		config   config.Config
+		expected LokiURLs
+	}{
+		{
+			name:     "discovered",
+			config:   config.Config{EndpointSource: "auto"},
+			expected: LokiURLs{Push: "https://loki.ingress.example.com/loki/api/v1/push", Ruler: "https://loki.ingress.example.com"},
+		},
+		{
+			name:     "static endpoint with path",
+			config:   config.Config{EndpointSource: "static", LokiEndpoint: "http://localhost:3100/hosted"},
+			expected: LokiURLs{Push: "http://localhost:3100/hosted/loki/api/v1/push", Ruler: "http://localhost:3100/hosted"},
+		},
+		{
+			name:     "push URL override",
+			config:   config.Config{EndpointSource: "auto", LokiPushURL: "https://logs.example.com/api/push"},
+			expected: LokiURLs{Push: "https://logs.example.com/api/push", Ruler: "https://loki.ingress.example.com"},
+		},
+		{
+			name:     "push and ruler URLs without endpoint",
+			config:   config.Config{EndpointSource: "static", LokiPushURL: "https://logs.example.com/api/push", LokiRulerURL: "https://rules.example.com"},
+			expected: LokiURLs{Push: "https://logs.example.com/api/push", Ruler: "https://rules.example.com"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithObjects(ingress.DeepCopy()).Build()
+
+			urls, err := ReadLokiURLs(context.Background(), c, tc.config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if urls != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, urls)
+			}
+		})
+	}
+}
+
+func TestManagementClusterEndpoints(t *testing.T) {
+	cluster := capicluster.New(&capiv1beta2.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-installation"}})
+
+	urls := ManagementClusterLokiURLs(config.Config{})
+	if urls.Push != "http://loki-gateway.loki.svc:80/loki/api/v1/push" || urls.Ruler != "http://loki-backend.loki.svc:3100/" {
+		t.Errorf("expected in-cluster Loki URLs by default, got %v", urls)
+	}
+	endpoint, err := ReadClusterTempoEndpoint(context.Background(), nil, config.Config{InstallationName: "test-installation"}, cluster)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if endpoint.Address() != "tempo-distributor.tempo.svc:4317" || endpoint.TLS {
+		t.Errorf("expected in-cluster Tempo endpoint by default, got %v", endpoint)
+	}
+
+	cfg := config.Config{
+		InstallationName:               "test-installation",
+		ManagementClusterLokiPushURL:   "https://logs.example.com/push",
+		ManagementClusterLokiRulerURL:  "https://logs.example.com",
+		ManagementClusterTempoEndpoint: "traces.example.com:4443",
+	}
+	urls = ManagementClusterLokiURLs(cfg)
+	if urls != (LokiURLs{Push: "https://logs.example.com/push", Ruler: "https://logs.example.com"}) {
+		t.Errorf("expected configured Loki URLs, got %v", urls)
+	}
+	endpoint, err = ReadClusterTempoEndpoint(context.Background(), nil, cfg, cluster)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if endpoint != (Endpoint{Host: "traces.example.com", Port: 4443, TLS: true}) {
+		t.Errorf("expected configured Tempo endpoint, got %v", endpoint)
+	}
+}
@@ -0,0 +1,64 @@
+package common
+
+import (
+	"strings"
+)
+
+// Reasons of the events emitted on the cluster objects.
+const (
+	// ObservabilityBundleTooOldForTracingReason is used when tracing is enabled but the observability bundle does not support it.
+	ObservabilityBundleTooOldForTracingReason = "ObservabilityBundleTooOldForTracing"
+	// LokiEndpointMissingReason is used when the Loki endpoint used to compute the logging URL cannot be read.
+	LokiEndpointMissingReason = "LokiEndpointMissing"
+	// TempoEndpointMissingReason is used when the Tempo endpoint used to compute the tracing endpoint cannot be read.
+	TempoEndpointMissingReason = "TempoEndpointMissing"
+	// CABundleMissingReason is used when the configured CA bundle cannot be read or is invalid.
+	CABundleMissingReason = "CABundleMissing"
+	// AuthSecretMissingReason is used when the credentials of the cluster are not available yet.
+	AuthSecretMissingReason = "AuthSecretMissing"
+	// ClientCertificateNotReadyReason is used when the client certificate of the cluster is not issued yet or is invalid.
+	ClientCertificateNotReadyReason = "ClientCertificateNotReady"
+	// ClientCertificateNotRenewedReason is used when the client certificate of the cluster was not renewed in time.
+	ClientCertificateNotRenewedReason = "ClientCertificateNotRenewed"
+	// InvalidProxyReason is used when the proxy configured for the cluster is invalid.
+	InvalidProxyReason = "InvalidProxy"
+	// InvalidValuesOverridesReason is used when the values overrides of the cluster cannot be read or merged.
+	InvalidValuesOverridesReason = "InvalidValuesOverrides"
+	// InvalidTemplateReason is used when the replacement template of a resource is invalid and the built-in one is used instead.
+	InvalidTemplateReason = "InvalidTemplate"
+	// InvalidTenantsReason is used when tenants are left out of the configuration because their name is invalid.
+	InvalidTenantsReason = "InvalidTenants"
+	// InvalidRevisionPinReason is used when the revision a cluster is pinned to by its annotation is invalid or missing.
+	InvalidRevisionPinReason = "InvalidRevisionPin"
+	// RolloutFailedReason is used when the Alloy workloads of a cluster do not become healthy after a configuration
+	// update, which pauses the rollout.
+	RolloutFailedReason = "RolloutFailed"
+	// ApplyConflictReason is used when applying a managed object conflicts with fields owned by another field manager.
+	ApplyConflictReason = "ApplyConflict"
+)
+
+// Actions performed on the objects managed for a cluster.
+const (
+	CreatedAction = "Created"
+	UpdatedAction = "Updated"
+	DeletedAction = "Deleted"
+	FailedAction  = "Failed"
+)
+
+// EventReason returns the reason of the event emitted when the given action is performed
+// on the object managed by a resource, e.g. LoggingConfigUpdated.
+func EventReason(resourceName, action string) string {
+	return CamelCase(resourceName) + action
+}
+
+// CamelCase converts a resource name like events-logger-config to EventsLoggerConfig.
+func CamelCase(name string) string {
+	var camelCase strings.Builder
+	for _, part := range strings.Split(name, "-") {
+		if part == "" {
+			continue
+		}
+		camelCase.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return camelCase.String()
+}
@@ -5,20 +5,24 @@ import (
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
-	capi "sigs.k8s.io/cluster-api/api/core/v1beta1" //nolint:staticcheck // SA1019 deprecated package
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/blang/semver"
 	appv1 "github.com/giantswarm/apiextensions-application/api/v1alpha1"
+
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
 )
 
+// TracingObservabilityBundleVersion is the first observability bundle version able to send traces to Tempo (release v30+).
+var TracingObservabilityBundleVersion = semver.MustParse("1.11.0")
+
 const (
 	observabilityBundleConfigMapName        = "observability-bundle-logging-extraconfig"
 	observabilityBundleAppName       string = "observability-bundle"
 )
 
 // ObservabilityBundleAppMeta returns metadata for the observability bundle app.
-func ObservabilityBundleAppMeta(cluster *capi.Cluster) metav1.ObjectMeta {
+func ObservabilityBundleAppMeta(cluster *capicluster.Cluster) metav1.ObjectMeta {
 	metadata := metav1.ObjectMeta{
 		Name:      AppConfigName(cluster, observabilityBundleAppName),
 		Namespace: cluster.GetNamespace(),
@@ -30,7 +34,7 @@ func ObservabilityBundleAppMeta(cluster *capi.Cluster) metav1.ObjectMeta {
 }
 
 // ObservabilityBundleConfigMapMeta returns metadata for the observability bundle extra values configmap.
-func ObservabilityBundleConfigMapMeta(cluster *capi.Cluster) metav1.ObjectMeta {
+func ObservabilityBundleConfigMapMeta(cluster *capicluster.Cluster) metav1.ObjectMeta {
 	metadata := metav1.ObjectMeta{
 		Name:      AppConfigName(cluster, observabilityBundleConfigMapName),
 		Namespace: cluster.GetNamespace(),
@@ -44,7 +48,7 @@ func ObservabilityBundleConfigMapMeta(cluster *capi.Cluster) metav1.ObjectMeta {
 	return metadata
 }
 
-func GetObservabilityBundleAppVersion(ctx context.Context, client client.Client, cluster *capi.Cluster) (version semver.Version, err error) {
+func GetObservabilityBundleAppVersion(ctx context.Context, client client.Client, cluster *capicluster.Cluster) (version semver.Version, err error) {
 	// Get observability bundle app metadata.
 	appMeta := ObservabilityBundleAppMeta(cluster)
 	// Retrieve the app.
@@ -0,0 +1,176 @@
+package common
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/logging-operator/pkg/alloyvalues"
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
+	"github.com/giantswarm/logging-operator/pkg/key"
+)
+
+// valuesOverridesSuffix is the suffix of the name of the configmap holding the values overrides of a cluster.
+const valuesOverridesSuffix = "-logging-overrides"
+
+// deniedValuesOverrides are the values which cannot be overridden: the Alloy configuration, which holds the
+// credentials and routes logs to the tenants, the environment, which holds the proxy settings and could expose
+// the credentials, the tenant routing of the pod logs, and the values granting the Alloy pods more access to the
+// workload cluster: their network policy, image, host access and security context, the policy exceptions and
+// the extra objects created by the chart. The hash of the configuration the pods are annotated with is followed by
+// the rollout and cannot be overridden either.
+var deniedValuesOverrides = []string{
+	"alloy.alloy.configMap",
+	"alloy.alloy.envFrom",
+	"alloy.alloy.extraEnv",
+	"alloy.alloy.extraSecretEnv",
+	"alloy.alloy.mounts",
+	"alloy.alloy.securityContext",
+	"alloy.controller.hostNetwork",
+	"alloy.controller.hostPID",
+	"alloy.controller.podAnnotations." + alloyvalues.ConfigHashAnnotation,
+	"alloy.controller.volumes",
+	"alloy.extraObjects",
+	"alloy.image",
+	"kyvernoPolicyExceptions",
+	"networkPolicy",
+	"podLogs",
+}
+
+// ValuesOverridesName returns the name of the configmap holding the values overrides of the cluster:
+// the one referenced by the overrides annotation, <cluster>-logging-overrides otherwise.
+func ValuesOverridesName(cluster *capicluster.Cluster) string {
+	if name := cluster.GetAnnotations()[key.ValuesOverridesAnnotation]; name != "" {
+		return name
+	}
+	return cluster.GetName() + valuesOverridesSuffix
+}
+
+// IsValuesOverrides returns true if the given configmap may hold the values overrides of clusters: it is named
+// <cluster>-logging-overrides or carries the overrides label.
+func IsValuesOverrides(object client.Object) bool {
+	return strings.HasSuffix(object.GetName(), valuesOverridesSuffix) || object.GetLabels()[key.ValuesOverridesLabel] == "true"
+}
+
+// ReadValuesOverrides returns the values overriding the values generated by the given resource, read from the key
+// named after the resource in the overrides configmap of the cluster. Nothing is returned when the configmap
+// or the key does not exist. A configmap referenced by the overrides annotation must carry the overrides label,
+// as the changes of the other configmaps are not watched.
+func ReadValuesOverrides(ctx context.Context, c client.Client, cluster *capicluster.Cluster, resourceName string) (map[string]any, error) {
+	name := types.NamespacedName{Name: ValuesOverridesName(cluster), Namespace: cluster.GetNamespace()}
+
+	var configmap v1.ConfigMap
+	err := c.Get(ctx, name, &configmap)
+	if apimachineryerrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if !IsValuesOverrides(&configmap) {
+		return nil, errors.Errorf("configmap %s referenced by the %s annotation must be labelled %s=true", name, key.ValuesOverridesAnnotation, key.ValuesOverridesLabel)
+	}
+
+	var overrides map[string]any
+	err = yaml.Unmarshal([]byte(configmap.Data[resourceName]), &overrides)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid values in key %s of configmap %s", resourceName, name)
+	}
+	return overrides, nil
+}
+
+// OverrideValues merges the values overrides of the cluster for the given resource into the values
+// of the given configmap.
+func OverrideValues(ctx context.Context, c client.Client, cluster *capicluster.Cluster, resourceName string, configmap *v1.ConfigMap) error {
+	overrides, err := ReadValuesOverrides(ctx, c, cluster, resourceName)
+	if err != nil {
+		return err
+	}
+
+	values, err := MergeValuesOverrides(configmap.Data["values"], overrides)
+	if err != nil {
+		return errors.Wrapf(err, "key %s of configmap %s/%s", resourceName, cluster.GetNamespace(), ValuesOverridesName(cluster))
+	}
+	configmap.Data["values"] = values
+	return nil
+}
+
+// MergeValuesOverrides deep merges the overrides over the given YAML values. Maps are merged, any other value,
+// lists included, replaces the generated one. The values are returned unchanged when there are no overrides.
+func MergeValuesOverrides(values string, overrides map[string]any) (string, error) {
+	if len(overrides) == 0 {
+		return values, nil
+	}
+
+	var merged map[string]any
+	err := yaml.Unmarshal([]byte(values), &merged)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if merged == nil {
+		merged = map[string]any{}
+	}
+
+	err = mergeValues(merged, overrides, "")
+	if err != nil {
+		return "", err
+	}
+
+	out, err := yaml.Marshal(merged)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return string(out), nil
+}
+
+// mergeValues merges src into dst, the values of src under the given path.
+func mergeValues(dst, src map[string]any, path string) error {
+	// Sort the keys so that the first denied value reported does not depend on the map order.
+	keys := make([]string, 0, len(src))
+	for k := range src {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		valuePath := k
+		if path != "" {
+			valuePath = path + "." + k
+		}
+
+		srcMap, srcIsMap := src[k].(map[string]any)
+		dstMap, dstIsMap := dst[k].(map[string]any)
+		if srcIsMap && dstIsMap {
+			err := mergeValues(dstMap, srcMap, valuePath)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		// The value replaces the generated one, with all the values under it.
+		if isDeniedValuesOverride(valuePath) {
+			return errors.Errorf("%s cannot be overridden", valuePath)
+		}
+		dst[k] = src[k]
+	}
+	return nil
+}
+
+// isDeniedValuesOverride returns true if replacing the value at the given path replaces a denied value,
+// or a value under it.
+func isDeniedValuesOverride(path string) bool {
+	for _, denied := range deniedValuesOverrides {
+		if path == denied || strings.HasPrefix(denied, path+".") || strings.HasPrefix(path, denied+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,234 @@
+package common
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capiv1beta2 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
+	"github.com/giantswarm/logging-operator/pkg/key"
+)
+
+const testValues = `alloy:
+  alloy:
+    configMap:
+      content: logging {}
+    resources:
+      limits:
+        memory: 300Mi
+      requests:
+        memory: 200Mi
+  controller:
+    tolerations:
+    - key: node-role.kubernetes.io/control-plane
+podLogs:
+- name: all-pods
+`
+
+func TestMergeValuesOverrides(t *testing.T) {
+	testCases := []struct {
+		name        string
+		overrides   map[string]any
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "no overrides",
+			expected: testValues,
+		},
+		{
+			name: "maps are merged and lists replaced",
+			overrides: map[string]any{
+				"alloy": map[string]any{
+					"alloy":      map[string]any{"resources": map[string]any{"limits": map[string]any{"memory": "1Gi"}}},
+					"controller": map[string]any{"tolerations": []any{map[string]any{"operator": "Exists"}}},
+				},
+				"verticalPodAutoscaler": map[string]any{"enabled": false},
+			},
+			expected: `alloy:
+  alloy:
+    configMap:
+      content: logging {}
+    resources:
+      limits:
+        memory: 1Gi
+      requests:
+        memory: 200Mi
+  controller:
+    tolerations:
+    - operator: Exists
+podLogs:
+- name: all-pods
+verticalPodAutoscaler:
+  enabled: false
+`,
+		},
+		{
+			name:        "alloy config",
+			overrides:   map[string]any{"alloy": map[string]any{"alloy": map[string]any{"configMap": map[string]any{"content": ""}}}},
+			expectError: true,
+		},
+		{
+			name:        "parent of the alloy config",
+			overrides:   map[string]any{"alloy": map[string]any{"alloy": nil}},
+			expectError: true,
+		},
+		{
+			name:        "secret environment",
+			overrides:   map[string]any{"alloy": map[string]any{"alloy": map[string]any{"extraSecretEnv": []any{}}}},
+			expectError: true,
+		},
+		{
+			name:        "environment",
+			overrides:   map[string]any{"alloy": map[string]any{"alloy": map[string]any{"extraEnv": []any{map[string]any{"name": "HTTPS_PROXY", "value": "http://attacker:3128"}}}}},
+			expectError: true,
+		},
+		{
+			name:        "network policy",
+			overrides:   map[string]any{"networkPolicy": map[string]any{"cilium": map[string]any{"egress": []any{map[string]any{"toEntities": []any{"world"}}}}}},
+			expectError: true,
+		},
+		{
+			name:        "extra objects",
+			overrides:   map[string]any{"alloy": map[string]any{"extraObjects": []any{map[string]any{"kind": "ClusterRoleBinding"}}}},
+			expectError: true,
+		},
+		{
+			name:        "image",
+			overrides:   map[string]any{"alloy": map[string]any{"image": map[string]any{"repository": "attacker/alloy"}}},
+			expectError: true,
+		},
+		{
+			name:        "config hash",
+			overrides:   map[string]any{"alloy": map[string]any{"controller": map[string]any{"podAnnotations": map[string]any{"logging.giantswarm.io/config-hash": "pinned"}}}},
+			expectError: true,
+		},
+		{
+			name:        "tenant routing",
+			overrides:   map[string]any{"podLogs": []any{}},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			values, err := MergeValuesOverrides(testValues, tc.overrides)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("expected error, got %s", values)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, values); diff != "" {
+				t.Errorf("unexpected values, diff:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIsValuesOverrides(t *testing.T) {
+	testCases := []struct {
+		name     string
+		labels   map[string]string
+		expected bool
+	}{
+		{name: "test-cluster-logging-overrides", expected: true},
+		{name: "shared-overrides", labels: map[string]string{key.ValuesOverridesLabel: "true"}, expected: true},
+		{name: "shared-overrides"},
+		{name: "test-cluster-logging-config"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			configmap := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: tc.name, Namespace: "org-test", Labels: tc.labels}}
+			if overrides := IsValuesOverrides(configmap); overrides != tc.expected {
+				t.Errorf("expected %t, got %t", tc.expected, overrides)
+			}
+		})
+	}
+}
+
+func TestReadValuesOverrides(t *testing.T) {
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		objects     []client.Object
+		expected    map[string]any
+		expectError bool
+	}{
+		{
+			name: "no configmap",
+		},
+		{
+			name: "naming convention",
+			objects: []client.Object{&v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-cluster-logging-overrides", Namespace: "org-test"},
+				Data:       map[string]string{"logging-config": "verticalPodAutoscaler:\n  enabled: false\n"},
+			}},
+			expected: map[string]any{"verticalPodAutoscaler": map[string]any{"enabled": false}},
+		},
+		{
+			name:        "annotation",
+			annotations: map[string]string{key.ValuesOverridesAnnotation: "overrides"},
+			objects: []client.Object{&v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "overrides", Namespace: "org-test", Labels: map[string]string{key.ValuesOverridesLabel: "true"}},
+				Data:       map[string]string{"logging-config": "verticalPodAutoscaler:\n  enabled: false\n"},
+			}},
+			expected: map[string]any{"verticalPodAutoscaler": map[string]any{"enabled": false}},
+		},
+		{
+			name:        "annotation without label",
+			annotations: map[string]string{key.ValuesOverridesAnnotation: "overrides"},
+			objects: []client.Object{&v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "overrides", Namespace: "org-test"},
+				Data:       map[string]string{"logging-config": "verticalPodAutoscaler:\n  enabled: false\n"},
+			}},
+			expectError: true,
+		},
+		{
+			name: "other resource",
+			objects: []client.Object{&v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-cluster-logging-overrides", Namespace: "org-test"},
+				Data:       map[string]string{"events-logger-config": "verticalPodAutoscaler:\n  enabled: false\n"},
+			}},
+		},
+		{
+			name: "invalid values",
+			objects: []client.Object{&v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-cluster-logging-overrides", Namespace: "org-test"},
+				Data:       map[string]string{"logging-config": "- not a map\n"},
+			}},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithObjects(tc.objects...).Build()
+			cluster := capicluster.New(&capiv1beta2.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", Namespace: "org-test", Annotations: tc.annotations}})
+
+			overrides, err := ReadValuesOverrides(context.Background(), c, cluster, "logging-config")
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("expected error, got %v", overrides)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, overrides); diff != "" {
+				t.Errorf("unexpected overrides, diff:\n%s", diff)
+			}
+		})
+	}
+}
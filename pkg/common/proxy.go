@@ -0,0 +1,169 @@
+package common
+
+import (
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
+	"github.com/giantswarm/logging-operator/pkg/config"
+	"github.com/giantswarm/logging-operator/pkg/key"
+)
+
+// proxySchemes maps the supported proxy schemes to their default port.
+var proxySchemes = map[string]string{"http": "80", "https": "443", "socks5": "1080"}
+
+// defaultNoProxy are always reached without the proxy: the local addresses and the in-cluster services.
+// The Kubernetes API is reached through its service IP, which is in the services CIDR of the cluster.
+var defaultNoProxy = []string{"localhost", "127.0.0.1", ".svc", ".cluster.local", "kubernetes.default.svc"}
+
+// noProxyEntryPattern matches a host, domain, IP address or CIDR block, optionally with a port or a wildcard.
+var noProxyEntryPattern = regexp.MustCompile(`^[A-Za-z0-9.*:/_\[\]-]+$`)
+
+// Proxy is the proxy a workload cluster sends its logs and traces through.
+type Proxy struct {
+	// URL is the URL of the proxy, empty when the cluster does not use one.
+	URL string
+	// Host and Port are the address of the proxy, allowed by the network policy of Alloy.
+	Host string
+	Port string
+	// NoProxy is the comma separated list of hosts, domains and CIDR blocks reached without the proxy.
+	NoProxy string
+}
+
+// Enabled returns true when the cluster uses a proxy.
+func (p Proxy) Enabled() bool {
+	return p.URL != ""
+}
+
+// CIDR returns the CIDR block of the proxy when its host is an IP address, or an empty string
+// when it is a host name.
+func (p Proxy) CIDR() string {
+	ip := net.ParseIP(p.Host)
+	switch {
+	case ip == nil:
+		return ""
+	case ip.To4() != nil:
+		return ip.String() + "/32"
+	default:
+		return ip.String() + "/128"
+	}
+}
+
+// ValidateProxyConfig checks the proxy URL and no proxy entries are valid.
+func ValidateProxyConfig(cfg config.Config) error {
+	if cfg.ProxyURL != "" {
+		_, err := parseProxyURL(cfg.ProxyURL)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return validateNoProxy(cfg.NoProxy)
+}
+
+// ReadProxy returns the proxy the cluster sends its logs and traces through. The proxy annotations of the cluster
+// take precedence over the installation configuration. The management cluster never uses a proxy, it sends
+// logs and traces to the in-cluster services.
+func ReadProxy(cluster *capicluster.Cluster, cfg config.Config) (Proxy, error) {
+	if !IsWorkloadCluster(cfg.InstallationName, cluster.GetName()) {
+		return Proxy{}, nil
+	}
+
+	proxyURL, noProxy := cfg.ProxyURL, cfg.NoProxy
+	annotations := cluster.GetAnnotations()
+	if value, ok := annotations[key.ProxyAnnotation]; ok {
+		proxyURL = strings.TrimSpace(value)
+	}
+	if value, ok := annotations[key.NoProxyAnnotation]; ok {
+		noProxy = splitNoProxy(value)
+	}
+	if proxyURL == "" {
+		return Proxy{}, nil
+	}
+
+	proxy, err := parseProxyURL(proxyURL)
+	if err != nil {
+		return Proxy{}, errors.WithStack(err)
+	}
+	err = validateNoProxy(noProxy)
+	if err != nil {
+		return Proxy{}, errors.WithStack(err)
+	}
+
+	var entries []string
+	for _, entry := range slices.Concat(defaultNoProxy, cluster.NetworkCIDRs(), noProxy) {
+		if !slices.Contains(entries, entry) {
+			entries = append(entries, entry)
+		}
+	}
+	proxy.NoProxy = strings.Join(entries, ",")
+
+	return proxy, nil
+}
+
+// parseProxyURL parses a proxy URL made of a scheme, a host and an optional port.
+// The URL is not included in the errors as it may hold credentials.
+func parseProxyURL(proxyURL string) (Proxy, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return Proxy{}, errors.New("invalid proxy URL: must be an absolute URL")
+	}
+
+	defaultPort, ok := proxySchemes[u.Scheme]
+	if !ok {
+		return Proxy{}, errors.New("invalid proxy URL: scheme must be http, https or socks5")
+	}
+	if u.User != nil {
+		return Proxy{}, errors.New("invalid proxy URL: credentials are not supported")
+	}
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return Proxy{}, errors.New("invalid proxy URL: must be a scheme, host and optional port")
+	}
+
+	host := u.Hostname()
+	if net.ParseIP(host) == nil && len(validation.IsDNS1123Subdomain(host)) > 0 {
+		return Proxy{}, errors.Errorf("invalid proxy URL: invalid host %q", host)
+	}
+
+	port := u.Port()
+	if port == "" {
+		port = defaultPort
+	}
+	if p, err := strconv.ParseUint(port, 10, 16); err != nil || p == 0 {
+		return Proxy{}, errors.Errorf("invalid proxy URL: invalid port %q", port)
+	}
+
+	return Proxy{
+		URL:  u.Scheme + "://" + net.JoinHostPort(host, port),
+		Host: host,
+		Port: port,
+	}, nil
+}
+
+// validateNoProxy checks the no proxy entries can be written as is in the Alloy configuration.
+func validateNoProxy(entries []string) error {
+	for _, entry := range entries {
+		if !noProxyEntryPattern.MatchString(entry) {
+			return errors.Errorf("invalid no proxy entry %q: must be a host, domain, IP address or CIDR block", entry)
+		}
+	}
+	return nil
+}
+
+// splitNoProxy splits a comma separated no proxy list, ignoring empty entries.
+func splitNoProxy(value string) []string {
+	var entries []string
+	for entry := range strings.SplitSeq(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
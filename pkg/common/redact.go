@@ -0,0 +1,56 @@
+package common
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// RedactedValue replaces the secret values in redacted output.
+const RedactedValue = "<redacted>"
+
+// RedactSecretValues returns the given Alloy secret values with the credentials replaced by RedactedValue.
+// Both the extraSecretEnv entries and the map keys whose name contains "password", or holding the client certificate
+// private key, are redacted.
+func RedactSecretValues(values []byte) ([]byte, error) {
+	var data any
+	err := yaml.Unmarshal(values, &data)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	redacted, err := yaml.Marshal(redact(data))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return redacted, nil
+}
+
+func redact(data any) any {
+	switch value := data.(type) {
+	case map[string]any:
+		// extraSecretEnv entries are name/value pairs.
+		if name, ok := value["name"].(string); ok && isSecretKey(name) {
+			if _, ok := value["value"]; ok {
+				value["value"] = RedactedValue
+			}
+		}
+		for key, item := range value {
+			if isSecretKey(key) {
+				value[key] = RedactedValue
+				continue
+			}
+			value[key] = redact(item)
+		}
+	case []any:
+		for i, item := range value {
+			value[i] = redact(item)
+		}
+	}
+	return data
+}
+
+func isSecretKey(key string) bool {
+	return strings.Contains(strings.ToLower(key), "password") || key == TLSKeyPEM
+}
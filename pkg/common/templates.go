@@ -0,0 +1,190 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/logging-operator/pkg/alloy"
+	"github.com/giantswarm/logging-operator/pkg/config"
+)
+
+// syntheticClusterID is the name of the workload cluster the replacement templates are validated with.
+const syntheticClusterID = "synthetic"
+
+// ConfigTemplateData is the data the replacement templates of the Alloy configurations are rendered with.
+// The templates render the final configuration as text: they patch the configuration built by the operator, append
+// components to it, replace some of its components, or replace the whole pipeline. Credentials are not given to the
+// templates, a replaced component reads them like the built-in one, from the credentials component.
+type ConfigTemplateData struct {
+	// Config is the Alloy configuration built by the operator, for templates patching it, e.g. with the replace function.
+	Config string
+	// Components are the top level components of Config in order, for templates replacing some of them: Config is the
+	// texts of the components joined with empty lines.
+	Components []alloy.PrintedComponent
+	// Settings are the inputs Config is built from, for templates writing their own components.
+	Settings ConfigSettings
+	ClusterLabels
+	IsWorkloadCluster bool
+	// Tenants are the names of the tenants logs and traces are routed to.
+	Tenants []string
+}
+
+// ConfigSettings are the inputs an Alloy configuration is built from, besides the cluster labels and the tenants.
+// The settings which do not apply to the configuration, like the events namespaces for the logs agent, are empty.
+type ConfigSettings struct {
+	// ManagementClusterLokiURLs are the Loki URLs of the management cluster, which workload clusters read from
+	// their credentials instead.
+	ManagementClusterLokiURLs LokiURLs
+	// TempoEndpoint is the endpoint the events logger sends traces to, when tracing is enabled.
+	TempoEndpoint Endpoint
+	InsecureCA    bool
+	// TLSKeys are the keys of the TLS material in the credentials.
+	TLSKeys TLSKeys
+	Proxy   Proxy
+	// NodeFiltering, NetworkMonitoring and Tracing tell which optional parts of the pipelines are enabled.
+	NodeFiltering     bool
+	NetworkMonitoring bool
+	Tracing           bool
+	// IncludeNamespaces and ExcludeNamespaces are the namespaces the events logger collects events from, or not.
+	IncludeNamespaces []string
+	ExcludeNamespaces []string
+}
+
+// NewConfigTemplateData returns the data the replacement template of an Alloy configuration is rendered with,
+// for the configuration built for the cluster with the given labels.
+func NewConfigTemplateData(alloyConfig string, components []alloy.PrintedComponent, settings ConfigSettings, clusterLabels ClusterLabels, tenants []Tenant) ConfigTemplateData {
+	data := ConfigTemplateData{
+		Config:            alloyConfig,
+		Components:        components,
+		Settings:          settings,
+		ClusterLabels:     clusterLabels,
+		IsWorkloadCluster: IsWorkloadCluster(clusterLabels.Installation, clusterLabels.ClusterID),
+	}
+	for _, tenant := range tenants {
+		data.Tenants = append(data.Tenants, tenant.Name)
+	}
+	return data
+}
+
+// SyntheticClusterLabels returns the labels of the workload cluster the replacement templates are validated with
+// before being used for the actual clusters.
+func SyntheticClusterLabels(cfg config.Config) ClusterLabels {
+	return ClusterLabels{
+		ClusterID:    syntheticClusterID,
+		ClusterType:  "workload_cluster",
+		Installation: cfg.InstallationName,
+		Organization: syntheticClusterID,
+		Provider:     syntheticClusterID,
+	}
+}
+
+// ValidateTemplatesConfig checks that the templates configmap, when configured, is given as <namespace>/<name>.
+func ValidateTemplatesConfig(cfg config.Config) error {
+	if cfg.TemplatesConfigMap == "" {
+		return nil
+	}
+	_, err := parseObjectReference(cfg.TemplatesConfigMap)
+	return err
+}
+
+// IsTemplatesConfigMap returns true if the given configmap holds the configured replacement templates.
+func IsTemplatesConfigMap(cfg config.Config, object client.Object) bool {
+	return cfg.TemplatesConfigMap != "" && cfg.TemplatesConfigMap == object.GetNamespace()+"/"+object.GetName()
+}
+
+// ReadTemplate returns the replacement template of the given resource, read from the key named after the resource
+// in the templates configmap and parsed with the sprig functions. Alloy configuration templates are rendered with
+// ConfigTemplateData. Nothing is returned when no templates configmap is configured, or when the configmap or the
+// key does not exist.
+func ReadTemplate(ctx context.Context, c client.Client, cfg config.Config, resourceName string) (*template.Template, error) {
+	configmap, err := readTemplatesConfigMap(ctx, c, cfg)
+	if err != nil || configmap == nil {
+		return nil, err
+	}
+
+	text, ok := configmap.Data[resourceName]
+	if !ok {
+		return nil, nil
+	}
+
+	tmpl, err := template.New(resourceName).Funcs(sprig.FuncMap()).Parse(text)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid template in key %s of configmap %s", resourceName, cfg.TemplatesConfigMap)
+	}
+	return tmpl, nil
+}
+
+// TemplatesHash returns the hash of the replacement templates, which is empty when no templates configmap is
+// configured or when it does not exist.
+func TemplatesHash(ctx context.Context, c client.Client, cfg config.Config) (string, error) {
+	configmap, err := readTemplatesConfigMap(ctx, c, cfg)
+	if err != nil || configmap == nil {
+		return "", err
+	}
+	return ConfigHash(*configmap), nil
+}
+
+// readTemplatesConfigMap returns the templates configmap, or nothing when it is not configured or does not exist.
+func readTemplatesConfigMap(ctx context.Context, c client.Client, cfg config.Config) (*v1.ConfigMap, error) {
+	if cfg.TemplatesConfigMap == "" {
+		return nil, nil
+	}
+
+	name, err := parseObjectReference(cfg.TemplatesConfigMap)
+	if err != nil {
+		return nil, err
+	}
+
+	var configmap v1.ConfigMap
+	err = c.Get(ctx, name, &configmap)
+	if apimachineryerrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &configmap, nil
+}
+
+// TemplateError is returned when a template fails to render a valid output.
+type TemplateError struct {
+	Name string
+	Err  error
+}
+
+func (e *TemplateError) Error() string {
+	return fmt.Sprintf("template %s: %s", e.Name, e.Err)
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// IsTemplateError returns true if the error, or an error it wraps, is a TemplateError.
+func IsTemplateError(err error) bool {
+	var templateErr *TemplateError
+	return errors.As(err, &templateErr)
+}
+
+// RenderTemplate renders the template with the given data and checks the output with the validate function.
+func RenderTemplate(tmpl *template.Template, data any, validate func(string) error) (string, error) {
+	var out bytes.Buffer
+	err := tmpl.Execute(&out, data)
+	if err != nil {
+		return "", errors.WithStack(&TemplateError{Name: tmpl.Name(), Err: err})
+	}
+
+	err = validate(out.String())
+	if err != nil {
+		return "", errors.WithStack(&TemplateError{Name: tmpl.Name(), Err: errors.Wrap(err, "invalid output")})
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,164 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"text/template"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/logging-operator/pkg/config"
+)
+
+func TestReadTemplate(t *testing.T) {
+	meta := metav1.ObjectMeta{Name: "logging-operator-templates", Namespace: "giantswarm"}
+	cfg := config.Config{TemplatesConfigMap: "giantswarm/logging-operator-templates"}
+
+	testCases := []struct {
+		name        string
+		cfg         config.Config
+		objects     []client.Object
+		expected    string
+		expectError bool
+	}{
+		{
+			name: "not configured",
+			objects: []client.Object{&v1.ConfigMap{
+				ObjectMeta: meta,
+				Data:       map[string]string{"logging-config": "{{ .Config }}"},
+			}},
+		},
+		{
+			name: "no configmap",
+			cfg:  cfg,
+		},
+		{
+			name: "other resource",
+			cfg:  cfg,
+			objects: []client.Object{&v1.ConfigMap{
+				ObjectMeta: meta,
+				Data:       map[string]string{"events-logger-config": "{{ .Config }}"},
+			}},
+		},
+		{
+			name: "template",
+			cfg:  cfg,
+			objects: []client.Object{&v1.ConfigMap{
+				ObjectMeta: meta,
+				Data:       map[string]string{"logging-config": `{{ .Config | replace "warn" "info" }}`},
+			}},
+			expected: "logging {\n\tlevel = \"info\"\n}\n",
+		},
+		{
+			name: "invalid template",
+			cfg:  cfg,
+			objects: []client.Object{&v1.ConfigMap{
+				ObjectMeta: meta,
+				Data:       map[string]string{"logging-config": "{{ .Config"},
+			}},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithObjects(tc.objects...).Build()
+
+			tmpl, err := ReadTemplate(context.Background(), c, tc.cfg, "logging-config")
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("expected error, got template %v", tmpl)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.expected == "" {
+				if tmpl != nil {
+					t.Errorf("expected no template, got %s", tmpl.Name())
+				}
+				return
+			}
+
+			out, err := RenderTemplate(tmpl, ConfigTemplateData{Config: "logging {\n\tlevel = \"warn\"\n}\n"}, func(string) error { return nil })
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, out)
+			}
+		})
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	validate := func(out string) error {
+		if out == "" {
+			return errors.New("empty output")
+		}
+		return nil
+	}
+
+	testCases := []struct {
+		name        string
+		template    string
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "valid output",
+			template: "{{ .ClusterID }} {{ range .Tenants }}{{ . }} {{ end }}",
+			expected: "test-cluster giantswarm test ",
+		},
+		{
+			name:        "execution error",
+			template:    "{{ .Unknown }}",
+			expectError: true,
+		},
+		{
+			name:        "invalid output",
+			template:    "{{ if .Config }}{{ .Config }}{{ end }}",
+			expectError: true,
+		},
+	}
+
+	tenants, _ := SanitizeTenants([]string{"giantswarm", "test"})
+	data := NewConfigTemplateData("", nil, ConfigSettings{}, ClusterLabels{ClusterID: "test-cluster", Installation: "test-installation"}, tenants)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpl := template.Must(template.New(tc.name).Parse(tc.template))
+
+			out, err := RenderTemplate(tmpl, data, validate)
+			if tc.expectError {
+				if !IsTemplateError(err) {
+					t.Errorf("expected template error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, out)
+			}
+		})
+	}
+}
+
+func TestIsTemplatesConfigMap(t *testing.T) {
+	cfg := config.Config{TemplatesConfigMap: "giantswarm/logging-operator-templates"}
+	meta := metav1.ObjectMeta{Name: "logging-operator-templates", Namespace: "giantswarm"}
+
+	if !IsTemplatesConfigMap(cfg, &v1.ConfigMap{ObjectMeta: meta}) {
+		t.Errorf("expected the configmap to hold the templates")
+	}
+	if IsTemplatesConfigMap(config.Config{}, &v1.ConfigMap{ObjectMeta: meta}) {
+		t.Errorf("expected no templates without configuration")
+	}
+}
@@ -0,0 +1,90 @@
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+// validTenantName matches the tenant IDs accepted by Loki and Tempo.
+var validTenantName = regexp.MustCompile(`^[a-zA-Z0-9!_.*'()-]{1,150}$`)
+
+// invalidIdentifierCharacters matches the characters which are not allowed in Alloy identifiers.
+var invalidIdentifierCharacters = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// Tenant is a tenant ready to be templated in Alloy configurations.
+type Tenant struct {
+	// Name is the tenant ID as known by Loki and Tempo, used in string values.
+	Name string
+	// ID is an Alloy identifier derived from the name, used as component label and in component references.
+	ID string
+}
+
+// Regex returns the tenant name escaped to be matched literally in a regular expression.
+func (t Tenant) Regex() string {
+	return regexp.QuoteMeta(t.Name)
+}
+
+// TenantNamePattern describes the valid tenant names, for error messages.
+const TenantNamePattern = "1 to 150 letters, digits or !_.*'()- characters, except \".\" and \"..\""
+
+// IsValidTenantName returns true if the given name is a valid Loki and Tempo tenant ID.
+func IsValidTenantName(name string) bool {
+	return validTenantName.MatchString(name) && name != "." && name != ".."
+}
+
+// SanitizeTenants returns the given tenants, deduplicated and in order, with their Alloy identifiers,
+// as well as the invalid tenant names which were left out.
+// Identifiers are derived from the names by replacing the characters not allowed in identifiers with
+// underscores. When two names map to the same identifier, a hash of the name is appended to the second one.
+func SanitizeTenants(names []string) ([]Tenant, []string) {
+	var tenants []Tenant
+	var invalid []string
+	seenNames := map[string]bool{}
+	seenIDs := map[string]bool{}
+
+	for _, name := range names {
+		if seenNames[name] {
+			continue
+		}
+		seenNames[name] = true
+
+		if !IsValidTenantName(name) {
+			invalid = append(invalid, name)
+			continue
+		}
+
+		id := invalidIdentifierCharacters.ReplaceAllString(name, "_")
+		if id[0] >= '0' && id[0] <= '9' {
+			id = "_" + id
+		}
+		if seenIDs[id] {
+			hash := sha256.Sum256([]byte(name))
+			id += "_" + hex.EncodeToString(hash[:4])
+		}
+		seenIDs[id] = true
+
+		tenants = append(tenants, Tenant{Name: name, ID: id})
+	}
+
+	return tenants, invalid
+}
+
+// TenantsRegex returns a regular expression matching exactly one of the given tenant names.
+func TenantsRegex(tenants []Tenant) string {
+	regexes := make([]string, 0, len(tenants))
+	for _, tenant := range tenants {
+		regexes = append(regexes, tenant.Regex())
+	}
+	return "^(" + strings.Join(regexes, "|") + ")$"
+}
+
+// TenantNames returns the names of the given tenants.
+func TenantNames(tenants []Tenant) []string {
+	names := make([]string, 0, len(tenants))
+	for _, tenant := range tenants {
+		names = append(names, tenant.Name)
+	}
+	return names
+}
@@ -0,0 +1,63 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSanitizeTenants(t *testing.T) {
+	testCases := []struct {
+		name            string
+		names           []string
+		expectedTenants []Tenant
+		expectedInvalid []string
+	}{
+		{
+			name:            "valid tenants",
+			names:           []string{"giantswarm", "team-a", "team.b"},
+			expectedTenants: []Tenant{{Name: "giantswarm", ID: "giantswarm"}, {Name: "team-a", ID: "team_a"}, {Name: "team.b", ID: "team_b"}},
+		},
+		{
+			name:            "duplicated tenants",
+			names:           []string{"giantswarm", "giantswarm"},
+			expectedTenants: []Tenant{{Name: "giantswarm", ID: "giantswarm"}},
+		},
+		{
+			name:            "identifier collision",
+			names:           []string{"team.a", "team-a"},
+			expectedTenants: []Tenant{{Name: "team.a", ID: "team_a"}, {Name: "team-a", ID: "team_a_96c2886c"}},
+		},
+		{
+			name:            "leading digit",
+			names:           []string{"3rd-party"},
+			expectedTenants: []Tenant{{Name: "3rd-party", ID: "_3rd_party"}},
+		},
+		{
+			name:            "invalid tenants",
+			names:           []string{"", ".", "..", `bad"tenant`, "bad tenant", "giantswarm"},
+			expectedTenants: []Tenant{{Name: "giantswarm", ID: "giantswarm"}},
+			expectedInvalid: []string{"", ".", "..", `bad"tenant`, "bad tenant"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tenants, invalid := SanitizeTenants(tc.names)
+			if !reflect.DeepEqual(tenants, tc.expectedTenants) {
+				t.Errorf("expected tenants %v, got %v", tc.expectedTenants, tenants)
+			}
+			if !reflect.DeepEqual(invalid, tc.expectedInvalid) {
+				t.Errorf("expected invalid tenants %v, got %v", tc.expectedInvalid, invalid)
+			}
+		})
+	}
+}
+
+func TestTenantsRegex(t *testing.T) {
+	tenants := []Tenant{{Name: "team.a", ID: "team_a"}, {Name: "3rd(party)*", ID: "_3rd_party__"}}
+
+	expected := `^(team\.a|3rd\(party\)\*)$`
+	if regex := TenantsRegex(tenants); regex != expected {
+		t.Errorf("expected regex %s, got %s", expected, regex)
+	}
+}
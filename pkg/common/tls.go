@@ -0,0 +1,38 @@
+package common
+
+import (
+	"github.com/giantswarm/logging-operator/pkg/config"
+)
+
+const (
+	// TLSCAPEM is the key of the CA bundle in the logging and events logger secrets.
+	TLSCAPEM = "tls-ca-pem"
+	// TLSCertPEM and TLSKeyPEM are the keys of the client certificate and its private key in the logging
+	// and events logger secrets.
+	TLSCertPEM = "tls-cert-pem"
+	TLSKeyPEM  = "tls-key-pem" // #nosec G101
+)
+
+// TLSKeys are the keys of the TLS material in the logging and events logger secrets.
+// A key is empty when the matching material is not distributed.
+type TLSKeys struct {
+	// CAPEM is the key of the CA bundle verifying the Loki and Tempo certificates.
+	CAPEM string
+	// CertPEM and KeyPEM are the keys of the client certificate authenticating the cluster, used instead of basic auth.
+	CertPEM string
+	KeyPEM  string
+}
+
+// SecretTLSKeys returns the keys of the TLS material distributed in the logging and events logger secrets
+// for the given configuration.
+func SecretTLSKeys(cfg config.Config) TLSKeys {
+	var keys TLSKeys
+	if HasCABundle(cfg) {
+		keys.CAPEM = TLSCAPEM
+	}
+	if IsMTLSAuth(cfg) {
+		keys.CertPEM = TLSCertPEM
+		keys.KeyPEM = TLSKeyPEM
+	}
+	return keys
+}
@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 // Config holds the global configuration for the logging operator
 // This replaces the loggedcluster.Options struct
 type Config struct {
@@ -11,4 +13,54 @@ type Config struct {
 	EnableNetworkMonitoringFlag bool
 	InstallationName            string
 	InsecureCA                  bool
+	// CABundleSecret and CABundleConfigMap reference, as <namespace>/<name>, the secret or configmap holding
+	// the CA bundle verifying the Loki and Tempo certificates under the CABundleKey key.
+	CABundleSecret              string
+	CABundleConfigMap           string
+	CABundleKey                 string
+	DefaultNamespaces           []string
+	IncludeEventsFromNamespaces []string
+	ExcludeEventsFromNamespaces []string
+	// EndpointSource is where the Loki and Tempo endpoints are read from: auto, ingress, httproute or static.
+	EndpointSource string
+	// LokiEndpoint and TempoEndpoint are the static endpoints, used instead of the discovered ones when set.
+	LokiEndpoint  string
+	TempoEndpoint string
+	// LokiPushURL and LokiRulerURL override the Loki URLs derived from the Loki endpoint when set.
+	LokiPushURL  string
+	LokiRulerURL string
+	// ManagementClusterLokiPushURL, ManagementClusterLokiRulerURL and ManagementClusterTempoEndpoint are the
+	// endpoints the management cluster sends logs and traces to.
+	ManagementClusterLokiPushURL   string
+	ManagementClusterLokiRulerURL  string
+	ManagementClusterTempoEndpoint string
+	// AuthMode is how the clusters authenticate to Loki and Tempo: basic or mtls.
+	AuthMode string
+	// ClientCertificateIssuer and ClientCertificateIssuerKind reference the cert-manager issuer signing the client
+	// certificates of the clusters when the auth mode is mtls.
+	ClientCertificateIssuer     string
+	ClientCertificateIssuerKind string
+	// ClientCertificateDuration is the validity of the client certificates, which are renewed
+	// ClientCertificateRenewBefore their expiry.
+	ClientCertificateDuration    time.Duration
+	ClientCertificateRenewBefore time.Duration
+	// ProxyURL is the proxy the workload clusters send logs and traces through,
+	// except to the NoProxy hosts, domains and CIDR blocks.
+	ProxyURL string
+	NoProxy  []string
+	// TemplatesConfigMap references, as <namespace>/<name>, the configmap holding the replacement templates of the
+	// resources, under keys named after them.
+	TemplatesConfigMap string
+	// RolloutEnabled holds configuration updates back so that they reach the canary clusters first, selected by
+	// RolloutCanarySelector or else RolloutCanaryPercentage, and the other clusters in batches of RolloutBatchSize
+	// once the canaries are healthy. A cluster is healthy once its Alloy workloads are ready for RolloutSoakDuration,
+	// and the rollout pauses when one is not ready within RolloutHealthTimeout.
+	RolloutEnabled          bool
+	RolloutCanarySelector   string
+	RolloutCanaryPercentage int
+	RolloutBatchSize        int
+	RolloutSoakDuration     time.Duration
+	RolloutHealthTimeout    time.Duration
+	// RevisionHistoryLimit is the number of revisions kept for each generated configmap, 0 disables the history.
+	RevisionHistoryLimit int
 }
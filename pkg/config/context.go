@@ -0,0 +1,19 @@
+package config
+
+import "context"
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the configuration resolved for a single cluster.
+func NewContext(ctx context.Context, cfg Config) context.Context {
+	return context.WithValue(ctx, contextKey{}, cfg)
+}
+
+// FromContext returns the cluster configuration stored in ctx by NewContext.
+// The given fallback, usually the global configuration, is returned when ctx does not carry any.
+func FromContext(ctx context.Context, fallback Config) Config {
+	if cfg, ok := ctx.Value(contextKey{}).(Config); ok {
+		return cfg
+	}
+	return fallback
+}
@@ -4,4 +4,38 @@ const (
 	Finalizer              = "giantswarm.io/logging-operator"
 	LoggingLabel           = "giantswarm.io/logging"
 	NetworkMonitoringLabel = "giantswarm.io/network-monitoring"
+	TracingLabel           = "giantswarm.io/tracing"
+	ManagedByLabel         = "giantswarm.io/managed-by"
+	ClusterLabel           = "giantswarm.io/cluster"
 )
+
+const (
+	// ProxyAnnotation overrides, on a cluster, the URL of the proxy its logs and traces are sent through.
+	// An empty value disables the proxy for the cluster.
+	ProxyAnnotation = "giantswarm.io/logging-proxy"
+	// NoProxyAnnotation overrides, on a cluster, the comma separated hosts, domains and CIDR blocks
+	// reached without the proxy.
+	NoProxyAnnotation = "giantswarm.io/logging-no-proxy"
+)
+
+const (
+	// ValuesOverridesAnnotation references, on a cluster, the configmap of its namespace holding the values overriding
+	// the generated Alloy values, instead of the <cluster>-logging-overrides one.
+	ValuesOverridesAnnotation = "giantswarm.io/logging-overrides"
+	// ValuesOverridesLabel marks, when set to true, the configmaps referenced by the overrides annotation so that
+	// their changes are watched. Referenced configmaps without the label are rejected.
+	ValuesOverridesLabel = "giantswarm.io/logging-values-overrides"
+)
+
+const (
+	// RevisionOfLabel holds, on the revisions of a generated configmap, the name of the resource generating it.
+	RevisionOfLabel = "giantswarm.io/logging-revision-of"
+	// RevisionLabel holds, on the revisions of a generated configmap, the revision number.
+	RevisionLabel = "giantswarm.io/logging-revision"
+)
+
+// RevisionAnnotation returns the annotation pinning, on a cluster, the configmap generated by the given resource
+// to one of its revisions, e.g. giantswarm.io/logging-config-revision.
+func RevisionAnnotation(resourceName string) string {
+	return "giantswarm.io/" + resourceName + "-revision"
+}
@@ -0,0 +1,93 @@
+package loggingpolicy
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	loggingv1alpha1 "github.com/giantswarm/logging-operator/api/v1alpha1"
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
+	"github.com/giantswarm/logging-operator/pkg/config"
+)
+
+// Resolve returns the configuration to use for the given cluster: the LoggingPolicies
+// selecting the cluster are merged over the global configuration built from the operator flags.
+func Resolve(ctx context.Context, c client.Client, cluster *capicluster.Cluster, defaults config.Config) (config.Config, error) {
+	policies := &loggingv1alpha1.LoggingPolicyList{}
+	err := c.List(ctx, policies, client.InNamespace(cluster.GetNamespace()))
+	if err != nil {
+		return config.Config{}, errors.WithStack(err)
+	}
+
+	var matching []loggingv1alpha1.LoggingPolicy
+	for _, policy := range policies.Items {
+		selected, err := Selects(policy, cluster)
+		if err != nil {
+			return config.Config{}, errors.Wrapf(err, "invalid cluster selector in logging policy %s/%s", policy.GetNamespace(), policy.GetName())
+		}
+		if selected {
+			matching = append(matching, policy)
+		}
+	}
+
+	return Merge(defaults, matching), nil
+}
+
+// Selects returns true if the policy cluster selector matches the given cluster.
+func Selects(policy loggingv1alpha1.LoggingPolicy, cluster *capicluster.Cluster) (bool, error) {
+	if policy.GetNamespace() != cluster.GetNamespace() {
+		return false, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(&policy.Spec.ClusterSelector)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return selector.Matches(labels.Set(cluster.GetLabels())), nil
+}
+
+// Merge applies the policies over the given configuration.
+// Policies are applied in name order so that when several policies set the same
+// field, the result does not depend on the order they were listed in.
+func Merge(defaults config.Config, policies []loggingv1alpha1.LoggingPolicy) config.Config {
+	policies = slices.Clone(policies)
+	slices.SortFunc(policies, func(a, b loggingv1alpha1.LoggingPolicy) int {
+		return strings.Compare(a.GetName(), b.GetName())
+	})
+
+	cfg := defaults
+	for _, policy := range policies {
+		spec := policy.Spec
+		if spec.Logging != nil {
+			cfg.EnableLoggingFlag = *spec.Logging
+		}
+		if spec.NodeFiltering != nil {
+			cfg.EnableNodeFilteringFlag = *spec.NodeFiltering
+		}
+		if spec.Tracing != nil {
+			cfg.EnableTracingFlag = *spec.Tracing
+		}
+		if spec.NetworkMonitoring != nil {
+			cfg.EnableNetworkMonitoringFlag = *spec.NetworkMonitoring
+		}
+		if spec.AuthMode != "" {
+			cfg.AuthMode = spec.AuthMode
+		}
+		if spec.DefaultNamespaces != nil {
+			cfg.DefaultNamespaces = spec.DefaultNamespaces
+		}
+		if spec.IncludeEventsFromNamespaces != nil {
+			cfg.IncludeEventsFromNamespaces = spec.IncludeEventsFromNamespaces
+		}
+		if spec.ExcludeEventsFromNamespaces != nil {
+			cfg.ExcludeEventsFromNamespaces = spec.ExcludeEventsFromNamespaces
+		}
+	}
+
+	return cfg
+}
@@ -0,0 +1,166 @@
+package loggingpolicy
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capiv1beta2 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+
+	loggingv1alpha1 "github.com/giantswarm/logging-operator/api/v1alpha1"
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
+	"github.com/giantswarm/logging-operator/pkg/config"
+)
+
+func TestMerge(t *testing.T) {
+	enabled := true
+	disabled := false
+
+	defaults := config.Config{
+		EnableLoggingFlag:           true,
+		EnableNodeFilteringFlag:     false,
+		EnableTracingFlag:           true,
+		InstallationName:            "test-installation",
+		AuthMode:                    "basic",
+		DefaultNamespaces:           []string{"kube-system", "giantswarm"},
+		ExcludeEventsFromNamespaces: []string{"default"},
+	}
+
+	testCases := []struct {
+		name     string
+		policies []loggingv1alpha1.LoggingPolicy
+		expected config.Config
+	}{
+		{
+			name:     "no policy",
+			expected: defaults,
+		},
+		{
+			name: "single policy",
+			policies: []loggingv1alpha1.LoggingPolicy{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "a"},
+					Spec: loggingv1alpha1.LoggingPolicySpec{
+						NodeFiltering:     &enabled,
+						Tracing:           &disabled,
+						AuthMode:          "mtls",
+						DefaultNamespaces: []string{"kube-system", "giantswarm", "customer"},
+					},
+				},
+			},
+			expected: config.Config{
+				EnableLoggingFlag:           true,
+				EnableNodeFilteringFlag:     true,
+				EnableTracingFlag:           false,
+				InstallationName:            "test-installation",
+				AuthMode:                    "mtls",
+				DefaultNamespaces:           []string{"kube-system", "giantswarm", "customer"},
+				ExcludeEventsFromNamespaces: []string{"default"},
+			},
+		},
+		{
+			name: "policies are applied in name order",
+			policies: []loggingv1alpha1.LoggingPolicy{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "b"},
+					Spec: loggingv1alpha1.LoggingPolicySpec{
+						Logging: &disabled,
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "a"},
+					Spec: loggingv1alpha1.LoggingPolicySpec{
+						Logging:                     &enabled,
+						ExcludeEventsFromNamespaces: []string{},
+					},
+				},
+			},
+			expected: config.Config{
+				EnableLoggingFlag:           false,
+				EnableTracingFlag:           true,
+				InstallationName:            "test-installation",
+				AuthMode:                    "basic",
+				DefaultNamespaces:           []string{"kube-system", "giantswarm"},
+				ExcludeEventsFromNamespaces: []string{},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := Merge(defaults, tc.policies)
+			if diff := cmp.Diff(tc.expected, result); diff != "" {
+				t.Errorf("unexpected configuration (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSelects(t *testing.T) {
+	cluster := capicluster.New(&capiv1beta2.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-cluster",
+			Namespace: "org-test",
+			Labels: map[string]string{
+				"giantswarm.io/organization": "test",
+			},
+		},
+	})
+
+	testCases := []struct {
+		name     string
+		policy   loggingv1alpha1.LoggingPolicy
+		expected bool
+	}{
+		{
+			name: "empty selector",
+			policy: loggingv1alpha1.LoggingPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "all", Namespace: "org-test"},
+			},
+			expected: true,
+		},
+		{
+			name: "matching selector",
+			policy: loggingv1alpha1.LoggingPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "matching", Namespace: "org-test"},
+				Spec: loggingv1alpha1.LoggingPolicySpec{
+					ClusterSelector: metav1.LabelSelector{
+						MatchLabels: map[string]string{"giantswarm.io/organization": "test"},
+					},
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "non matching selector",
+			policy: loggingv1alpha1.LoggingPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "org-test"},
+				Spec: loggingv1alpha1.LoggingPolicySpec{
+					ClusterSelector: metav1.LabelSelector{
+						MatchLabels: map[string]string{"giantswarm.io/organization": "other"},
+					},
+				},
+			},
+			expected: false,
+		},
+		{
+			name: "other namespace",
+			policy: loggingv1alpha1.LoggingPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "all", Namespace: "org-other"},
+			},
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			selected, err := Selects(tc.policy, cluster)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if selected != tc.expected {
+				t.Errorf("expected %t, got %t", tc.expected, selected)
+			}
+		})
+	}
+}
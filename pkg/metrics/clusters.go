@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ClusterState is the state of a cluster exposed as metrics.
+type ClusterState struct {
+	LoggingEnabled             bool
+	NetworkMonitoringEnabled   bool
+	ObservabilityBundleVersion string
+}
+
+// ClusterCollector exposes the state of the reconciled clusters.
+// States are kept in memory and updated on each reconciliation so that
+// scrapes never hit the API server.
+type ClusterCollector struct {
+	mutex    sync.Mutex
+	clusters map[types.NamespacedName]ClusterState
+	// rollouts holds the rollout phase of the clusters tracked by the rollout.
+	rollouts map[types.NamespacedName]string
+
+	clustersDesc                   *prometheus.Desc
+	networkMonitoringClustersDesc  *prometheus.Desc
+	observabilityBundleVersionDesc *prometheus.Desc
+	rolloutClustersDesc            *prometheus.Desc
+}
+
+func newClusterCollector() *ClusterCollector {
+	return &ClusterCollector{
+		clusters: map[types.NamespacedName]ClusterState{},
+		rollouts: map[types.NamespacedName]string{},
+		clustersDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "", "clusters"),
+			"Number of clusters with logging enabled or disabled.",
+			[]string{"logging"}, nil,
+		),
+		networkMonitoringClustersDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "", "network_monitoring_clusters"),
+			"Number of clusters with network monitoring enabled.",
+			nil, nil,
+		),
+		observabilityBundleVersionDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "", "observability_bundle_info"),
+			"Version of the observability bundle detected for a cluster.",
+			[]string{"cluster_namespace", "cluster_id", "version"}, nil,
+		),
+		rolloutClustersDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "", "rollout_clusters"),
+			"Number of clusters per phase of the configuration rollout.",
+			[]string{"phase"}, nil,
+		),
+	}
+}
+
+func (c *ClusterCollector) set(cluster types.NamespacedName, state ClusterState) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.clusters[cluster] = state
+}
+
+func (c *ClusterCollector) setRolloutPhase(cluster types.NamespacedName, phase string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if phase == "" {
+		delete(c.rollouts, cluster)
+		return
+	}
+	c.rollouts[cluster] = phase
+}
+
+func (c *ClusterCollector) delete(cluster types.NamespacedName) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.clusters, cluster)
+	delete(c.rollouts, cluster)
+}
+
+// Describe implements prometheus.Collector.
+func (c *ClusterCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.clustersDesc
+	ch <- c.networkMonitoringClustersDesc
+	ch <- c.observabilityBundleVersionDesc
+	ch <- c.rolloutClustersDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *ClusterCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var enabled, disabled, networkMonitoring float64
+	for cluster, state := range c.clusters {
+		if state.LoggingEnabled {
+			enabled++
+		} else {
+			disabled++
+		}
+		if state.NetworkMonitoringEnabled {
+			networkMonitoring++
+		}
+		if state.ObservabilityBundleVersion != "" {
+			ch <- prometheus.MustNewConstMetric(c.observabilityBundleVersionDesc, prometheus.GaugeValue, 1, cluster.Namespace, cluster.Name, state.ObservabilityBundleVersion)
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.clustersDesc, prometheus.GaugeValue, enabled, "enabled")
+	ch <- prometheus.MustNewConstMetric(c.clustersDesc, prometheus.GaugeValue, disabled, "disabled")
+	ch <- prometheus.MustNewConstMetric(c.networkMonitoringClustersDesc, prometheus.GaugeValue, networkMonitoring)
+
+	rollouts := map[string]float64{}
+	for _, phase := range c.rollouts {
+		rollouts[phase]++
+	}
+	for phase, count := range rollouts {
+		ch <- prometheus.MustNewConstMetric(c.rolloutClustersDesc, prometheus.GaugeValue, count, phase)
+	}
+}
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestClusterCollector(t *testing.T) {
+	collector := newClusterCollector()
+	collector.set(types.NamespacedName{Namespace: "org-a", Name: "a"}, ClusterState{LoggingEnabled: true, NetworkMonitoringEnabled: true, ObservabilityBundleVersion: "2.3.0"})
+	collector.set(types.NamespacedName{Namespace: "org-b", Name: "b"}, ClusterState{LoggingEnabled: true})
+	collector.set(types.NamespacedName{Namespace: "org-c", Name: "c"}, ClusterState{ObservabilityBundleVersion: "1.7.0"})
+	collector.set(types.NamespacedName{Namespace: "org-d", Name: "d"}, ClusterState{LoggingEnabled: true})
+	collector.setRolloutPhase(types.NamespacedName{Namespace: "org-a", Name: "a"}, "Healthy")
+	collector.setRolloutPhase(types.NamespacedName{Namespace: "org-b", Name: "b"}, "Progressing")
+	collector.setRolloutPhase(types.NamespacedName{Namespace: "org-c", Name: "c"}, "Progressing")
+	collector.setRolloutPhase(types.NamespacedName{Namespace: "org-c", Name: "c"}, "")
+	collector.setRolloutPhase(types.NamespacedName{Namespace: "org-d", Name: "d"}, "Failed")
+	collector.delete(types.NamespacedName{Namespace: "org-d", Name: "d"})
+
+	expected := `
+# HELP logging_operator_clusters Number of clusters with logging enabled or disabled.
+# TYPE logging_operator_clusters gauge
+logging_operator_clusters{logging="disabled"} 1
+logging_operator_clusters{logging="enabled"} 2
+# HELP logging_operator_network_monitoring_clusters Number of clusters with network monitoring enabled.
+# TYPE logging_operator_network_monitoring_clusters gauge
+logging_operator_network_monitoring_clusters 1
+# HELP logging_operator_observability_bundle_info Version of the observability bundle detected for a cluster.
+# TYPE logging_operator_observability_bundle_info gauge
+logging_operator_observability_bundle_info{cluster_id="a",cluster_namespace="org-a",version="2.3.0"} 1
+logging_operator_observability_bundle_info{cluster_id="c",cluster_namespace="org-c",version="1.7.0"} 1
+# HELP logging_operator_rollout_clusters Number of clusters per phase of the configuration rollout.
+# TYPE logging_operator_rollout_clusters gauge
+logging_operator_rollout_clusters{phase="Healthy"} 1
+logging_operator_rollout_clusters{phase="Progressing"} 1
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,126 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	metricsNamespace = "logging_operator"
+
+	// Operations performed on resources.
+	CreateOperation = "create"
+	DeleteOperation = "delete"
+)
+
+var (
+	resourceReconcileDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "resource_reconcile_duration_seconds",
+			Help:      "Duration of the reconciliation of a resource for a cluster.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"resource", "operation"},
+	)
+
+	resourceReconcileErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "resource_reconcile_errors_total",
+			Help:      "Number of failed reconciliations of a resource, per cluster.",
+		},
+		[]string{"resource", "operation", "cluster_namespace", "cluster_id"},
+	)
+
+	configDriftCorrections = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "config_drift_corrections_total",
+			Help:      "Number of times a managed object differed from the desired one and was updated.",
+		},
+		[]string{"resource"},
+	)
+
+	orphanedObjects = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "orphaned_objects",
+			Help:      "Number of managed objects whose cluster no longer exists, found by the last orphan sweep.",
+		},
+		[]string{"kind"},
+	)
+
+	orphanedObjectsDeleted = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "orphaned_objects_deleted_total",
+			Help:      "Number of managed objects deleted by the orphan sweeper because their cluster no longer exists.",
+		},
+		[]string{"kind"},
+	)
+
+	clusters = newClusterCollector()
+)
+
+// Register registers the logging-operator collectors into the given registerer.
+func Register(registerer prometheus.Registerer) error {
+	collectors := []prometheus.Collector{
+		resourceReconcileDuration,
+		resourceReconcileErrors,
+		configDriftCorrections,
+		orphanedObjects,
+		orphanedObjectsDeleted,
+		clusters,
+	}
+	for _, collector := range collectors {
+		if err := registerer.Register(collector); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ObserveResourceReconcile records the duration and outcome of a resource reconciliation.
+func ObserveResourceReconcile(resource, operation, clusterNamespace, clusterName string, duration time.Duration, err error) {
+	resourceReconcileDuration.WithLabelValues(resource, operation).Observe(duration.Seconds())
+	if err != nil {
+		resourceReconcileErrors.WithLabelValues(resource, operation, clusterNamespace, clusterName).Inc()
+	}
+}
+
+// ObserveConfigDriftCorrection records that the object managed by the given resource was updated.
+func ObserveConfigDriftCorrection(resource string) {
+	configDriftCorrections.WithLabelValues(resource).Inc()
+}
+
+// SetOrphanedObjects records the number of orphaned objects of the given kind found by the last sweep.
+func SetOrphanedObjects(kind string, count int) {
+	orphanedObjects.WithLabelValues(kind).Set(float64(count))
+}
+
+// ObserveOrphanedObjectDeletion records that an orphaned object of the given kind was deleted.
+func ObserveOrphanedObjectDeletion(kind string) {
+	orphanedObjectsDeleted.WithLabelValues(kind).Inc()
+}
+
+// SetClusterState records the state of the given cluster.
+func SetClusterState(cluster types.NamespacedName, state ClusterState) {
+	clusters.set(cluster, state)
+}
+
+// SetClusterRolloutPhase records the rollout phase of the given cluster, an empty phase when it is not tracked.
+func SetClusterRolloutPhase(cluster types.NamespacedName, phase string) {
+	clusters.setRolloutPhase(cluster, phase)
+}
+
+// DeleteCluster removes all the series of the given cluster, once it is gone.
+func DeleteCluster(cluster types.NamespacedName) {
+	clusters.delete(cluster)
+	resourceReconcileErrors.DeletePartialMatch(prometheus.Labels{
+		"cluster_namespace": cluster.Namespace,
+		"cluster_id":        cluster.Name,
+	})
+}
@@ -0,0 +1,101 @@
+package clientcertificate
+
+import (
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
+	"github.com/giantswarm/logging-operator/pkg/common"
+	"github.com/giantswarm/logging-operator/pkg/config"
+)
+
+const (
+	clientCertificateName = "client-certificate"
+
+	// ResourceName is the name of the client-certificate resource.
+	ResourceName = clientCertificateName
+)
+
+// generateCertificate returns the cert-manager Certificate requesting the client certificate of the given cluster.
+// The cluster name is the common name of the certificate, the issued secret is labelled like the other managed objects
+// so that it is swept with them.
+func generateCertificate(cluster *capicluster.Cluster, cfg config.Config) *unstructured.Unstructured {
+	metadata := certificateMeta(cluster)
+
+	certificate := &unstructured.Unstructured{Object: map[string]any{
+		"spec": map[string]any{
+			"secretName":  metadata.GetName(),
+			"commonName":  cluster.GetName(),
+			"duration":    cfg.ClientCertificateDuration.String(),
+			"renewBefore": cfg.ClientCertificateRenewBefore.String(),
+			"usages": []any{
+				"client auth",
+				"digital signature",
+				"key encipherment",
+			},
+			"privateKey": map[string]any{
+				"algorithm":      "ECDSA",
+				"size":           int64(256),
+				"rotationPolicy": "Always",
+			},
+			"issuerRef": map[string]any{
+				"group": common.CertificateGroupVersionKind.Group,
+				"kind":  cfg.ClientCertificateIssuerKind,
+				"name":  cfg.ClientCertificateIssuer,
+			},
+			"secretTemplate": map[string]any{
+				"labels": stringMap(metadata.GetLabels()),
+			},
+		},
+	}}
+	certificate.SetGroupVersionKind(common.CertificateGroupVersionKind)
+	certificate.SetName(metadata.GetName())
+	certificate.SetNamespace(metadata.GetNamespace())
+	certificate.SetLabels(metadata.GetLabels())
+	certificate.SetOwnerReferences(metadata.OwnerReferences)
+
+	return certificate
+}
+
+// certificateMeta returns metadata for the client certificate
+func certificateMeta(cluster *capicluster.Cluster) metav1.ObjectMeta {
+	metadata := metav1.ObjectMeta{
+		Name:      common.ClientCertificateName(cluster.GetName()),
+		Namespace: cluster.GetNamespace(),
+		Labels:    map[string]string{},
+	}
+
+	common.AddCommonLabels(metadata.Labels)
+	common.AddClusterOwner(&metadata, cluster)
+	return metadata
+}
+
+// certificateUpToDate returns true if the labels and the spec fields set in the desired certificate
+// have the same values in the current one. Fields defaulted by cert-manager are ignored.
+func certificateUpToDate(current, desired *unstructured.Unstructured) bool {
+	currentLabels := current.GetLabels()
+	for key, value := range desired.GetLabels() {
+		if currentLabels[key] != value {
+			return false
+		}
+	}
+
+	currentSpec, _, _ := unstructured.NestedMap(current.Object, "spec")
+	desiredSpec, _, _ := unstructured.NestedMap(desired.Object, "spec")
+	for key, value := range desiredSpec {
+		if !equality.Semantic.DeepEqual(value, currentSpec[key]) {
+			return false
+		}
+	}
+	return true
+}
+
+// stringMap converts the given labels to the map type used by unstructured objects.
+func stringMap(labels map[string]string) map[string]any {
+	converted := make(map[string]any, len(labels))
+	for key, value := range labels {
+		converted[key] = value
+	}
+	return converted
+}
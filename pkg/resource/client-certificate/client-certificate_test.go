@@ -0,0 +1,77 @@
+package clientcertificate
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	capiv1beta2 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
+	"github.com/giantswarm/logging-operator/pkg/common"
+	"github.com/giantswarm/logging-operator/pkg/config"
+)
+
+func TestCertificateUpToDate(t *testing.T) {
+	cluster := capicluster.New(&capiv1beta2.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", Namespace: "org-test"}})
+	cfg := config.Config{
+		ClientCertificateIssuer:      "logging-ca",
+		ClientCertificateIssuerKind:  common.DefaultClientCertificateIssuerKind,
+		ClientCertificateDuration:    common.DefaultClientCertificateDuration,
+		ClientCertificateRenewBefore: common.DefaultClientCertificateRenewBefore,
+	}
+	desired := generateCertificate(cluster, cfg)
+
+	testCases := []struct {
+		name     string
+		update   func(current *unstructured.Unstructured)
+		expected bool
+	}{
+		{
+			name:     "same certificate",
+			update:   func(current *unstructured.Unstructured) {},
+			expected: true,
+		},
+		{
+			name: "fields defaulted by cert-manager",
+			update: func(current *unstructured.Unstructured) {
+				_ = unstructured.SetNestedField(current.Object, false, "spec", "isCA")
+			},
+			expected: true,
+		},
+		{
+			name: "other labels and status",
+			update: func(current *unstructured.Unstructured) {
+				labels := current.GetLabels()
+				labels["app"] = "test"
+				current.SetLabels(labels)
+				_ = unstructured.SetNestedField(current.Object, "2026-01-01T00:00:00Z", "status", "notAfter")
+			},
+			expected: true,
+		},
+		{
+			name: "different issuer",
+			update: func(current *unstructured.Unstructured) {
+				_ = unstructured.SetNestedField(current.Object, "other-ca", "spec", "issuerRef", "name")
+			},
+			expected: false,
+		},
+		{
+			name: "different duration",
+			update: func(current *unstructured.Unstructured) {
+				_ = unstructured.SetNestedField(current.Object, "24h0m0s", "spec", "duration")
+			},
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			current := desired.DeepCopy()
+			tc.update(current)
+			if upToDate := certificateUpToDate(current, desired); upToDate != tc.expected {
+				t.Errorf("expected up to date %t, got %t", tc.expected, upToDate)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package clientcertificate
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
+	"github.com/giantswarm/logging-operator/pkg/common"
+	"github.com/giantswarm/logging-operator/pkg/config"
+)
+
+// renewalGracePeriod is how long cert-manager is given to renew a client certificate
+// before the certificate is reported as not renewed.
+const renewalGracePeriod = time.Hour
+
+// Resource implements a resource.Interface to handle
+// Client certificate: cert-manager Certificate issuing the client certificate the cluster authenticates with
+// when the auth mode is mtls
+type Resource struct {
+	Client   client.Client
+	Config   config.Config
+	Recorder record.EventRecorder
+}
+
+// Name returns the name of the resource.
+func (r *Resource) Name() string {
+	return clientCertificateName
+}
+
+// DependsOn returns the resources which must be reconciled first, the client certificate does not depend on any.
+func (r *Resource) DependsOn() []string {
+	return nil
+}
+
+// ReconcileCreate ensures the client certificate is requested when the cluster authenticates with mutual TLS,
+// and deleted otherwise. The reconciliation is requeued until the certificate is issued.
+func (r *Resource) ReconcileCreate(ctx context.Context, cluster *capicluster.Cluster) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	logger.Info("client-certificate create")
+
+	cfg := config.FromContext(ctx, r.Config)
+	if !common.IsMTLSAuth(cfg) {
+		return r.deleteIssued(ctx, cluster)
+	}
+	if cfg.ClientCertificateIssuer == "" {
+		return ctrl.Result{}, errors.New("no client certificate issuer is configured")
+	}
+
+	desiredCertificate := generateCertificate(cluster, cfg)
+
+	// Check if the certificate already exists.
+	logger.Info("client-certificate - getting", "namespace", desiredCertificate.GetNamespace(), "name", desiredCertificate.GetName())
+	currentCertificate := &unstructured.Unstructured{}
+	currentCertificate.SetGroupVersionKind(common.CertificateGroupVersionKind)
+	err := r.Client.Get(ctx, client.ObjectKeyFromObject(desiredCertificate), currentCertificate)
+	switch {
+	case apimachineryerrors.IsNotFound(err):
+		logger.Info("client-certificate not found, creating")
+		err = r.apply(ctx, cluster, desiredCertificate)
+		if err != nil {
+			return ctrl.Result{}, errors.WithStack(err)
+		}
+		r.Recorder.Eventf(cluster.Object, v1.EventTypeNormal, common.EventReason(r.Name(), common.CreatedAction), "Created certificate %s/%s", desiredCertificate.GetNamespace(), desiredCertificate.GetName())
+	case err != nil:
+		return ctrl.Result{}, errors.WithStack(err)
+	case !certificateUpToDate(currentCertificate, desiredCertificate):
+		logger.Info("client-certificate - updating")
+		err = r.apply(ctx, cluster, desiredCertificate)
+		if err != nil {
+			return ctrl.Result{}, errors.WithStack(err)
+		}
+		r.Recorder.Eventf(cluster.Object, v1.EventTypeNormal, common.EventReason(r.Name(), common.UpdatedAction), "Updated certificate %s/%s", desiredCertificate.GetNamespace(), desiredCertificate.GetName())
+	default:
+		logger.Info("client-certificate up to date")
+	}
+
+	// Wait for cert-manager to issue the certificate, the secrets depending on it can not be generated before.
+	clientCertificate, err := common.ReadClientCertificate(ctx, r.Client, cluster)
+	if err != nil {
+		if apimachineryerrors.IsNotFound(err) {
+			logger.Info("client-certificate not issued yet, requeueing")
+			return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+		}
+		r.Recorder.Eventf(cluster.Object, v1.EventTypeWarning, common.ClientCertificateNotReadyReason, "Client certificate of the cluster is not usable: %s", err)
+		return ctrl.Result{}, errors.WithStack(err)
+	}
+
+	// cert-manager renews the certificate renewBefore its expiry, the updated secret triggers a reconciliation
+	// rolling the new certificate out. Report certificates which were not renewed in time.
+	renewal := clientCertificate.NotAfter.Add(-cfg.ClientCertificateRenewBefore).Add(renewalGracePeriod)
+	if time.Now().After(renewal) {
+		r.Recorder.Eventf(cluster.Object, v1.EventTypeWarning, common.ClientCertificateNotRenewedReason, "Client certificate of the cluster expires at %s and was not renewed", clientCertificate.NotAfter.UTC().Format(time.RFC3339))
+	}
+
+	logger.Info("client-certificate - done")
+	return ctrl.Result{}, nil
+}
+
+// Desired implements resource.Renderer, it returns the certificate ReconcileCreate requests for the given cluster,
+// none when the cluster does not authenticate with mutual TLS.
+func (r *Resource) Desired(ctx context.Context, cluster *capicluster.Cluster) ([]client.Object, error) {
+	cfg := config.FromContext(ctx, r.Config)
+	if !common.IsMTLSAuth(cfg) {
+		return nil, nil
+	}
+	if cfg.ClientCertificateIssuer == "" {
+		return nil, errors.New("no client certificate issuer is configured")
+	}
+	return []client.Object{generateCertificate(cluster, cfg)}, nil
+}
+
+// ReconcileDelete ensures the client certificate and its secret are deleted.
+func (r *Resource) ReconcileDelete(ctx context.Context, cluster *capicluster.Cluster) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	logger.Info("client-certificate delete")
+
+	metadata := certificateMeta(cluster)
+
+	// Only the metadata of the certificate is needed to delete it.
+	certificate := &metav1.PartialObjectMetadata{}
+	certificate.SetGroupVersionKind(common.CertificateGroupVersionKind)
+	certificate.SetName(metadata.GetName())
+	certificate.SetNamespace(metadata.GetNamespace())
+
+	// The secret is not deleted by cert-manager with its certificate.
+	secret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: metadata.GetName(), Namespace: metadata.GetNamespace()}}
+
+	// The certificate is deleted first so that cert-manager does not issue the secret again.
+	objects := []struct {
+		kind   string
+		object client.Object
+	}{
+		{kind: "certificate", object: certificate},
+		{kind: "secret", object: secret},
+	}
+	for _, o := range objects {
+		kind, object := o.kind, o.object
+		err := r.Client.Delete(ctx, object)
+		if err != nil {
+			// Nothing to delete when the object does not exist, or cert-manager is not installed.
+			if apimachineryerrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+				continue
+			}
+			return ctrl.Result{}, errors.WithStack(err)
+		}
+		r.Recorder.Eventf(cluster.Object, v1.EventTypeNormal, common.EventReason(r.Name(), common.DeletedAction), "Deleted %s %s/%s", kind, object.GetNamespace(), object.GetName())
+		logger.Info("client-certificate deleted", "kind", kind, "namespace", object.GetNamespace(), "name", object.GetName())
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// deleteIssued deletes the client certificate when it was issued, i.e. the cluster authenticated with mutual TLS
+// before. This runs on every reconciliation of the clusters which do not authenticate with mutual TLS, so only the
+// secret is looked up, from the cache: the certificates are not cached. A certificate which was never issued is
+// deleted with the cluster, or once cert-manager issues its secret.
+func (r *Resource) deleteIssued(ctx context.Context, cluster *capicluster.Cluster) (ctrl.Result, error) {
+	metadata := certificateMeta(cluster)
+	secret := &v1.Secret{}
+	err := r.Client.Get(ctx, client.ObjectKey{Name: metadata.GetName(), Namespace: metadata.GetNamespace()}, secret)
+	if apimachineryerrors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	}
+	if err != nil {
+		return ctrl.Result{}, errors.WithStack(err)
+	}
+
+	return r.ReconcileDelete(ctx, cluster)
+}
+
+// apply applies the desired certificate with server-side apply.
+// Conflicts with fields owned by other managers are reported with a warning event.
+func (r *Resource) apply(ctx context.Context, cluster *capicluster.Cluster, desired *unstructured.Unstructured) error {
+	err := common.Apply(ctx, r.Client, client.ApplyConfigurationFromUnstructured(desired))
+	if apimachineryerrors.IsConflict(err) {
+		r.Recorder.Eventf(cluster.Object, v1.EventTypeWarning, common.ApplyConflictReason, "Failed to apply certificate %s/%s, fields are owned by another manager: %s", desired.GetNamespace(), desired.GetName(), err)
+	}
+	return err
+}
@@ -1,114 +1,272 @@
 package eventsloggerconfig
 
 import (
-	"bytes"
-	_ "embed"
 	"fmt"
-	"net"
+	"strings"
 	"text/template"
 
-	"github.com/Masterminds/sprig/v3"
+	"github.com/pkg/errors"
 
+	"github.com/giantswarm/logging-operator/pkg/alloy"
 	"github.com/giantswarm/logging-operator/pkg/common"
+	"github.com/giantswarm/logging-operator/pkg/config"
 )
 
-var (
-	//go:embed alloy/events-logger.alloy.template
-	alloyEvents         string
-	alloyEventsTemplate *template.Template
+// generateAlloyEventsConfig returns the events logger values. The Alloy configuration is rendered with the
+// replacement template when one is given.
+func generateAlloyEventsConfig(includeNamespaces, excludeNamespaces []string, insecureCA, tracingEnabled bool, tlsKeys common.TLSKeys, proxy common.Proxy, tempoEndpoint common.Endpoint, managementClusterLokiURLs common.LokiURLs, tenants []common.Tenant, clusterLabels common.ClusterLabels, configTemplate *template.Template) (string, error) {
+	alloyConfig, components, err := generateAlloyConfig(includeNamespaces, excludeNamespaces, insecureCA, tracingEnabled, tlsKeys, proxy, tempoEndpoint, managementClusterLokiURLs, tenants, clusterLabels)
+	if err != nil {
+		return "", err
+	}
 
-	//go:embed alloy/events-logger-config.alloy.yaml.template
-	alloyEventsConfig         string
-	alloyEventsConfigTemplate *template.Template
-)
+	if configTemplate != nil {
+		settings := common.ConfigSettings{
+			ManagementClusterLokiURLs: managementClusterLokiURLs,
+			TempoEndpoint:             tempoEndpoint,
+			InsecureCA:                insecureCA,
+			TLSKeys:                   tlsKeys,
+			Proxy:                     proxy,
+			Tracing:                   tracingEnabled,
+			IncludeNamespaces:         includeNamespaces,
+			ExcludeNamespaces:         excludeNamespaces,
+		}
+		alloyConfig, err = common.RenderTemplate(configTemplate, common.NewConfigTemplateData(alloyConfig, components, settings, clusterLabels, tenants), alloy.Validate)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	isWorkloadCluster := common.IsWorkloadCluster(clusterLabels.Installation, clusterLabels.ClusterID)
+	return eventsValues(alloyConfig, tracingEnabled, isWorkloadCluster, proxy).Marshal(eventsValuesHeader)
+}
 
-func init() {
-	alloyEventsTemplate = template.Must(template.New("events-logger.alloy").Funcs(sprig.FuncMap()).Parse(alloyEvents))
-	alloyEventsConfigTemplate = template.Must(template.New("events-logger-config.alloy.yaml").Funcs(sprig.FuncMap()).Parse(alloyEventsConfig))
+// validateConfigTemplate checks that the replacement template renders a valid configuration for a synthetic
+// workload cluster, configured like the installation.
+func validateConfigTemplate(configTemplate *template.Template, cfg config.Config) error {
+	tenants, _ := common.SanitizeTenants([]string{common.DefaultWriteTenant})
+	tempoEndpoint := common.Endpoint{Host: "tempo.synthetic", Port: 443, TLS: true}
+	_, err := generateAlloyEventsConfig(cfg.IncludeEventsFromNamespaces, cfg.ExcludeEventsFromNamespaces, cfg.InsecureCA, cfg.EnableTracingFlag, common.SecretTLSKeys(cfg), common.Proxy{}, tempoEndpoint, common.ManagementClusterLokiURLs(cfg), tenants, common.SyntheticClusterLabels(cfg), configTemplate)
+	return err
 }
 
-func generateAlloyEventsConfig(includeNamespaces, excludeNamespaces []string, insecureCA, tracingEnabled bool, tempoURL string, tenants []string, clusterLabels common.ClusterLabels) (string, error) {
-	var values bytes.Buffer
+// eventsConfig holds the settings the components of the events logger configuration depend on.
+type eventsConfig struct {
+	clusterLabels             common.ClusterLabels
+	managementClusterLokiURLs common.LokiURLs
+	isWorkloadCluster         bool
+	insecureCA                bool
+	tlsKeys                   common.TLSKeys
+	proxy                     common.Proxy
+	// tempoEndpoint must be in host:port format which is required by the gRPC exporter.
+	tempoEndpoint common.Endpoint
+	credentials   alloy.RemoteKubernetesSecret
+}
 
-	alloyConfig, err := generateAlloyConfig(includeNamespaces, excludeNamespaces, insecureCA, tracingEnabled, tempoURL, tenants, clusterLabels)
-	if err != nil {
-		return "", err
+func generateAlloyConfig(includeNamespaces, excludeNamespaces []string, insecureCA, tracingEnabled bool, tlsKeys common.TLSKeys, proxy common.Proxy, tempoEndpoint common.Endpoint, managementClusterLokiURLs common.LokiURLs, tenants []common.Tenant, clusterLabels common.ClusterLabels) (string, []alloy.PrintedComponent, error) {
+	c := eventsConfig{
+		clusterLabels:             clusterLabels,
+		managementClusterLokiURLs: managementClusterLokiURLs,
+		isWorkloadCluster:         common.IsWorkloadCluster(clusterLabels.Installation, clusterLabels.ClusterID),
+		insecureCA:                insecureCA,
+		tlsKeys:                   tlsKeys,
+		proxy:                     proxy,
+		tempoEndpoint:             tempoEndpoint,
+		credentials: alloy.RemoteKubernetesSecret{
+			Label:     "credentials",
+			Namespace: "kube-system",
+			Name:      common.AlloyEventsLoggerAppName,
+		},
+	}
+	// The management cluster sends events and traces to the in-cluster services without authenticating.
+	if !c.isWorkloadCluster {
+		c.tlsKeys.CertPEM, c.tlsKeys.KeyPEM = "", ""
+	}
+
+	var config alloy.Config
+	config.Add(alloy.Logging{Level: "info", Format: "logfmt"}, c.credentials)
+
+	write := c.lokiWrite()
+	source := alloy.LokiSourceKubernetesEvents{
+		Label:     "local",
+		ForwardTo: alloy.Refs(write.Receiver()),
+	}
+	if c.isWorkloadCluster {
+		source.Namespaces = includeNamespaces
 	}
 
-	data := struct {
-		AlloyConfig       string
-		TracingEnabled    bool
-		IsWorkloadCluster bool
-	}{
-		AlloyConfig:       alloyConfig,
-		TracingEnabled:    tracingEnabled,
-		IsWorkloadCluster: common.IsWorkloadCluster(clusterLabels.Installation, clusterLabels.ClusterID),
+	if c.isWorkloadCluster && len(excludeNamespaces) > 0 {
+		process := alloy.LokiProcess{
+			Label:     "default",
+			ForwardTo: alloy.Refs(write.Receiver()),
+			Stages: []alloy.Stage{
+				alloy.StageDrop{Source: "namespace", Expression: strings.Join(excludeNamespaces, "|")},
+			},
+		}
+		source.ForwardTo = alloy.Refs(process.Receiver())
+		config.Add(source, alloy.Commented("exclude configured namespaces", process))
+	} else {
+		config.Add(source)
+	}
+
+	config.Add(alloy.Commented("Loki target configuration", write))
+
+	if tracingEnabled {
+		config.Add(c.tracingPipeline(tenants)...)
 	}
 
-	err = alloyEventsConfigTemplate.Execute(&values, data)
+	alloyConfig, err := config.Build()
 	if err != nil {
-		return "", err
+		return "", nil, errors.Wrap(err, "generated events logger alloy config is invalid")
+	}
+	return alloyConfig, config.Components(), nil
+}
+
+// lokiWrite returns the component sending the events to Loki.
+func (c eventsConfig) lokiWrite() alloy.LokiWrite {
+	endpoint := alloy.LokiEndpoint{
+		URL:              alloy.String(c.managementClusterLokiURLs.Push),
+		MaxBackoffPeriod: common.LokiMaxBackoffPeriod.String(),
+		RemoteTimeout:    common.LokiRemoteTimeout.String(),
+		TenantID:         alloy.Nonsensitive(c.credentials.Data(common.LoggingTenantID)),
+		TLSConfig:        alloy.TLSConfig{InsecureSkipVerify: c.insecureCA, CAPEM: c.caPEM()},
+	}
+
+	if c.isWorkloadCluster {
+		endpoint.URL = alloy.Nonsensitive(c.credentials.Data(common.LoggingURL))
+		endpoint.ProxyURL = c.proxy.URL
+		endpoint.NoProxy = c.proxy.NoProxy
+		if c.tlsKeys.CertPEM == "" {
+			endpoint.BasicAuth = &alloy.BasicAuth{
+				Username: alloy.Nonsensitive(c.credentials.Data(common.LoggingUsername)),
+				Password: c.credentials.Data(common.LoggingPassword),
+			}
+		}
+		endpoint.TLSConfig.CertPEM, endpoint.TLSConfig.KeyPEM = c.clientCertificate()
 	}
 
-	return values.String(), nil
+	return alloy.LokiWrite{
+		Label:     "default",
+		Endpoints: []alloy.LokiEndpoint{endpoint},
+		ExternalLabels: alloy.Object{
+			{Key: "cluster_id", Value: alloy.String(c.clusterLabels.ClusterID)},
+			{Key: "cluster_type", Value: alloy.String(c.clusterLabels.ClusterType)},
+			{Key: "organization", Value: alloy.String(c.clusterLabels.Organization)},
+			{Key: "provider", Value: alloy.String(c.clusterLabels.Provider)},
+			{Key: "scrape_job", Value: alloy.String("kubernetes-events")},
+		},
+	}
 }
 
-func generateAlloyConfig(includeNamespaces, excludeNamespaces []string, insecureCA, tracingEnabled bool, tempoURL string, tenants []string, clusterLabels common.ClusterLabels) (string, error) {
-	var values bytes.Buffer
-
-	// endpoint must be in host:port format which is required by the gRPC exporter.
-	// Direclty adding port 443 here as tempoURL is an ingress host
-	// which does not contain any port and exposes port 443.
-	// see https://kubernetes.io/docs/reference/generated/kubernetes-api
-	endpoint := net.JoinHostPort(tempoURL, "443")
-
-	data := struct {
-		ClusterID          string
-		ClusterType        string
-		Organization       string
-		Provider           string
-		InsecureSkipVerify string
-		MaxBackoffPeriod   string
-		RemoteTimeout      string
-		IncludeNamespaces  []string
-		ExcludeNamespaces  []string
-		SecretName         string
-		LoggingURLKey      string
-		LoggingTenantIDKey string
-		LoggingUsernameKey string
-		LoggingPasswordKey string
-		IsWorkloadCluster  bool
-		TracingEnabled     bool
-		TracingEndpoint    string
-		TracingUsernameKey string
-		TracingPasswordKey string
-		Tenants            []string
-	}{
-		ClusterID:          clusterLabels.ClusterID,
-		ClusterType:        clusterLabels.ClusterType,
-		Organization:       clusterLabels.Organization,
-		Provider:           clusterLabels.Provider,
-		InsecureSkipVerify: fmt.Sprintf("%t", insecureCA),
-		MaxBackoffPeriod:   common.LokiMaxBackoffPeriod.String(),
-		RemoteTimeout:      common.LokiRemoteTimeout.String(),
-		SecretName:         common.AlloyEventsLoggerAppName,
-		IncludeNamespaces:  includeNamespaces,
-		ExcludeNamespaces:  excludeNamespaces,
-		LoggingURLKey:      common.LoggingURL,
-		LoggingTenantIDKey: common.LoggingTenantID,
-		LoggingUsernameKey: common.LoggingUsername,
-		LoggingPasswordKey: common.LoggingPassword,
-		IsWorkloadCluster:  common.IsWorkloadCluster(clusterLabels.Installation, clusterLabels.ClusterID),
-		TracingEnabled:     tracingEnabled,
-		TracingEndpoint:    endpoint,
-		TracingUsernameKey: common.TracingUsername,
-		TracingPasswordKey: common.TracingPassword,
-		Tenants:            tenants,
-	}
-
-	if err := alloyEventsTemplate.Execute(&values, data); err != nil {
-		return "", err
+// tracingPipeline returns the components receiving the traces of the workloads, adding the cluster metadata,
+// and sending them to the Tempo tenant of their pod.
+func (c eventsConfig) tracingPipeline(tenants []common.Tenant) []alloy.Component {
+	var components []alloy.Component
+
+	var auth alloy.Expr
+	if c.isWorkloadCluster && c.tlsKeys.CertPEM == "" {
+		credentials := alloy.OtelcolAuthBasic{
+			Label:    "tracing_credentials",
+			Username: alloy.Nonsensitive(c.credentials.Data(common.TracingUsername)),
+			Password: c.credentials.Data(common.TracingPassword),
+		}
+		auth = credentials.Handler()
+		components = append(components, credentials)
+	}
+
+	filters := make([]alloy.Component, 0, 2*len(tenants))
+	filterInputs := make(alloy.List, 0, len(tenants))
+	for _, tenant := range tenants {
+		exporter := c.otlpExporter(tenant, auth)
+		filter := alloy.OtelcolProcessorFilter{
+			Label: tenant.ID,
+			Spans: []string{fmt.Sprintf(`resource.attributes["giantswarm.tenant"] != %q`, tenant.Name)},
+			Output: alloy.OtelcolOutput{
+				Traces: alloy.Refs(exporter.Input()),
+			},
+		}
+		filterInputs = append(filterInputs, filter.Input())
+
+		exporterComment := "one OTLP gRPC exporter for traces per tenant"
+		if c.isWorkloadCluster && c.proxy.Enabled() {
+			exporterComment += "\nThe gRPC client sends traces through the proxy set in the HTTPS_PROXY environment variable."
+		}
+		filters = append(filters,
+			alloy.Commented("drop the traces of the other tenants", filter),
+			alloy.Commented(exporterComment, exporter),
+		)
+	}
+
+	transform := alloy.OtelcolProcessorTransform{
+		Label:     "default",
+		ErrorMode: "ignore",
+		TraceStatements: []alloy.OtelcolStatements{
+			{
+				Context: "resource",
+				Statements: []string{
+					fmt.Sprintf(`set(attributes["giantswarm.cluster.id"], %q)`, c.clusterLabels.ClusterID),
+					fmt.Sprintf(`set(attributes["giantswarm.cluster.type"], %q)`, c.clusterLabels.ClusterType),
+					fmt.Sprintf(`set(attributes["giantswarm.cluster.organization"], %q)`, c.clusterLabels.Organization),
+					fmt.Sprintf(`set(attributes["giantswarm.cluster.provider"], %q)`, c.clusterLabels.Provider),
+				},
+			},
+		},
+		Output: alloy.OtelcolOutput{Traces: filterInputs},
+	}
+
+	k8sAttributes := alloy.OtelcolProcessorK8sAttributes{
+		Label:           "default",
+		Metadata:        []string{"k8s.namespace.name", "k8s.pod.name", "k8s.container.name"},
+		Labels:          []alloy.OtelcolK8sLabel{{Key: "observability.giantswarm.io/tenant", TagName: "giantswarm.tenant"}},
+		OtelAnnotations: true,
+		Output:          alloy.OtelcolOutput{Traces: alloy.Refs(transform.Input())},
+	}
+
+	receiver := alloy.OtelcolReceiverOTLP{
+		Label:        "traces",
+		GRPCEndpoint: "0.0.0.0:4317",
+		HTTPEndpoint: "0.0.0.0:4318",
+		Output:       alloy.OtelcolOutput{Traces: alloy.Refs(k8sAttributes.Input())},
 	}
 
-	return values.String(), nil
+	components = append(components, alloy.Commented("OTLP receiver for traces", receiver), k8sAttributes, transform)
+	return append(components, filters...)
+}
+
+// otlpExporter returns the component sending the traces of the tenant to Tempo.
+func (c eventsConfig) otlpExporter(tenant common.Tenant, auth alloy.Expr) alloy.OtelcolExporterOTLP {
+	client := alloy.OtelcolClient{
+		Endpoint: c.tempoEndpoint.Address(),
+		Auth:     auth,
+		Headers:  alloy.Object{{Key: "X-Scope-OrgID", Value: alloy.String(tenant.Name)}},
+		// Use insecure connection when the exporter uses a (direct) internal Tempo endpoint which is not behind a TLS reverse proxy.
+		TLS: alloy.OtelcolTLS{Insecure: true},
+	}
+
+	if c.tempoEndpoint.TLS {
+		client.TLS = alloy.OtelcolTLS{CAPEM: c.caPEM()}
+		// The management cluster does not verify the in-cluster Tempo certificate when the CA is insecure.
+		if !c.isWorkloadCluster {
+			client.TLS.InsecureSkipVerify = c.insecureCA
+		}
+		client.TLS.CertPEM, client.TLS.KeyPEM = c.clientCertificate()
+	}
+
+	return alloy.OtelcolExporterOTLP{Label: tenant.ID, Client: client}
+}
+
+// caPEM returns the CA bundle of the secret, nil when there is none.
+func (c eventsConfig) caPEM() alloy.Expr {
+	if c.tlsKeys.CAPEM == "" {
+		return nil
+	}
+	return alloy.Nonsensitive(c.credentials.Data(c.tlsKeys.CAPEM))
+}
+
+// clientCertificate returns the client certificate and key of the secret, nil when there are none.
+func (c eventsConfig) clientCertificate() (alloy.Expr, alloy.Expr) {
+	if c.tlsKeys.CertPEM == "" {
+		return nil, nil
+	}
+	return alloy.Nonsensitive(c.credentials.Data(c.tlsKeys.CertPEM)), c.credentials.Data(c.tlsKeys.KeyPEM)
 }
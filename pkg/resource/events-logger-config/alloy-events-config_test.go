@@ -1,6 +1,7 @@
 package eventsloggerconfig
 
 import (
+	"context"
 	_ "embed"
 	"flag"
 	"os"
@@ -8,8 +9,12 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capiv1beta2 "sigs.k8s.io/cluster-api/api/core/v1beta2"
 
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
 	"github.com/giantswarm/logging-operator/pkg/common"
+	"github.com/giantswarm/logging-operator/pkg/config"
 )
 
 var (
@@ -24,7 +29,13 @@ func TestGenerateAlloyEventsConfig(t *testing.T) {
 		clusterName       string
 		includeNamespaces []string
 		excludeNamespaces []string
+		tenants           []string
 		tracingEnabled    bool
+		tlsKeys           common.TLSKeys
+		proxy             common.Proxy
+		// endpoints of the management cluster, the in-cluster services when empty
+		managementClusterLokiPushURL   string
+		managementClusterTempoEndpoint string
 	}{
 		{
 			goldenFile:       "alloy/test/events-logger-config.alloy.MC.yaml",
@@ -64,6 +75,47 @@ func TestGenerateAlloyEventsConfig(t *testing.T) {
 			clusterName:      "test-cluster",
 			tracingEnabled:   true,
 		},
+		{
+			goldenFile:       "alloy/test/events-logger-config.alloy.WC.tracing-enabled.special-tenants.yaml",
+			installationName: "test-installation",
+			clusterName:      "test-cluster",
+			tenants:          []string{"giantswarm", "team.a", "team-a", "3rd(party)*", `bad"tenant`},
+			tracingEnabled:   true,
+		},
+		{
+			goldenFile:       "alloy/test/events-logger-config.alloy.WC.tracing-enabled.ca-bundle.yaml",
+			installationName: "test-installation",
+			clusterName:      "test-cluster",
+			tracingEnabled:   true,
+			tlsKeys:          common.TLSKeys{CAPEM: common.TLSCAPEM},
+		},
+		{
+			goldenFile:       "alloy/test/events-logger-config.alloy.WC.tracing-enabled.mtls.yaml",
+			installationName: "test-installation",
+			clusterName:      "test-cluster",
+			tracingEnabled:   true,
+			tlsKeys:          common.TLSKeys{CertPEM: common.TLSCertPEM, KeyPEM: common.TLSKeyPEM},
+		},
+		{
+			goldenFile:       "alloy/test/events-logger-config.alloy.WC.tracing-enabled.proxy.yaml",
+			installationName: "test-installation",
+			clusterName:      "test-cluster",
+			tracingEnabled:   true,
+			proxy: common.Proxy{
+				URL:     "http://proxy.example.com:3128",
+				Host:    "proxy.example.com",
+				Port:    "3128",
+				NoProxy: "localhost,127.0.0.1,.svc,.cluster.local,kubernetes.default.svc,100.64.0.0/12,172.31.0.0/16",
+			},
+		},
+		{
+			goldenFile:                     "alloy/test/events-logger-config.alloy.MC.custom-endpoints.yaml",
+			installationName:               "test-installation",
+			clusterName:                    "test-installation",
+			tracingEnabled:                 true,
+			managementClusterLokiPushURL:   "https://logs.example.com:8443/custom/api/v1/push",
+			managementClusterTempoEndpoint: "https://traces.example.com:4443",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -85,7 +137,25 @@ func TestGenerateAlloyEventsConfig(t *testing.T) {
 				Organization: "test-organization",
 				Provider:     "capa",
 			}
-			config, err := generateAlloyEventsConfig(tc.includeNamespaces, tc.excludeNamespaces, false, tc.tracingEnabled, "<tempo-url>", []string{"giantswarm"}, clusterLabels)
+			tenantNames := tc.tenants
+			if tenantNames == nil {
+				tenantNames = []string{"giantswarm"}
+			}
+			tenants, _ := common.SanitizeTenants(tenantNames)
+			cfg := config.Config{
+				InstallationName:               tc.installationName,
+				ManagementClusterLokiPushURL:   tc.managementClusterLokiPushURL,
+				ManagementClusterTempoEndpoint: tc.managementClusterTempoEndpoint,
+			}
+			tempoEndpoint := common.Endpoint{Host: "<tempo-url>", Port: 443, TLS: true}
+			if !common.IsWorkloadCluster(tc.installationName, tc.clusterName) {
+				cluster := capicluster.New(&capiv1beta2.Cluster{ObjectMeta: metav1.ObjectMeta{Name: tc.clusterName}})
+				tempoEndpoint, err = common.ReadClusterTempoEndpoint(context.Background(), nil, cfg, cluster)
+				if err != nil {
+					t.Fatalf("Failed to read tempo endpoint: %v", err)
+				}
+			}
+			config, err := generateAlloyEventsConfig(tc.includeNamespaces, tc.excludeNamespaces, false, tc.tracingEnabled, tc.tlsKeys, tc.proxy, tempoEndpoint, common.ManagementClusterLokiURLs(cfg), tenants, clusterLabels, nil)
 			if err != nil {
 				t.Fatalf("Failed to generate alloy config: %v", err)
 			}
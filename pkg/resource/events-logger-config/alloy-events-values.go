@@ -0,0 +1,198 @@
+package eventsloggerconfig
+
+import (
+	"github.com/giantswarm/logging-operator/pkg/alloyvalues"
+	"github.com/giantswarm/logging-operator/pkg/common"
+)
+
+// eventsValuesHeader documents the generated values of the events logger.
+const eventsValuesHeader = `# This file was generated by logging-operator.
+# It configures Alloy to be used as events logger.
+# - configMap is the Alloy configuration built by logging-operator, passed as a string
+#   here and will be created by Alloy's chart.
+# - Alloy runs as a deployment, with only 1 replica.
+`
+
+// alloyEventsLabels are the labels of the events logger pods.
+var alloyEventsLabels = map[string]string{
+	"app.kubernetes.io/instance": "alloy-events",
+	"app.kubernetes.io/name":     "alloy",
+}
+
+// eventsValues returns the values of the Alloy chart deploying the events logger with the given configuration.
+func eventsValues(alloyConfig string, tracingEnabled bool, isWorkloadCluster bool, proxy common.Proxy) alloyvalues.Values {
+	values := alloyvalues.Values{
+		NetworkPolicy: eventsNetworkPolicy(tracingEnabled, isWorkloadCluster),
+		Alloy: alloyvalues.Chart{
+			Alloy: alloyvalues.Alloy{
+				ConfigMap: alloyvalues.NewConfigMap(alloyConfig),
+				// We decided to configure the alloy-events resources as such after some investigation done https://github.com/giantswarm/giantswarm/issues/32655
+				// We also updated the alloy-events CPU request and limits here https://github.com/giantswarm/giantswarm/issues/34619 to avoid CPU throttling
+				Resources: alloyvalues.Resources{
+					Limits:   map[string]string{"cpu": "500m", "memory": "256Mi"},
+					Requests: map[string]string{"cpu": "50m", "memory": "128Mi"},
+				},
+				SecurityContext: alloyvalues.SecurityContext{
+					AllowPrivilegeEscalation: false,
+					Capabilities:             &alloyvalues.Capabilities{Drop: []string{"ALL"}},
+					ReadOnlyRootFilesystem:   false,
+					RunAsUser:                10,
+					RunAsGroup:               10,
+					RunAsNonRoot:             true,
+					SeccompProfile:           &alloyvalues.Profile{Type: "RuntimeDefault"},
+				},
+			},
+			Controller: alloyvalues.Controller{
+				Type:     "deployment",
+				Replicas: 1,
+			},
+			CRDs: &alloyvalues.CRDs{Create: false},
+		},
+		// We decided to configure the alloy-events vertical pod autoscaler as such after some investigation done https://github.com/giantswarm/giantswarm/issues/32655
+		VerticalPodAutoscaler: &alloyvalues.VerticalPodAutoscaler{
+			Enabled: true,
+			ResourcePolicy: alloyvalues.ResourcePolicy{
+				ContainerPolicies: []alloyvalues.ContainerPolicy{
+					{
+						ContainerName:       "alloy",
+						ControlledResources: []string{"memory"},
+						ControlledValues:    "RequestsAndLimits",
+					},
+				},
+			},
+		},
+	}
+
+	// Roll the pods out when the configuration changes, for the rollout to follow which configuration they run.
+	values.Alloy.Controller.PodAnnotations = alloyvalues.ConfigPodAnnotations(alloyConfig)
+
+	// Components without proxy settings, like the OTLP gRPC exporters, honor the proxy environment variables
+	if proxy.Enabled() {
+		values.Alloy.Alloy.ExtraEnv = alloyvalues.ProxyEnv(proxy.URL, proxy.NoProxy)
+	}
+
+	if tracingEnabled {
+		values.Alloy.ExtraObjects = []map[string]any{otlpGatewayService()}
+	}
+
+	return values
+}
+
+// eventsNetworkPolicy returns the network policy of the events logger. On the management cluster, it allows access
+// to the Kubernetes API, the DNS, Loki and Tempo. When tracing is enabled, it allows receiving traces over OTLP.
+// There is no network policy on workload clusters without tracing.
+func eventsNetworkPolicy(tracingEnabled bool, isWorkloadCluster bool) *alloyvalues.NetworkPolicy {
+	if !tracingEnabled && isWorkloadCluster {
+		return nil
+	}
+
+	var policy alloyvalues.CiliumNetworkPolicy
+
+	if !isWorkloadCluster {
+		policy.Egress = []alloyvalues.CiliumRule{
+			{ToEntities: []string{"kube-apiserver", "world"}},
+			// Allow direct access to loki-gateway
+			{
+				ToEndpoints: []alloyvalues.LabelSelector{{MatchLabels: map[string]string{
+					"app.kubernetes.io/component": "gateway",
+					"app.kubernetes.io/name":      "loki",
+					"io.kubernetes.pod.namespace": "loki",
+				}}},
+				ToPorts: []alloyvalues.PortRule{{Ports: []alloyvalues.PortProtocol{{Port: "8080", Protocol: "TCP"}}}},
+			},
+			{
+				ToEndpoints: []alloyvalues.LabelSelector{
+					{MatchLabels: map[string]string{"k8s-app": "coredns"}},
+					{MatchLabels: map[string]string{"k8s-app": "k8s-dns-node-cache"}},
+				},
+				ToPorts: []alloyvalues.PortRule{{
+					Ports: []alloyvalues.PortProtocol{{Port: "53", Protocol: "ANY"}, {Port: "1053", Protocol: "ANY"}},
+					Rules: &alloyvalues.L7Rules{DNS: []alloyvalues.DNSRule{{MatchPattern: "*"}}},
+				}},
+			},
+			{
+				ToEndpoints: []alloyvalues.LabelSelector{{MatchLabels: map[string]string{"app.kubernetes.io/name": "ingress-nginx"}}},
+				ToPorts:     []alloyvalues.PortRule{{Ports: []alloyvalues.PortProtocol{{Port: "80", Protocol: "ANY"}, {Port: "443", Protocol: "ANY"}}}},
+			},
+			{
+				ToEndpoints: []alloyvalues.LabelSelector{{MatchLabels: alloyEventsLabels}},
+				ToPorts:     []alloyvalues.PortRule{{Ports: []alloyvalues.PortProtocol{{Port: "12345", Protocol: "TCP"}}}},
+			},
+		}
+
+		// Allow direct access to tempo
+		if tracingEnabled {
+			policy.Egress = append(policy.Egress, alloyvalues.CiliumRule{
+				ToEndpoints: []alloyvalues.LabelSelector{{MatchLabels: map[string]string{
+					"app.kubernetes.io/name":      "tempo",
+					"io.kubernetes.pod.namespace": "tempo",
+				}}},
+				ToPorts: []alloyvalues.PortRule{{Ports: []alloyvalues.PortProtocol{{Port: "4317", Protocol: "TCP"}, {Port: "4318", Protocol: "TCP"}}}},
+			})
+		}
+	}
+
+	if tracingEnabled {
+		policy.Ingress = []alloyvalues.CiliumRule{
+			{
+				ToPorts: []alloyvalues.PortRule{{Ports: []alloyvalues.PortProtocol{
+					// Alloy Control Plane
+					{Port: "12345", Protocol: "TCP"},
+					// OTLP GRPC
+					{Port: "4317", Protocol: "TCP"},
+					// OTLP HTTP
+					{Port: "4318", Protocol: "TCP"},
+				}}},
+			},
+		}
+	}
+
+	return &alloyvalues.NetworkPolicy{Cilium: policy}
+}
+
+// otlpGatewayService returns the service the workloads send their traces to.
+func otlpGatewayService() map[string]any {
+	return map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Service",
+		"metadata": map[string]any{
+			"annotations": map[string]any{
+				"meta.helm.sh/release-name":      "alloy-events",
+				"meta.helm.sh/release-namespace": "kube-system",
+			},
+			"labels": map[string]any{
+				"app.kubernetes.io/component":    "networking",
+				"app.kubernetes.io/instance":     "alloy-events",
+				"app.kubernetes.io/managed-by":   "Helm",
+				"app.kubernetes.io/name":         "alloy",
+				"app.kubernetes.io/part-of":      "alloy",
+				"application.giantswarm.io/team": "atlas",
+				"giantswarm.io/managed-by":       "alloy-events",
+				"giantswarm.io/service-type":     "managed",
+				"helm.sh/chart":                  "alloy-1.1.0",
+			},
+			"name":      "otlp-gateway",
+			"namespace": "kube-system",
+		},
+		"spec": map[string]any{
+			"ports": []any{
+				map[string]any{
+					"appProtocol": "grpc",
+					"name":        "otlp",
+					"port":        4317,
+					"protocol":    "TCP",
+					"targetPort":  4317,
+				},
+				map[string]any{
+					"appProtocol": "http",
+					"name":        "otlp-http",
+					"port":        4318,
+					"protocol":    "TCP",
+					"targetPort":  4318,
+				},
+			},
+			"selector": alloyEventsLabels,
+			"type":     "ClusterIP",
+		},
+	}
+}
@@ -2,11 +2,12 @@ package eventsloggerconfig
 
 import (
 	"fmt"
+	"text/template"
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	capi "sigs.k8s.io/cluster-api/api/core/v1beta1" //nolint:staticcheck // SA1019 deprecated package
 
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
 	"github.com/giantswarm/logging-operator/pkg/common"
 )
 
@@ -14,17 +15,17 @@ const (
 	eventsLogggerConfigName = "events-logger-config"
 )
 
-func generateEventsLoggerConfig(cluster *capi.Cluster, tenants []string, includeNamespaces []string, excludeNamespaces []string, insecureCA bool, tracingEnabled bool, tempoURL string, clusterLabels common.ClusterLabels) (v1.ConfigMap, error) {
+func generateEventsLoggerConfig(cluster *capicluster.Cluster, tenants []common.Tenant, includeNamespaces []string, excludeNamespaces []string, insecureCA bool, tracingEnabled bool, tlsKeys common.TLSKeys, proxy common.Proxy, tempoEndpoint common.Endpoint, managementClusterLokiURLs common.LokiURLs, clusterLabels common.ClusterLabels, configTemplate *template.Template) (v1.ConfigMap, error) {
 	var values string
 	var err error
 
-	values, err = generateAlloyEventsConfig(includeNamespaces, excludeNamespaces, insecureCA, tracingEnabled, tempoURL, tenants, clusterLabels)
+	values, err = generateAlloyEventsConfig(includeNamespaces, excludeNamespaces, insecureCA, tracingEnabled, tlsKeys, proxy, tempoEndpoint, managementClusterLokiURLs, tenants, clusterLabels, configTemplate)
 	if err != nil {
 		return v1.ConfigMap{}, err
 	}
 
 	configmap := v1.ConfigMap{
-		ObjectMeta: configMeta(cluster),
+		ObjectMeta: ConfigMeta(cluster),
 		Data: map[string]string{
 			"values": values,
 		},
@@ -33,8 +34,8 @@ func generateEventsLoggerConfig(cluster *capi.Cluster, tenants []string, include
 	return configmap, nil
 }
 
-// ConfigMeta returns metadata for the logging-config
-func configMeta(cluster *capi.Cluster) metav1.ObjectMeta {
+// ConfigMeta returns metadata for the events-logger-config
+func ConfigMeta(cluster *capicluster.Cluster) metav1.ObjectMeta {
 	metadata := metav1.ObjectMeta{
 		Name:      getEventsLoggerConfigName(cluster),
 		Namespace: cluster.GetNamespace(),
@@ -42,9 +43,10 @@ func configMeta(cluster *capi.Cluster) metav1.ObjectMeta {
 	}
 
 	common.AddCommonLabels(metadata.Labels)
+	common.AddClusterOwner(&metadata, cluster)
 	return metadata
 }
 
-func getEventsLoggerConfigName(cluster *capi.Cluster) string {
+func getEventsLoggerConfigName(cluster *capicluster.Cluster) string {
 	return fmt.Sprintf("%s-%s", cluster.GetName(), eventsLogggerConfigName)
 }
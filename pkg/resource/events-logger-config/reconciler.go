@@ -2,129 +2,242 @@ package eventsloggerconfig
 
 import (
 	"context"
-	"reflect"
+	"text/template"
 
-	"github.com/blang/semver"
 	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
 	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
-	capi "sigs.k8s.io/cluster-api/api/core/v1beta1" //nolint:staticcheck // SA1019 deprecated package
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	ollyop "github.com/giantswarm/observability-operator/pkg/common/tenancy"
 
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
 	"github.com/giantswarm/logging-operator/pkg/common"
 	"github.com/giantswarm/logging-operator/pkg/config"
-)
-
-var (
-	supportTracing = semver.MustParse("1.11.0")
+	"github.com/giantswarm/logging-operator/pkg/key"
+	"github.com/giantswarm/logging-operator/pkg/metrics"
+	eventsloggersecret "github.com/giantswarm/logging-operator/pkg/resource/events-logger-secret"
+	"github.com/giantswarm/logging-operator/pkg/revision"
+	"github.com/giantswarm/logging-operator/pkg/rollout"
 )
 
 // Resource implements a resource.Interface to handle
 // EventsLogger config: extra events-logger config defining what we want to retrieve.
 type Resource struct {
-	Client            client.Client
-	Config            config.Config
-	IncludeNamespaces []string
-	ExcludeNamespaces []string
+	Client   client.Client
+	Config   config.Config
+	Recorder record.EventRecorder
+}
+
+// Name returns the name of the resource.
+func (r *Resource) Name() string {
+	return eventsLogggerConfigName
+}
+
+// DependsOn returns the resources which must be reconciled first: the secret holding the credentials the config refers to.
+func (r *Resource) DependsOn() []string {
+	return []string{eventsloggersecret.ResourceName}
 }
 
 // ReconcileCreate ensures events-logger config is created with the right credentials
-func (r *Resource) ReconcileCreate(ctx context.Context, cluster *capi.Cluster) (ctrl.Result, error) {
+func (r *Resource) ReconcileCreate(ctx context.Context, cluster *capicluster.Cluster) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 	logger.Info("events-logger-config create")
 
-	var tempoURL string
-	var tenants []string
+	desiredEventsLoggerConfig, pinnedRevision, err := r.desired(ctx, cluster)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Check if config already exists.
+	logger.Info("events-logger-config - getting", "namespace", desiredEventsLoggerConfig.GetNamespace(), "name", desiredEventsLoggerConfig.GetName())
+	var currentEventsLoggerConfig v1.ConfigMap
+	err = r.Client.Get(ctx, types.NamespacedName{Name: desiredEventsLoggerConfig.GetName(), Namespace: desiredEventsLoggerConfig.GetNamespace()}, &currentEventsLoggerConfig)
+	if err != nil {
+		if apimachineryerrors.IsNotFound(err) {
+			logger.Info("events-logger-config not found, creating")
+			err = r.apply(ctx, cluster, desiredEventsLoggerConfig)
+			if err != nil {
+				return ctrl.Result{}, errors.WithStack(err)
+			}
+			r.Recorder.Eventf(cluster.Object, v1.EventTypeNormal, common.EventReason(r.Name(), common.CreatedAction), "Created configmap %s/%s", desiredEventsLoggerConfig.GetNamespace(), desiredEventsLoggerConfig.GetName())
+			err = r.recordRevision(ctx, cluster, desiredEventsLoggerConfig, pinnedRevision != nil)
+			if err != nil {
+				return ctrl.Result{}, errors.WithStack(err)
+			}
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, errors.WithStack(err)
+	}
+
+	// Take over the fields written before the operator used server-side apply
+	err = common.UpgradeManagedFields(ctx, r.Client, &currentEventsLoggerConfig)
+	if err != nil {
+		return ctrl.Result{}, errors.WithStack(err)
+	}
+
+	upToDate, err := common.ConfigMapUpToDate(currentEventsLoggerConfig, common.ConfigMapApplyConfiguration(desiredEventsLoggerConfig))
+	if err != nil {
+		return ctrl.Result{}, errors.WithStack(err)
+	}
+	if upToDate {
+		logger.Info("events-logger-config up to date")
+		// Recording is idempotent, this records the configmaps of clusters without history and retries failed records.
+		err = r.recordRevision(ctx, cluster, desiredEventsLoggerConfig, pinnedRevision != nil)
+		if err != nil {
+			return ctrl.Result{}, errors.WithStack(err)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// Hold the update back until the rollout reaches the cluster, the cluster is reconciled again meanwhile.
+	// Rollbacks to a pinned revision are not held back.
+	decision := rollout.FromContext(ctx)
+	if !decision.Allowed && pinnedRevision == nil {
+		logger.Info("events-logger-config - update held back by the rollout", "reason", decision.Reason)
+		decision.Hold()
+		return ctrl.Result{RequeueAfter: rollout.RequeueAfter}, nil
+	}
+
+	diff := common.ConfigMapDiff(currentEventsLoggerConfig, desiredEventsLoggerConfig)
+	logger.Info("events-logger-config - updating", "diff", diff)
+	err = r.apply(ctx, cluster, desiredEventsLoggerConfig)
+	if err != nil {
+		return ctrl.Result{}, errors.WithStack(err)
+	}
+	r.Recorder.Eventf(cluster.Object, v1.EventTypeNormal, common.EventReason(r.Name(), common.UpdatedAction), "Updated configmap %s/%s:\n%s", desiredEventsLoggerConfig.GetNamespace(), desiredEventsLoggerConfig.GetName(), common.EventDiff(diff))
+	metrics.ObserveConfigDriftCorrection(r.Name())
+
+	err = r.recordRevision(ctx, cluster, desiredEventsLoggerConfig, pinnedRevision != nil)
+	if err != nil {
+		return ctrl.Result{}, errors.WithStack(err)
+	}
+
+	logger.Info("events-logger-config - done")
+	return ctrl.Result{}, nil
+
+}
+
+// Desired implements resource.Renderer, it returns the events-logger-config ReconcileCreate writes for the given cluster.
+func (r *Resource) Desired(ctx context.Context, cluster *capicluster.Cluster) ([]client.Object, error) {
+	desiredEventsLoggerConfig, _, err := r.desired(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	return []client.Object{&desiredEventsLoggerConfig}, nil
+}
+
+// desired returns the desired events-logger-config of the cluster, with the data of the revision it is pinned to if any.
+func (r *Resource) desired(ctx context.Context, cluster *capicluster.Cluster) (v1.ConfigMap, *v1.ConfigMap, error) {
+	logger := log.FromContext(ctx)
+	cfg := config.FromContext(ctx, r.Config)
+
+	var tempoEndpoint common.Endpoint
+	var tenants []common.Tenant
 	var err error
 	var tracingEnabled bool
 
-	// Only retrieve Tempo ingress if tracing is enabled AND observability bundle version >= 1.11.0 (release v30+)
-	if r.Config.EnableTracingFlag {
+	// Only retrieve Tempo endpoint if tracing is enabled for the cluster AND observability bundle version >= 1.11.0 (release v30+)
+	if common.IsTracingEnabled(cluster, cfg.EnableTracingFlag) {
 		// Get observability bundle version
 		observabilityBundleVersion, err := common.GetObservabilityBundleAppVersion(ctx, r.Client, cluster)
 		if err != nil {
 			logger.Info("Failed to get observability bundle version", "error", err)
-			return ctrl.Result{}, errors.WithStack(err)
+			return v1.ConfigMap{}, nil, errors.WithStack(err)
 		}
 
 		// Check if version >= 1.11.0
-		if observabilityBundleVersion.GE(supportTracing) {
+		if observabilityBundleVersion.GE(common.TracingObservabilityBundleVersion) {
 			tracingEnabled = true
 
-			tempoURL, err = common.ReadTempoIngressURL(ctx, cluster, r.Client)
+			tempoEndpoint, err = common.ReadClusterTempoEndpoint(ctx, r.Client, cfg, cluster)
 			if err != nil {
-				logger.Info("Failed to read Tempo ingress URL, but tracing is enabled", "error", err)
-				return ctrl.Result{}, errors.WithStack(err)
+				logger.Info("Failed to read Tempo endpoint, but tracing is enabled", "error", err)
+				r.Recorder.Eventf(cluster.Object, v1.EventTypeWarning, common.TempoEndpointMissingReason, "Tracing is enabled but the Tempo endpoint could not be read: %s", err)
+				return v1.ConfigMap{}, nil, errors.WithStack(err)
 			}
 
 			// Get list of tenants
-			tenants, err = ollyop.ListTenants(ctx, r.Client)
+			tenantNames, err := ollyop.ListTenants(ctx, r.Client)
 			if err != nil {
-				return ctrl.Result{}, errors.WithStack(err)
+				return v1.ConfigMap{}, nil, errors.WithStack(err)
+			}
+			var invalidTenants []string
+			tenants, invalidTenants = common.SanitizeTenants(tenantNames)
+			if len(invalidTenants) > 0 {
+				logger.Info("events-logger-config - ignoring invalid tenants", "tenants", invalidTenants)
+				r.Recorder.Eventf(cluster.Object, v1.EventTypeWarning, common.InvalidTenantsReason, "Ignoring invalid tenants %q, tenants must be %s", invalidTenants, common.TenantNamePattern)
 			}
 		} else {
 			logger.Info("Tracing is enabled but observability bundle version is too old", "version", observabilityBundleVersion.String(), "required", ">=1.11.0")
+			r.Recorder.Eventf(cluster.Object, v1.EventTypeWarning, common.ObservabilityBundleTooOldForTracingReason, "Tracing is enabled but observability bundle %s is older than %s, tracing is disabled", observabilityBundleVersion, common.TracingObservabilityBundleVersion)
 			tracingEnabled = false
 		}
 	}
 
 	// Extract cluster labels once at this level where we have k8s client
-	clusterLabels, err := common.ExtractClusterLabels(ctx, r.Client, cluster, r.Config)
+	clusterLabels, err := common.ExtractClusterLabels(ctx, r.Client, cluster, cfg)
 	if err != nil {
-		return ctrl.Result{}, errors.WithStack(err)
+		return v1.ConfigMap{}, nil, errors.WithStack(err)
 	}
 
-	// Get desired config
-	desiredEventsLoggerConfig, err := generateEventsLoggerConfig(cluster, tenants, r.IncludeNamespaces, r.ExcludeNamespaces, r.Config.InsecureCA, tracingEnabled, tempoURL, clusterLabels)
+	proxy, err := common.ReadProxy(cluster, cfg)
 	if err != nil {
-		logger.Info("events-logger-config - failed generating events-logger config!", "error", err)
-		return ctrl.Result{}, errors.WithStack(err)
+		r.Recorder.Eventf(cluster.Object, v1.EventTypeWarning, common.InvalidProxyReason, "Proxy of the cluster is invalid: %s", err)
+		return v1.ConfigMap{}, nil, errors.WithStack(err)
 	}
 
-	// Check if config already exists.
-	logger.Info("events-logger-config - getting", "namespace", desiredEventsLoggerConfig.GetNamespace(), "name", desiredEventsLoggerConfig.GetName())
-	var currentEventsLoggerConfig v1.ConfigMap
-	err = r.Client.Get(ctx, types.NamespacedName{Name: desiredEventsLoggerConfig.GetName(), Namespace: desiredEventsLoggerConfig.GetNamespace()}, &currentEventsLoggerConfig)
+	// Get desired config, falling back to the built-in configuration when the replacement template fails to render
+	configTemplate := r.template(ctx, cluster, cfg)
+	desiredEventsLoggerConfig, err := generateEventsLoggerConfig(cluster, tenants, cfg.IncludeEventsFromNamespaces, cfg.ExcludeEventsFromNamespaces, cfg.InsecureCA, tracingEnabled, common.SecretTLSKeys(cfg), proxy, tempoEndpoint, common.ManagementClusterLokiURLs(cfg), clusterLabels, configTemplate)
+	if configTemplate != nil && common.IsTemplateError(err) {
+		logger.Info("events-logger-config - failed rendering the replacement template, using the built-in configuration", "error", err)
+		r.Recorder.Eventf(cluster.Object, v1.EventTypeWarning, common.InvalidTemplateReason, "Replacement template of %s is invalid, using the built-in configuration: %s", r.Name(), err)
+		desiredEventsLoggerConfig, err = generateEventsLoggerConfig(cluster, tenants, cfg.IncludeEventsFromNamespaces, cfg.ExcludeEventsFromNamespaces, cfg.InsecureCA, tracingEnabled, common.SecretTLSKeys(cfg), proxy, tempoEndpoint, common.ManagementClusterLokiURLs(cfg), clusterLabels, nil)
+	}
 	if err != nil {
-		if apimachineryerrors.IsNotFound(err) {
-			logger.Info("events-logger-config not found, creating")
-			err = r.Client.Create(ctx, &desiredEventsLoggerConfig)
-			if err != nil {
-				return ctrl.Result{}, errors.WithStack(err)
-			}
-			return ctrl.Result{}, nil
-		}
-		return ctrl.Result{}, errors.WithStack(err)
+		logger.Info("events-logger-config - failed generating events-logger config!", "error", err)
+		return v1.ConfigMap{}, nil, errors.WithStack(err)
 	}
 
-	if !needUpdate(currentEventsLoggerConfig, desiredEventsLoggerConfig) {
-		logger.Info("events-logger-config up to date")
-		return ctrl.Result{}, nil
+	// Merge the values overrides of the cluster owner over the generated values.
+	err = common.OverrideValues(ctx, r.Client, cluster, r.Name(), &desiredEventsLoggerConfig)
+	if err != nil {
+		logger.Info("events-logger-config - failed merging values overrides", "error", err)
+		r.Recorder.Eventf(cluster.Object, v1.EventTypeWarning, common.InvalidValuesOverridesReason, "Failed to merge the values overrides: %s", err)
+		return v1.ConfigMap{}, nil, errors.WithStack(err)
 	}
 
-	logger.Info("events-logger-config - updating")
-	err = r.Client.Update(ctx, &desiredEventsLoggerConfig)
+	// Roll the config back to the revision the cluster is pinned to, until the pin is removed.
+	pinnedRevision, err := r.pinnedRevision(ctx, cluster)
 	if err != nil {
-		return ctrl.Result{}, errors.WithStack(err)
+		return v1.ConfigMap{}, nil, errors.WithStack(err)
+	}
+	if pinnedRevision != nil {
+		logger.Info("events-logger-config - pinned to a revision", "revision", pinnedRevision.GetLabels()[key.RevisionLabel])
+		desiredEventsLoggerConfig.Data = pinnedRevision.Data
 	}
 
-	logger.Info("events-logger-config - done")
-	return ctrl.Result{}, nil
-
+	return desiredEventsLoggerConfig, pinnedRevision, nil
 }
 
-func (r *Resource) ReconcileDelete(ctx context.Context, cluster *capi.Cluster) (ctrl.Result, error) {
+func (r *Resource) ReconcileDelete(ctx context.Context, cluster *capicluster.Cluster) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 	logger.Info("events-logger-config delete")
 
+	// Delete the revision history of the config.
+	err := revision.Delete(ctx, r.Client, cluster, r.Name())
+	if err != nil {
+		return ctrl.Result{}, errors.WithStack(err)
+	}
+
 	// Get expected configmap.
 	var currentEventsLoggerConfig v1.ConfigMap
-	err := r.Client.Get(ctx, types.NamespacedName{Name: getEventsLoggerConfigName(cluster), Namespace: cluster.GetNamespace()}, &currentEventsLoggerConfig)
+	err = r.Client.Get(ctx, types.NamespacedName{Name: getEventsLoggerConfigName(cluster), Namespace: cluster.GetNamespace()}, &currentEventsLoggerConfig)
 	if err != nil {
 		if apimachineryerrors.IsNotFound(err) {
 			logger.Info("events-logger-config not found, nothing to delete")
@@ -145,12 +258,65 @@ func (r *Resource) ReconcileDelete(ctx context.Context, cluster *capi.Cluster) (
 		}
 		return ctrl.Result{}, errors.WithStack(err)
 	}
+	r.Recorder.Eventf(cluster.Object, v1.EventTypeNormal, common.EventReason(r.Name(), common.DeletedAction), "Deleted configmap %s/%s", currentEventsLoggerConfig.GetNamespace(), currentEventsLoggerConfig.GetName())
 	logger.Info("events-logger-config deleted")
 
 	return ctrl.Result{}, nil
 }
 
-// needUpdate return true if current.Data and desired.Data do not match.
-func needUpdate(current, desired v1.ConfigMap) bool {
-	return !reflect.DeepEqual(current.Data, desired.Data)
+// template returns the replacement template of the Alloy configuration, nil when there is none or when it does not
+// render a valid configuration for a synthetic cluster, the built-in configuration being used then.
+func (r *Resource) template(ctx context.Context, cluster *capicluster.Cluster, cfg config.Config) *template.Template {
+	logger := log.FromContext(ctx)
+
+	configTemplate, err := common.ReadTemplate(ctx, r.Client, cfg, r.Name())
+	if err == nil && configTemplate != nil {
+		err = validateConfigTemplate(configTemplate, cfg)
+	}
+	if err != nil {
+		logger.Info("events-logger-config - invalid replacement template, using the built-in configuration", "error", err)
+		r.Recorder.Eventf(cluster.Object, v1.EventTypeWarning, common.InvalidTemplateReason, "Replacement template of %s is invalid, using the built-in configuration: %s", r.Name(), err)
+		return nil
+	}
+	return configTemplate
+}
+
+// pinnedRevision returns the revision the config is pinned to by the revision annotation of the cluster, nil when
+// it is not pinned. An invalid annotation or a missing revision is reported with a warning event.
+func (r *Resource) pinnedRevision(ctx context.Context, cluster *capicluster.Cluster) (*v1.ConfigMap, error) {
+	number, pinned, err := revision.Pinned(cluster, r.Name())
+	if err != nil {
+		r.Recorder.Eventf(cluster.Object, v1.EventTypeWarning, common.InvalidRevisionPinReason, "Failed to pin %s to a revision: %s", r.Name(), err)
+		return nil, err
+	}
+	if !pinned {
+		return nil, nil
+	}
+
+	pinnedRevision, err := revision.Get(ctx, r.Client, cluster, r.Name(), number)
+	if err != nil {
+		r.Recorder.Eventf(cluster.Object, v1.EventTypeWarning, common.InvalidRevisionPinReason, "Failed to pin %s to revision %d: %s", r.Name(), number, err)
+		return nil, err
+	}
+	return pinnedRevision, nil
+}
+
+// recordRevision keeps the applied config in the revision history, unless it is pinned to a revision.
+func (r *Resource) recordRevision(ctx context.Context, cluster *capicluster.Cluster, desired v1.ConfigMap, pinned bool) error {
+	if pinned {
+		return nil
+	}
+	cfg := config.FromContext(ctx, r.Config)
+	_, err := revision.Record(ctx, r.Client, cluster, r.Name(), desired, cfg.RevisionHistoryLimit)
+	return err
+}
+
+// apply applies the desired configmap with server-side apply.
+// Conflicts with fields owned by other managers are reported with a warning event.
+func (r *Resource) apply(ctx context.Context, cluster *capicluster.Cluster, desired v1.ConfigMap) error {
+	err := common.Apply(ctx, r.Client, common.ConfigMapApplyConfiguration(desired))
+	if apimachineryerrors.IsConflict(err) {
+		r.Recorder.Eventf(cluster.Object, v1.EventTypeWarning, common.ApplyConflictReason, "Failed to apply configmap %s/%s, fields are owned by another manager: %s", desired.GetNamespace(), desired.GetName(), err)
+	}
+	return err
 }
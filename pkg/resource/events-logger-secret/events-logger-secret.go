@@ -3,26 +3,29 @@ package eventsloggersecret
 import (
 	"context"
 	"fmt"
+	"text/template"
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
-	capi "sigs.k8s.io/cluster-api/api/core/v1beta1" //nolint:staticcheck // SA1019 deprecated package
-
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
 	"github.com/giantswarm/logging-operator/pkg/common"
 	loggingsecret "github.com/giantswarm/logging-operator/pkg/resource/logging-secret"
 )
 
 const (
 	eventsLoggerSecretName = "events-logger-secret" // #nosec G101
+
+	// ResourceName is the name of the events-logger-secret resource.
+	ResourceName = eventsLoggerSecretName
 )
 
-func (r *Resource) generateEventsLoggerSecret(ctx context.Context, cluster *capi.Cluster, lokiURL string, tracingEnabled bool) (v1.Secret, error) {
+func (r *Resource) generateEventsLoggerSecret(ctx context.Context, cluster *capicluster.Cluster, lokiURLs common.LokiURLs, caBundle string, clientCertificate *common.ClientCertificate, tracingEnabled bool, secretTemplate *template.Template) (v1.Secret, error) {
 	var data map[string][]byte
 	var err error
 
 	// In the case of Alloy being the events logger, we reuse the secret generation from the logging-secret package
-	data, err = loggingsecret.GenerateAlloyLoggingSecret(ctx, cluster, r.LogsAuthManager, r.TracesAuthManager, lokiURL, tracingEnabled)
+	data, err = loggingsecret.GenerateAlloyLoggingSecret(ctx, cluster, r.LogsAuthManager, r.TracesAuthManager, lokiURLs, caBundle, clientCertificate, tracingEnabled, secretTemplate)
 	if err != nil {
 		return v1.Secret{}, err
 	}
@@ -36,7 +39,7 @@ func (r *Resource) generateEventsLoggerSecret(ctx context.Context, cluster *capi
 }
 
 // SecretMeta returns metadata for the events-logger-secret
-func secretMeta(cluster *capi.Cluster) metav1.ObjectMeta {
+func secretMeta(cluster *capicluster.Cluster) metav1.ObjectMeta {
 	metadata := metav1.ObjectMeta{
 		Name:      getEventsLoggerSecretName(cluster),
 		Namespace: cluster.GetNamespace(),
@@ -44,9 +47,10 @@ func secretMeta(cluster *capi.Cluster) metav1.ObjectMeta {
 	}
 
 	common.AddCommonLabels(metadata.Labels)
+	common.AddClusterOwner(&metadata, cluster)
 	return metadata
 }
 
-func getEventsLoggerSecretName(cluster *capi.Cluster) string {
+func getEventsLoggerSecretName(cluster *capicluster.Cluster) string {
 	return fmt.Sprintf("%s-%s", cluster.GetName(), eventsLoggerSecretName)
 }
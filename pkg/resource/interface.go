@@ -3,8 +3,10 @@ package resource
 import (
 	"context"
 
-	capi "sigs.k8s.io/cluster-api/api/core/v1beta1" //nolint:staticcheck // SA1019 deprecated package
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
 )
 
 // Interface provides a resource interface which is the controller core logic
@@ -12,7 +14,21 @@ import (
 //
 // An implementation can then be used by a controller to extend its capabilities.
 type Interface interface {
-	ReconcileCreate(ctx context.Context, cluster *capi.Cluster) (ctrl.Result, error)
+	// Name returns the name of the resource, e.g. logging-config.
+	Name() string
+
+	// DependsOn returns the names of the resources which must be reconciled successfully
+	// before this one, e.g. logging-config depends on logging-secret.
+	DependsOn() []string
+
+	ReconcileCreate(ctx context.Context, cluster *capicluster.Cluster) (ctrl.Result, error)
+
+	ReconcileDelete(ctx context.Context, cluster *capicluster.Cluster) (ctrl.Result, error)
+}
 
-	ReconcileDelete(ctx context.Context, cluster *capi.Cluster) (ctrl.Result, error)
+// Renderer is implemented by the resources which can tell the objects they write for a cluster without writing them,
+// e.g. to render the configuration of a cluster.
+type Renderer interface {
+	// Desired returns the objects ReconcileCreate writes for the given cluster.
+	Desired(ctx context.Context, cluster *capicluster.Cluster) ([]client.Object, error)
 }
@@ -1,134 +1,433 @@
 package loggingconfig
 
 import (
-	"bytes"
-	_ "embed"
 	"fmt"
 	"slices"
+	"strings"
 	"text/template"
 
-	"github.com/Masterminds/sprig/v3"
 	"github.com/blang/semver"
-	capi "sigs.k8s.io/cluster-api/api/core/v1beta1" //nolint:staticcheck // SA1019 deprecated package
+	"github.com/pkg/errors"
 
+	"github.com/giantswarm/logging-operator/pkg/alloy"
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
 	"github.com/giantswarm/logging-operator/pkg/common"
+	"github.com/giantswarm/logging-operator/pkg/config"
 )
 
 var (
-	//go:embed alloy/logging.alloy.template
-	alloyLogging         string
-	alloyLoggingTemplate *template.Template
-
-	//go:embed alloy/logging-config.alloy.yaml.template
-	alloyLoggingConfig         string
-	alloyLoggingConfigTemplate *template.Template
-
 	alloyNodeFilterFixedObservabilityBundleAppVersion = semver.MustParse("2.4.0")
 	alloyNodeFilterImageVersion                       = semver.MustParse("1.12.0")
 )
 
-func init() {
-	alloyLoggingTemplate = template.Must(template.New("logging.alloy").Funcs(sprig.FuncMap()).Parse(alloyLogging))
-	alloyLoggingConfigTemplate = template.Must(template.New("logging-config.alloy.yaml").Funcs(sprig.FuncMap()).Parse(alloyLoggingConfig))
-}
-
 // GenerateAlloyLoggingConfig returns a configmap for
-// the logging extra-config
-func GenerateAlloyLoggingConfig(cluster *capi.Cluster, observabilityBundleVersion semver.Version, defaultNamespaces, tenants []string, clusterLabels common.ClusterLabels, insecureCA bool, enableNodeFiltering bool, enableNetworkMonitoring bool) (string, error) {
-	var values bytes.Buffer
-
+// the logging extra-config. The Alloy configuration is rendered with the replacement template when one is given.
+func GenerateAlloyLoggingConfig(cluster *capicluster.Cluster, observabilityBundleVersion semver.Version, defaultNamespaces []string, tenants []common.Tenant, clusterLabels common.ClusterLabels, managementClusterLokiURLs common.LokiURLs, insecureCA bool, tlsKeys common.TLSKeys, proxy common.Proxy, enableNodeFiltering bool, enableNetworkMonitoring bool, configTemplate *template.Template) (string, error) {
 	// If network monitoring is enabled, node filtering must also be enabled as clustering does not work with host network.
 	enableNodeFiltering = enableNodeFiltering || enableNetworkMonitoring
 
-	alloyConfig, err := generateAlloyConfig(tenants, clusterLabels, insecureCA, enableNodeFiltering, enableNetworkMonitoring)
+	alloyConfig, components, err := generateAlloyConfig(tenants, clusterLabels, managementClusterLokiURLs, insecureCA, tlsKeys, proxy, enableNodeFiltering, enableNetworkMonitoring)
 	if err != nil {
 		return "", err
 	}
 
-	data := struct {
-		AlloyConfig                      string
-		AlloyImageTag                    *string
-		DefaultWorkloadClusterNamespaces []string
-		DefaultWriteTenant               string
-		NetworkMonitoringEnabled         bool
-		NodeFilteringEnabled             bool
-		IsWorkloadCluster                bool
-		PriorityClassName                string
-	}{
-		AlloyConfig:                      alloyConfig,
-		DefaultWorkloadClusterNamespaces: defaultNamespaces,
-		DefaultWriteTenant:               common.DefaultWriteTenant,
-		NetworkMonitoringEnabled:         enableNetworkMonitoring,
-		NodeFilteringEnabled:             enableNodeFiltering,
+	if configTemplate != nil {
+		settings := common.ConfigSettings{
+			ManagementClusterLokiURLs: managementClusterLokiURLs,
+			InsecureCA:                insecureCA,
+			TLSKeys:                   tlsKeys,
+			Proxy:                     proxy,
+			NodeFiltering:             enableNodeFiltering,
+			NetworkMonitoring:         enableNetworkMonitoring,
+		}
+		alloyConfig, err = common.RenderTemplate(configTemplate, common.NewConfigTemplateData(alloyConfig, components, settings, clusterLabels, tenants), alloy.Validate)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	opts := loggingValuesOptions{
 		IsWorkloadCluster:                common.IsWorkloadCluster(clusterLabels.Installation, clusterLabels.ClusterID),
-		PriorityClassName:                common.PriorityClassName,
+		NodeFilteringEnabled:             enableNodeFiltering,
+		NetworkMonitoringEnabled:         enableNetworkMonitoring,
+		DefaultWorkloadClusterNamespaces: defaultNamespaces,
+		Proxy:                            proxy,
 	}
 
 	if enableNodeFiltering && observabilityBundleVersion.LT(alloyNodeFilterFixedObservabilityBundleAppVersion) {
 		// Use fixed image version
-		imageTag := fmt.Sprintf("v%s", alloyNodeFilterImageVersion.String())
-		data.AlloyImageTag = &imageTag
+		opts.AlloyImageTag = fmt.Sprintf("v%s", alloyNodeFilterImageVersion.String())
+	}
+
+	return loggingValues(alloyConfig, opts).Marshal(loggingValuesHeader)
+}
+
+// validateConfigTemplate checks that the replacement template renders a valid configuration for a synthetic
+// workload cluster, configured like the installation.
+func validateConfigTemplate(configTemplate *template.Template, cfg config.Config) error {
+	tenants, _ := common.SanitizeTenants([]string{common.DefaultWriteTenant})
+	_, err := GenerateAlloyLoggingConfig(nil, alloyNodeFilterFixedObservabilityBundleAppVersion, cfg.DefaultNamespaces, tenants, common.SyntheticClusterLabels(cfg), common.ManagementClusterLokiURLs(cfg), cfg.InsecureCA, common.SecretTLSKeys(cfg), common.Proxy{}, cfg.EnableNodeFilteringFlag, cfg.EnableNetworkMonitoringFlag, configTemplate)
+	return err
+}
+
+// loggingConfig holds the settings the components of the logs agent configuration depend on.
+type loggingConfig struct {
+	clusterLabels             common.ClusterLabels
+	managementClusterLokiURLs common.LokiURLs
+	// authenticated is true when logs are sent with the credentials of the secret. The management cluster only uses
+	// them when network monitoring is enabled, it sends logs to the in-cluster services without authenticating otherwise.
+	authenticated bool
+	insecureCA    bool
+	tlsKeys       common.TLSKeys
+	proxy         common.Proxy
+	credentials   alloy.RemoteKubernetesSecret
+}
+
+func generateAlloyConfig(tenants []common.Tenant, clusterLabels common.ClusterLabels, managementClusterLokiURLs common.LokiURLs, insecureCA bool, tlsKeys common.TLSKeys, proxy common.Proxy, enableNodeFiltering bool, enableNetworkMonitoring bool) (string, []alloy.PrintedComponent, error) {
+	// Ensure default tenant is included in the list of tenants
+	if !slices.ContainsFunc(tenants, func(tenant common.Tenant) bool { return tenant.Name == common.DefaultWriteTenant }) {
+		defaultTenant, _ := common.SanitizeTenants([]string{common.DefaultWriteTenant})
+		tenants = append(slices.Clip(tenants), defaultTenant...)
+	}
+
+	c := loggingConfig{
+		clusterLabels:             clusterLabels,
+		managementClusterLokiURLs: managementClusterLokiURLs,
+		authenticated:             common.IsWorkloadCluster(clusterLabels.Installation, clusterLabels.ClusterID) || enableNetworkMonitoring,
+		insecureCA:                insecureCA,
+		tlsKeys:                   tlsKeys,
+		proxy:                     proxy,
+		credentials: alloy.RemoteKubernetesSecret{
+			Label:     "credentials",
+			Namespace: "kube-system",
+			Name:      common.AlloyLogAgentAppName,
+		},
+	}
+	if !c.authenticated {
+		c.tlsKeys.CertPEM, c.tlsKeys.KeyPEM = "", ""
 	}
 
-	err = alloyLoggingConfigTemplate.Execute(&values, data)
+	var config alloy.Config
+	config.Add(alloy.Logging{Level: "warn", Format: "logfmt"}, c.credentials)
+	if enableNetworkMonitoring {
+		config.Add(beylaEBPF(clusterLabels.ClusterID))
+	}
+	for _, tenant := range tenants {
+		config.Add(alloy.Commented("load rules for tenant "+tenant.Name, c.lokiRules(tenant)))
+	}
+
+	write := c.lokiWrite()
+	config.Add(podLogsPipeline(tenants, enableNodeFiltering, write)...)
+	config.Add(journalPipeline(write)...)
+	config.Add(auditPipeline(write)...)
+	config.Add(alloy.Commented("Loki target configuration", write))
+
+	alloyConfig, err := config.Build()
 	if err != nil {
-		return "", err
+		return "", nil, errors.Wrap(err, "generated logging alloy config is invalid")
 	}
+	return alloyConfig, config.Components(), nil
+}
 
-	return values.String(), nil
+// beylaEBPF returns the Beyla component monitoring the network flows between the pods.
+func beylaEBPF(clusterID string) alloy.Block {
+	return alloy.Block{
+		Name:  "beyla.ebpf",
+		Label: "default",
+		Body: alloy.Body{
+			alloy.Block{Name: "attributes", Body: alloy.Body{
+				alloy.Block{Name: "kubernetes", Body: alloy.Body{
+					alloy.Attribute{Name: "cluster_name", Value: alloy.String(clusterID)},
+					alloy.Attribute{Name: "enable", Value: alloy.String("true")},
+				}},
+				alloy.Block{Name: "select", Body: alloy.Body{
+					alloy.Attribute{Name: "attr", Value: alloy.String("beyla_network_flow_bytes")},
+					alloy.Attribute{Name: "include", Value: alloy.Strings(
+						"k8s.src.namespace",
+						"k8s.src.name",
+						"k8s.src.type",
+						"k8s.dst.namespace",
+						"k8s.dst.name",
+						"k8s.dst.type",
+						"src.cidr",
+						"src.address",
+						"src.name",
+						"dst.cidr",
+						"dst.address",
+						"src.zone",
+						"dst.zone",
+						"dst.name",
+						"transport",
+						"direction",
+					)},
+				}},
+			}},
+			alloy.Block{Name: "filters", Body: alloy.Body{
+				alloy.Block{Name: "network", Body: alloy.Body{
+					alloy.Attribute{Name: "attr", Value: alloy.String("direction")},
+					alloy.Attribute{Name: "match", Value: alloy.String("request")},
+				}},
+			}},
+			alloy.Block{Name: "metrics", Body: alloy.Body{
+				alloy.Attribute{Name: "features", Value: alloy.Strings("network", "network_inter_zone")},
+			}},
+		},
+	}
 }
 
-func generateAlloyConfig(tenants []string, clusterLabels common.ClusterLabels, insecureCA bool, enableNodeFiltering bool, enableNetworkMonitoring bool) (string, error) {
-	var values bytes.Buffer
+// lokiRules returns the component loading the Loki rules of the tenant into the ruler.
+func (c loggingConfig) lokiRules(tenant common.Tenant) alloy.LokiRulesKubernetes {
+	rules := alloy.LokiRulesKubernetes{
+		Label:               tenant.ID,
+		Address:             alloy.String(c.managementClusterLokiURLs.Ruler),
+		LokiNamespacePrefix: c.clusterLabels.ClusterID,
+		TenantID:            tenant.Name,
+		TLSConfig:           c.tlsConfig(false),
+		RuleSelector: alloy.LabelSelector{
+			MatchLabels: map[string]string{"observability.giantswarm.io/tenant": tenant.Name},
+			MatchExpressions: []alloy.LabelSelectorRequirement{
+				{Key: "application.giantswarm.io/prometheus-rule-kind", Operator: "In", Values: []string{"loki"}},
+			},
+		},
+	}
 
-	// Ensure default tenant is included in the list of tenants
-	if !slices.Contains(tenants, common.DefaultWriteTenant) {
-		tenants = append(tenants, common.DefaultWriteTenant)
-	}
-
-	data := struct {
-		ClusterID                string
-		ClusterType              string
-		Organization             string
-		Installation             string
-		Provider                 string
-		MaxBackoffPeriod         string
-		RemoteTimeout            string
-		IsWorkloadCluster        bool
-		NodeFilteringEnabled     bool
-		NetworkMonitoringEnabled bool
-		InsecureSkipVerify       bool
-		SecretName               string
-		LoggingURLKey            string
-		LoggingTenantIDKey       string
-		LoggingUsernameKey       string
-		LoggingPasswordKey       string
-		LokiRulerAPIURLKey       string
-		Tenants                  []string
-	}{
-		ClusterID:                clusterLabels.ClusterID,
-		ClusterType:              clusterLabels.ClusterType,
-		Organization:             clusterLabels.Organization,
-		Installation:             clusterLabels.Installation,
-		Provider:                 clusterLabels.Provider,
-		MaxBackoffPeriod:         common.LokiMaxBackoffPeriod.String(),
-		RemoteTimeout:            common.LokiRemoteTimeout.String(),
-		IsWorkloadCluster:        common.IsWorkloadCluster(clusterLabels.Installation, clusterLabels.ClusterID),
-		NodeFilteringEnabled:     enableNodeFiltering,
-		NetworkMonitoringEnabled: enableNetworkMonitoring,
-		InsecureSkipVerify:       insecureCA,
-		SecretName:               common.AlloyLogAgentAppName,
-		LoggingURLKey:            common.LoggingURL,
-		LoggingTenantIDKey:       common.LoggingTenantID,
-		LoggingUsernameKey:       common.LoggingUsername,
-		LoggingPasswordKey:       common.LoggingPassword,
-		LokiRulerAPIURLKey:       common.LokiRulerAPIURL,
-		Tenants:                  tenants,
-	}
-
-	if err := alloyLoggingTemplate.Execute(&values, data); err != nil {
-		return "", err
+	if c.authenticated {
+		rules.Address = alloy.Nonsensitive(c.credentials.Data(common.LokiRulerAPIURL))
+		rules.ProxyURL = c.proxy.URL
+		rules.NoProxy = c.proxy.NoProxy
+		rules.BasicAuth = c.basicAuth()
 	}
 
-	return values.String(), nil
+	return rules
+}
+
+// lokiWrite returns the component sending the logs to Loki.
+func (c loggingConfig) lokiWrite() alloy.LokiWrite {
+	endpoint := alloy.LokiEndpoint{
+		URL:              alloy.String(c.managementClusterLokiURLs.Push),
+		MaxBackoffPeriod: common.LokiMaxBackoffPeriod.String(),
+		RemoteTimeout:    common.LokiRemoteTimeout.String(),
+		TenantID:         alloy.Nonsensitive(c.credentials.Data(common.LoggingTenantID)),
+		ProxyURL:         c.proxy.URL,
+		NoProxy:          c.proxy.NoProxy,
+		TLSConfig:        c.tlsConfig(c.insecureCA),
+	}
+
+	if c.authenticated {
+		endpoint.URL = alloy.Nonsensitive(c.credentials.Data(common.LoggingURL))
+		endpoint.BasicAuth = c.basicAuth()
+	}
+
+	return alloy.LokiWrite{
+		Label:     "default",
+		Endpoints: []alloy.LokiEndpoint{endpoint},
+		ExternalLabels: alloy.Object{
+			{Key: "cluster_id", Value: alloy.String(c.clusterLabels.ClusterID)},
+			{Key: "cluster_type", Value: alloy.String(c.clusterLabels.ClusterType)},
+			{Key: "organization", Value: alloy.String(c.clusterLabels.Organization)},
+			{Key: "provider", Value: alloy.String(c.clusterLabels.Provider)},
+		},
+	}
+}
+
+// basicAuth returns the credentials of the secret, nothing when a client certificate authenticates the cluster.
+func (c loggingConfig) basicAuth() *alloy.BasicAuth {
+	if c.tlsKeys.CertPEM != "" {
+		return nil
+	}
+	return &alloy.BasicAuth{
+		Username: alloy.Nonsensitive(c.credentials.Data(common.LoggingUsername)),
+		Password: c.credentials.Data(common.LoggingPassword),
+	}
+}
+
+// tlsConfig returns the CA bundle and the client certificate of the secret, when there are some.
+func (c loggingConfig) tlsConfig(insecureSkipVerify bool) alloy.TLSConfig {
+	tls := alloy.TLSConfig{InsecureSkipVerify: insecureSkipVerify}
+	if c.tlsKeys.CAPEM != "" {
+		tls.CAPEM = alloy.Nonsensitive(c.credentials.Data(c.tlsKeys.CAPEM))
+	}
+	if c.tlsKeys.CertPEM != "" {
+		tls.CertPEM = alloy.Nonsensitive(c.credentials.Data(c.tlsKeys.CertPEM))
+		tls.KeyPEM = c.credentials.Data(c.tlsKeys.KeyPEM)
+	}
+	return tls
+}
+
+// appLabelRegex matches the first non empty value of the source labels.
+const appLabelRegex = "^;*([^;]+)(;.*)?$"
+
+// podLogsPipeline returns the components collecting the logs of the pods, and routing them to their tenant.
+func podLogsPipeline(tenants []common.Tenant, enableNodeFiltering bool, write alloy.LokiWrite) []alloy.Component {
+	process := alloy.LokiProcess{
+		Label:     "kubernetes_pods",
+		ForwardTo: alloy.Refs(write.Receiver()),
+		Stages: []alloy.Stage{
+			alloy.Commented("Parse container runtime interface (CRI) log format", alloy.StageCRI{}),
+			alloy.Commented("Multi-tenant filtering: drop logs without valid tenant authorization", alloy.StageDrop{
+				DropCounterReason: "no_tenant_id",
+				Source:            "__tenant_id__",
+				Expression:        "^$",
+			}),
+			alloy.Commented("Move high-cardinality metadata to structured metadata instead of labels", alloy.StageStructuredMetadata{
+				Values: alloy.Object{
+					{Key: "filename", Value: alloy.String("")},
+					{Key: "stream", Value: alloy.String("")},
+				},
+			}),
+			alloy.Commented("Clean up temporary labels used only for processing", alloy.StageLabelDrop{
+				Values: []string{"filename", "stream"},
+			}),
+		},
+	}
+
+	relabel := alloy.LokiRelabel{
+		Label:     "kubernetes_pods",
+		ForwardTo: alloy.Refs(process.Receiver()),
+		Rules: []alloy.RelabelRule{
+			{TargetLabel: "scrape_job", Replacement: "kubernetes-pods"},
+			{
+				Comment: "Extract namespace, pod, and container from the structured instance label\n" +
+					`Format: "namespace/pod:container" (e.g., "kube-system/mimir-distributor-abc123:mimir")`,
+				SourceLabels: []string{"instance"},
+				Regex:        "([^/]+)/.+",
+				TargetLabel:  "namespace",
+			},
+			{SourceLabels: []string{"instance"}, Regex: "[^/]+/([^:]+):.+", TargetLabel: "pod"},
+			{SourceLabels: []string{"instance"}, Regex: "[^/]+/[^:]+:(.+)", TargetLabel: "container"},
+			{
+				Comment: "Extract tenant ID for authorized tenants only - logs from unauthorized\n" +
+					"tenants will be dropped later in the processing pipeline\n" +
+					"Configured tenants: " + strings.Join(common.TenantNames(tenants), ", "),
+				SourceLabels: []string{"giantswarm_observability_tenant"},
+				Regex:        common.TenantsRegex(tenants),
+				TargetLabel:  "__tenant_id__",
+			},
+			{
+				Comment: "Remove the source tenant label to keep Loki labels clean",
+				Regex:   "giantswarm_observability_tenant",
+				Action:  "labeldrop",
+			},
+			{
+				Comment: "Extract and normalize standard k8s labels with priority-based fallbacks\n" +
+					"Priority: app.kubernetes.io/name > app > pod name (pod logs then file-based discovery)",
+				SourceLabels: []string{"app_kubernetes_io_name", "app", "pod", "__meta_kubernetes_pod_name"},
+				Regex:        appLabelRegex,
+				TargetLabel:  "app",
+			},
+			{SourceLabels: []string{"app_kubernetes_io_component", "component"}, Regex: appLabelRegex, TargetLabel: "component"},
+			{SourceLabels: []string{"app_kubernetes_io_version", "version"}, Regex: appLabelRegex, TargetLabel: "version"},
+			{
+				Comment: "Create unified service name by combining app + component to align Loki and Tempo signals\n" +
+					"Only creates service label when BOTH app and component are non-empty\n" +
+					`Handles app names with hyphens like "alertmanager-to-github" or "background-controller"` + "\n" +
+					`Examples: "mimir" + "distributor" → "mimir-distributor" (matches Tempo service.name)` + "\n" +
+					`          "alertmanager-to-github" + "webhook" → "alertmanager-to-github-webhook"`,
+				SourceLabels: []string{"app", "component"},
+				Regex:        "^(.+);(.+)$",
+				Replacement:  "${1}-${2}",
+				TargetLabel:  "service",
+			},
+			{Regex: "app_kubernetes_io_(component|name|version)", Action: "labeldrop"},
+		},
+	}
+
+	source := alloy.LokiSourcePodLogs{
+		Label:     "kubernetes_pods",
+		ForwardTo: alloy.Refs(relabel.Receiver()),
+	}
+	if enableNodeFiltering {
+		source.NodeName = alloy.Env("NODE_NAME")
+	}
+
+	return []alloy.Component{
+		alloy.Commented("Native podlogs collection (preferred method for scalability)", source),
+		relabel,
+		process,
+	}
+}
+
+// journalPipeline returns the components collecting the journald logs from /run/log/journal.
+func journalPipeline(write alloy.LokiWrite) []alloy.Component {
+	process := alloy.LokiProcess{
+		Label:     "systemd_journal_run",
+		ForwardTo: alloy.Refs(write.Receiver()),
+		Stages: []alloy.Stage{
+			alloy.StageJSON{Expressions: alloy.Object{{Key: "SYSLOG_IDENTIFIER", Value: alloy.String("SYSLOG_IDENTIFIER")}}},
+			alloy.StageDrop{Source: "SYSLOG_IDENTIFIER", Value: "audit"},
+		},
+	}
+
+	relabel := alloy.DiscoveryRelabel{
+		Label: "systemd_journal_run",
+		Rules: []alloy.RelabelRule{
+			{SourceLabels: []string{"__journal__systemd_unit"}, TargetLabel: "__tmp_systemd_unit"},
+			{SourceLabels: []string{"__journal__systemd_unit", "__journal_syslog_identifier"}, Regex: ";(.+)", TargetLabel: "__tmp_systemd_unit"},
+			{SourceLabels: []string{"__tmp_systemd_unit"}, TargetLabel: "systemd_unit"},
+			{SourceLabels: []string{"__journal__hostname"}, TargetLabel: "node"},
+		},
+	}
+
+	source := alloy.LokiSourceJournal{
+		Label:        "systemd_journal_run",
+		FormatAsJSON: true,
+		MaxAge:       "12h0m0s",
+		Path:         "/run/log/journal",
+		RelabelRules: relabel.RelabelRules(),
+		ForwardTo:    alloy.Refs(process.Receiver()),
+		Labels:       alloy.Object{{Key: "scrape_job", Value: alloy.String("system-logs")}},
+	}
+
+	return []alloy.Component{
+		alloy.Commented("journald logs from /run/log/journal", process),
+		relabel,
+		source,
+	}
+}
+
+// auditPipeline returns the components collecting the Kubernetes API server audit logs.
+func auditPipeline(write alloy.LokiWrite) []alloy.Component {
+	files := alloy.LocalFileMatch{
+		Label: "kubernetes_audit",
+		PathTargets: []alloy.Object{{
+			{Key: "__address__", Value: alloy.String("localhost")},
+			{Key: "__path__", Value: alloy.String("/var/log/apiserver/audit.log")},
+			{Key: "node", Value: alloy.Call{Func: "coalesce", Args: []alloy.Expr{alloy.Env("NODE_NAME"), alloy.String("unknown")}}},
+			{Key: "scrape_job", Value: alloy.String("audit-logs")},
+		}},
+	}
+
+	process := alloy.LokiProcess{
+		Label:     "kubernetes_audit",
+		ForwardTo: alloy.Refs(write.Receiver()),
+		Stages: []alloy.Stage{
+			alloy.StageJSON{Expressions: alloy.Object{{Key: "objectRef", Value: alloy.String("objectRef")}}},
+			alloy.StageJSON{
+				Expressions: alloy.Object{
+					{Key: "namespace", Value: alloy.String("namespace")},
+					{Key: "resource", Value: alloy.String("resource")},
+				},
+				Source: "objectRef",
+			},
+			alloy.StageStructuredMetadata{Values: alloy.Object{
+				{Key: "resource", Value: alloy.String("")},
+				{Key: "filename", Value: alloy.String("")},
+			}},
+			alloy.StageLabelDrop{Values: []string{"filename"}},
+			alloy.StageLabels{Values: alloy.Object{{Key: "namespace", Value: alloy.String("")}}},
+		},
+	}
+
+	source := alloy.LokiSourceFile{
+		Label:               "kubernetes_audit",
+		Targets:             files.Targets(),
+		ForwardTo:           alloy.Refs(process.Receiver()),
+		LegacyPositionsFile: "/run/alloy/positions.yaml",
+	}
+
+	return []alloy.Component{
+		alloy.Commented("Kubernetes API server audit logs", files),
+		process,
+		source,
+	}
 }
@@ -6,13 +6,17 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"text/template"
 
+	"github.com/Masterminds/sprig/v3"
 	"github.com/blang/semver"
 	"github.com/google/go-cmp/cmp"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	capi "sigs.k8s.io/cluster-api/api/core/v1beta1" //nolint:staticcheck // SA1019 deprecated package
+	capiv1beta2 "sigs.k8s.io/cluster-api/api/core/v1beta2"
 
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
 	"github.com/giantswarm/logging-operator/pkg/common"
+	"github.com/giantswarm/logging-operator/pkg/config"
 )
 
 var (
@@ -29,6 +33,10 @@ func TestGenerateAlloyLoggingConfig(t *testing.T) {
 		tenants                    []string
 		enableNodeFiltering        bool
 		enableNetworkMonitoring    bool
+		tlsKeys                    common.TLSKeys
+		proxy                      common.Proxy
+		// Loki URLs of the management cluster, the in-cluster services when empty
+		managementClusterLokiURLs common.LokiURLs
 	}{
 		{
 			goldenFile:                 "alloy/test/logging-config.alloy.170_MC.yaml",
@@ -39,6 +47,59 @@ func TestGenerateAlloyLoggingConfig(t *testing.T) {
 			enableNodeFiltering:        false,
 			enableNetworkMonitoring:    false,
 		},
+		{
+			goldenFile:                 "alloy/test/logging-config.alloy.170_WC_ca_bundle.yaml",
+			observabilityBundleVersion: "1.7.0",
+			defaultNamespaces:          []string{"test-selector"},
+			installationName:           "test-installation",
+			clusterName:                "test-cluster",
+			tlsKeys:                    common.TLSKeys{CAPEM: common.TLSCAPEM},
+		},
+		{
+			goldenFile:                 "alloy/test/logging-config.alloy.170_WC_mtls.yaml",
+			observabilityBundleVersion: "1.7.0",
+			defaultNamespaces:          []string{"test-selector"},
+			installationName:           "test-installation",
+			clusterName:                "test-cluster",
+			tlsKeys:                    common.TLSKeys{CAPEM: common.TLSCAPEM, CertPEM: common.TLSCertPEM, KeyPEM: common.TLSKeyPEM},
+		},
+		{
+			goldenFile:                 "alloy/test/logging-config.alloy.170_WC_proxy.yaml",
+			observabilityBundleVersion: "1.7.0",
+			defaultNamespaces:          []string{"test-selector"},
+			installationName:           "test-installation",
+			clusterName:                "test-cluster",
+			proxy: common.Proxy{
+				URL:     "http://proxy.example.com:3128",
+				Host:    "proxy.example.com",
+				Port:    "3128",
+				NoProxy: "localhost,127.0.0.1,.svc,.cluster.local,kubernetes.default.svc,100.64.0.0/12,172.31.0.0/16",
+			},
+		},
+		{
+			goldenFile:                 "alloy/test/logging-config.alloy.170_WC_proxy_ip.yaml",
+			observabilityBundleVersion: "1.7.0",
+			defaultNamespaces:          []string{"test-selector"},
+			installationName:           "test-installation",
+			clusterName:                "test-cluster",
+			proxy: common.Proxy{
+				URL:     "http://10.0.0.10:3128",
+				Host:    "10.0.0.10",
+				Port:    "3128",
+				NoProxy: "localhost,127.0.0.1,.svc,.cluster.local,kubernetes.default.svc",
+			},
+		},
+		{
+			goldenFile:                 "alloy/test/logging-config.alloy.170_MC_custom_endpoints.yaml",
+			observabilityBundleVersion: "1.7.0",
+			defaultNamespaces:          []string{"test-selector"},
+			installationName:           "test-installation",
+			clusterName:                "test-installation",
+			managementClusterLokiURLs: common.LokiURLs{
+				Push:  "https://logs.example.com:8443/custom/api/v1/push",
+				Ruler: "https://logs.example.com:8443/custom",
+			},
+		},
 		{
 			goldenFile:                 "alloy/test/logging-config.alloy.170_WC.yaml",
 			observabilityBundleVersion: "1.7.0",
@@ -76,6 +137,16 @@ func TestGenerateAlloyLoggingConfig(t *testing.T) {
 			enableNodeFiltering:        false,
 			enableNetworkMonitoring:    false,
 		},
+		{
+			goldenFile:                 "alloy/test/logging-config.alloy.170_WC_special_tenants.yaml",
+			observabilityBundleVersion: "1.7.0",
+			defaultNamespaces:          []string{""},
+			installationName:           "test-installation",
+			clusterName:                "test-cluster",
+			tenants:                    []string{"team.a", "team-a", "3rd(party)*", `bad"tenant`},
+			enableNodeFiltering:        false,
+			enableNetworkMonitoring:    false,
+		},
 		// Tests with node filtering enabled
 		{
 			goldenFile:                 "alloy/test/logging-config.alloy.170_MC_node_filtering.yaml",
@@ -145,11 +216,11 @@ func TestGenerateAlloyLoggingConfig(t *testing.T) {
 				t.Fatalf("Failed to read golden file: %v", err)
 			}
 
-			cluster := &capi.Cluster{
+			cluster := capicluster.New(&capiv1beta2.Cluster{
 				ObjectMeta: metav1.ObjectMeta{
 					Name: tc.clusterName,
 				},
-			}
+			})
 
 			clusterLabels := common.ClusterLabels{
 				ClusterID: tc.clusterName,
@@ -164,7 +235,13 @@ func TestGenerateAlloyLoggingConfig(t *testing.T) {
 				Provider:     "capa",
 			}
 
-			config, err := GenerateAlloyLoggingConfig(cluster, observabilityBundleVersion, tc.defaultNamespaces, tc.tenants, clusterLabels, false, tc.enableNodeFiltering, tc.enableNetworkMonitoring)
+			managementClusterLokiURLs := tc.managementClusterLokiURLs
+			if managementClusterLokiURLs == (common.LokiURLs{}) {
+				managementClusterLokiURLs = common.ManagementClusterLokiURLs(config.Config{})
+			}
+
+			tenants, _ := common.SanitizeTenants(tc.tenants)
+			config, err := GenerateAlloyLoggingConfig(cluster, observabilityBundleVersion, tc.defaultNamespaces, tenants, clusterLabels, managementClusterLokiURLs, false, tc.tlsKeys, tc.proxy, tc.enableNodeFiltering, tc.enableNetworkMonitoring, nil)
 			if err != nil {
 				t.Fatalf("Failed to generate alloy config: %v", err)
 			}
@@ -182,3 +259,74 @@ func TestGenerateAlloyLoggingConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateAlloyLoggingConfigInvalid(t *testing.T) {
+	clusterLabels := common.ClusterLabels{
+		ClusterID:    "test-cluster",
+		ClusterType:  "workload_cluster",
+		Installation: "test-installation",
+		Organization: "test-organization",
+		Provider:     "capa",
+	}
+
+	// A tenant identifier which is not a valid Alloy identifier can not be used as component label.
+	_, _, err := generateAlloyConfig([]common.Tenant{{Name: "bad-tenant", ID: "bad-tenant"}}, clusterLabels, common.LokiURLs{}, false, common.TLSKeys{}, common.Proxy{}, false, false)
+	if err == nil {
+		t.Fatal("expected invalid alloy config to be rejected")
+	}
+}
+
+func TestValidateConfigTemplate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		template    string
+		expectError bool
+	}{
+		{
+			name:     "patched config",
+			template: `{{ .Config | replace "level  = \"warn\"" "level  = \"info\"" }}`,
+		},
+		{
+			name:     "config from scratch",
+			template: "logging {\n\tlevel = \"info\"\n}\n\n// {{ .ClusterID }} {{ .Tenants | join \",\" }}\n",
+		},
+		{
+			name: "replaced component",
+			template: `{{ range .Components }}{{ if eq .ID "loki.write.default" }}loki.write "default" {
+	endpoint {
+		url       = convert.nonsensitive(remote.kubernetes.secret.credentials.data["logging-url"])
+		proxy_url = "{{ $.Settings.Proxy.URL }}"
+	}
+}
+{{ else }}{{ .Text }}{{ end }}
+{{ end }}`,
+		},
+		{
+			name:        "invalid config",
+			template:    `{{ .Config | replace "loki.write.default.receiver" "loki.write.missing.receiver" }}`,
+			expectError: true,
+		},
+		{
+			name:        "execution error",
+			template:    `{{ .Config | fail }}`,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			configTemplate := template.Must(template.New(tc.name).Funcs(sprig.FuncMap()).Parse(tc.template))
+
+			err := validateConfigTemplate(configTemplate, config.Config{InstallationName: "test-installation"})
+			if tc.expectError {
+				if !common.IsTemplateError(err) {
+					t.Errorf("expected template error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
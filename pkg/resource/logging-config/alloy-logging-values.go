@@ -0,0 +1,374 @@
+package loggingconfig
+
+import (
+	"strings"
+
+	"github.com/giantswarm/logging-operator/pkg/alloyvalues"
+	"github.com/giantswarm/logging-operator/pkg/common"
+)
+
+// loggingValuesHeader documents the generated values of the logs agent.
+const loggingValuesHeader = `# This file was generated by logging-operator.
+# It configures Alloy to be used as a logging agent.
+# - configMap is the Alloy configuration built by logging-operator, passed as a string
+#   here and will be created by Alloy's chart.
+# - Alloy runs as a daemonset, with required tolerations in order to scrape logs
+#   from every machine in the cluster.
+# - Running as root user is required in order to be able to read log files within
+#   /run/log/journal directories.
+# - NODE_NAME env var is used as additional label for kubernetes_audit logs.
+`
+
+// alloyLogsLabels are the labels of the logs agent pods.
+var alloyLogsLabels = map[string]string{
+	"app.kubernetes.io/instance": "alloy-logs",
+	"app.kubernetes.io/name":     "alloy",
+}
+
+// loggingValuesOptions are the settings the values of the logs agent depend on.
+type loggingValuesOptions struct {
+	IsWorkloadCluster                bool
+	NodeFilteringEnabled             bool
+	NetworkMonitoringEnabled         bool
+	DefaultWorkloadClusterNamespaces []string
+	// AlloyImageTag overrides the Alloy image tag when set.
+	AlloyImageTag string
+	Proxy         common.Proxy
+}
+
+// loggingValues returns the values of the Alloy chart deploying the logs agent with the given configuration.
+func loggingValues(alloyConfig string, opts loggingValuesOptions) alloyvalues.Values {
+	values := alloyvalues.Values{
+		NetworkPolicy: loggingNetworkPolicy(opts),
+		Alloy: alloyvalues.Chart{
+			Alloy: alloyvalues.Alloy{
+				ConfigMap: alloyvalues.NewConfigMap(alloyConfig),
+				Clustering: &alloyvalues.Clustering{
+					Enabled: !opts.NodeFilteringEnabled,
+					Name:    "alloy-logs",
+				},
+				ExtraEnv: loggingExtraEnv(opts.Proxy),
+				Mounts: &alloyvalues.Mounts{
+					Varlog:           true,
+					DockerContainers: true,
+					Extra: []alloyvalues.VolumeMount{
+						{Name: "runlogjournal", MountPath: "/run/log/journal", ReadOnly: true},
+						// This is needed to allow alloy to create files when using readOnlyRootFilesystem
+						{Name: "alloy-tmp", MountPath: "/tmp/alloy"},
+					},
+				},
+				// We decided to configure the alloy-logs resources as such after some investigation done https://github.com/giantswarm/giantswarm/issues/32655
+				Resources: alloyvalues.Resources{
+					Limits:   map[string]string{"cpu": "2000m", "memory": "300Mi"},
+					Requests: map[string]string{"cpu": "25m", "memory": "200Mi"},
+				},
+				SecurityContext: loggingSecurityContext(opts.NetworkMonitoringEnabled),
+			},
+			Controller: loggingController(opts.NetworkMonitoringEnabled),
+		},
+		// We decided to configure the alloy-logs vertical pod autoscaler as such after some investigation done https://github.com/giantswarm/giantswarm/issues/32655
+		VerticalPodAutoscaler: &alloyvalues.VerticalPodAutoscaler{
+			Enabled: true,
+			ResourcePolicy: alloyvalues.ResourcePolicy{
+				ContainerPolicies: []alloyvalues.ContainerPolicy{
+					{
+						ContainerName:       "alloy",
+						ControlledResources: []string{"memory"},
+						ControlledValues:    "RequestsAndLimits",
+						MaxAllowed:          map[string]string{"memory": "1Gi"},
+					},
+				},
+			},
+		},
+		PodLogs: loggingPodLogs(opts.IsWorkloadCluster, opts.DefaultWorkloadClusterNamespaces),
+	}
+
+	// Roll the pods out when the configuration changes, for the rollout to follow which configuration they run.
+	values.Alloy.Controller.PodAnnotations = alloyvalues.ConfigPodAnnotations(alloyConfig)
+
+	if opts.AlloyImageTag != "" {
+		values.Alloy.Image = &alloyvalues.Image{Tag: opts.AlloyImageTag}
+	}
+
+	if opts.NetworkMonitoringEnabled {
+		values.Alloy.ExtraObjects = []map[string]any{beylaServiceMonitor()}
+		values.KyvernoPolicyExceptions = networkMonitoringPolicyExceptions()
+	}
+
+	return values
+}
+
+// loggingNetworkPolicy returns the network policy of the logs agent, allowing access to the Kubernetes API,
+// the DNS, Loki, and the other logs agents for clustering.
+func loggingNetworkPolicy(opts loggingValuesOptions) *alloyvalues.NetworkPolicy {
+	dns := alloyvalues.PortRule{
+		Ports: []alloyvalues.PortProtocol{
+			{Port: "1053", Protocol: "UDP"},
+			{Port: "1053", Protocol: "TCP"},
+			{Port: "53", Protocol: "UDP"},
+			{Port: "53", Protocol: "TCP"},
+		},
+	}
+	// DNS visibility is required to allow the proxy by name
+	if opts.Proxy.Enabled() && opts.Proxy.CIDR() == "" {
+		dns.Rules = &alloyvalues.L7Rules{DNS: []alloyvalues.DNSRule{{MatchPattern: "*"}}}
+	}
+
+	egress := []alloyvalues.CiliumRule{
+		{ToEntities: []string{"kube-apiserver", "world"}},
+		{
+			ToEndpoints: []alloyvalues.LabelSelector{
+				{MatchLabels: map[string]string{"io.kubernetes.pod.namespace": "kube-system", "k8s-app": "coredns"}},
+				{MatchLabels: map[string]string{"io.kubernetes.pod.namespace": "kube-system", "k8s-app": "k8s-dns-node-cache"}},
+			},
+			ToPorts: []alloyvalues.PortRule{dns},
+		},
+	}
+
+	// Allow access to the proxy
+	if opts.Proxy.Enabled() {
+		rule := alloyvalues.CiliumRule{
+			ToPorts: []alloyvalues.PortRule{{Ports: []alloyvalues.PortProtocol{{Port: opts.Proxy.Port, Protocol: "TCP"}}}},
+		}
+		if cidr := opts.Proxy.CIDR(); cidr != "" {
+			rule.ToCIDR = []string{cidr}
+		} else {
+			rule.ToFQDNs = []alloyvalues.FQDNSelector{{MatchName: opts.Proxy.Host}}
+		}
+		egress = append(egress, rule)
+	}
+
+	// Allow direct access to loki-backend, loki-gateway and nginx
+	if !opts.IsWorkloadCluster {
+		egress = append(egress,
+			alloyvalues.CiliumRule{
+				ToEndpoints: []alloyvalues.LabelSelector{{MatchLabels: map[string]string{
+					"app.kubernetes.io/component": "gateway",
+					"app.kubernetes.io/name":      "loki",
+					"io.kubernetes.pod.namespace": "loki",
+				}}},
+				ToPorts: []alloyvalues.PortRule{{Ports: []alloyvalues.PortProtocol{{Port: "80", Protocol: "TCP"}, {Port: "8080", Protocol: "TCP"}}}},
+			},
+			alloyvalues.CiliumRule{
+				ToEndpoints: []alloyvalues.LabelSelector{{MatchLabels: map[string]string{
+					"app.kubernetes.io/component": "backend",
+					"app.kubernetes.io/name":      "loki",
+					"io.kubernetes.pod.namespace": "loki",
+				}}},
+				ToPorts: []alloyvalues.PortRule{{Ports: []alloyvalues.PortProtocol{{Port: "3100", Protocol: "TCP"}}}},
+			},
+			alloyvalues.CiliumRule{
+				ToEndpoints: []alloyvalues.LabelSelector{{MatchLabels: map[string]string{"app.kubernetes.io/name": "ingress-nginx"}}},
+				ToPorts:     []alloyvalues.PortRule{{Ports: []alloyvalues.PortProtocol{{Port: "80", Protocol: "ANY"}, {Port: "443", Protocol: "ANY"}}}},
+			},
+		)
+	}
+
+	// Allow clustering
+	if !opts.NodeFilteringEnabled {
+		egress = append(egress, alloyvalues.CiliumRule{
+			ToEndpoints: []alloyvalues.LabelSelector{{MatchLabels: alloyLogsLabels}},
+			ToPorts:     []alloyvalues.PortRule{{Ports: []alloyvalues.PortProtocol{{Port: "12345", Protocol: "TCP"}}}},
+		})
+	}
+
+	return &alloyvalues.NetworkPolicy{
+		Cilium:           alloyvalues.CiliumNetworkPolicy{Egress: egress},
+		EndpointSelector: &alloyvalues.LabelSelector{MatchLabels: alloyLogsLabels},
+	}
+}
+
+// loggingExtraEnv returns the environment of the logs agent: the node name, used as label of the audit logs,
+// and the proxy settings, honored by the components without proxy settings like the OTLP gRPC exporters.
+func loggingExtraEnv(proxy common.Proxy) []alloyvalues.EnvVar {
+	env := []alloyvalues.EnvVar{
+		{Name: "NODE_NAME", ValueFrom: &alloyvalues.EnvVarSource{FieldRef: &alloyvalues.ObjectFieldSelector{FieldPath: "spec.nodeName"}}},
+	}
+	if proxy.Enabled() {
+		env = append(env, alloyvalues.ProxyEnv(proxy.URL, proxy.NoProxy)...)
+	}
+	return env
+}
+
+// loggingSecurityContext returns the security context of the logs agent, which runs as root to read the journal.
+// Network monitoring requires a privileged container to load its eBPF programs.
+func loggingSecurityContext(networkMonitoringEnabled bool) alloyvalues.SecurityContext {
+	securityContext := alloyvalues.SecurityContext{
+		AllowPrivilegeEscalation: false,
+		Capabilities:             &alloyvalues.Capabilities{Drop: []string{"ALL"}},
+		ReadOnlyRootFilesystem:   true,
+		RunAsUser:                0,
+		RunAsGroup:               0,
+		RunAsNonRoot:             false,
+		SeccompProfile:           &alloyvalues.Profile{Type: "RuntimeDefault"},
+	}
+
+	if networkMonitoringEnabled {
+		securityContext.AllowPrivilegeEscalation = true
+		securityContext.AppArmorProfile = &alloyvalues.Profile{Type: "Unconfined"}
+		securityContext.Capabilities = &alloyvalues.Capabilities{
+			Add: []string{
+				"BPF",
+				"CHECKPOINT_RESTORE",
+				"DAC_READ_SEARCH",
+				"NET_RAW",
+				"NET_ADMIN",
+				"PERFMON",
+				"SYS_PTRACE",
+				"SYS_RESOURCE",
+				"SYS_ADMIN",
+			},
+			Drop: []string{},
+		}
+		securityContext.Privileged = true
+		securityContext.SeccompProfile = &alloyvalues.Profile{Type: "Unconfined"}
+	}
+
+	return securityContext
+}
+
+// loggingController returns the daemonset running the logs agent on every node, control plane nodes included.
+// Network monitoring requires the host network and PID namespaces.
+func loggingController(networkMonitoringEnabled bool) alloyvalues.Controller {
+	return alloyvalues.Controller{
+		Type:              "daemonset",
+		HostPID:           networkMonitoringEnabled,
+		HostNetwork:       networkMonitoringEnabled,
+		PriorityClassName: common.PriorityClassName,
+		Tolerations: []alloyvalues.Toleration{
+			{Effect: "NoSchedule", Key: "node-role.kubernetes.io/master", Operator: "Exists"},
+			{Effect: "NoSchedule", Key: "node-role.kubernetes.io/control-plane", Operator: "Exists"},
+		},
+		Volumes: &alloyvalues.Volumes{
+			Extra: []alloyvalues.Volume{
+				{Name: "runlogjournal", HostPath: &alloyvalues.HostPath{Path: "/run/log/journal"}},
+				{Name: "alloy-tmp", EmptyDir: &alloyvalues.EmptyDir{}},
+			},
+		},
+	}
+}
+
+// loggingPodLogs returns the pods whose logs are collected. On workload clusters, the logs of the default namespaces
+// are sent to the default tenant and the logs of the other namespaces to the tenant of their pod, when it has one.
+// On the management cluster, the logs of all pods are sent to the default tenant.
+func loggingPodLogs(isWorkloadCluster bool, defaultNamespaces []string) []alloyvalues.PodLogs {
+	appLabelsRelabelings := []alloyvalues.RelabelConfig{
+		{Action: "replace", SourceLabels: []string{"__meta_kubernetes_pod_label_app_kubernetes_io_name"}, TargetLabel: "app_kubernetes_io_name"},
+		{Action: "replace", SourceLabels: []string{"__meta_kubernetes_pod_label_app_kubernetes_io_component"}, TargetLabel: "app_kubernetes_io_component"},
+		{Action: "replace", SourceLabels: []string{"__meta_kubernetes_pod_label_app_kubernetes_io_version"}, TargetLabel: "app_kubernetes_io_version"},
+	}
+	defaultTenantRelabelings := append([]alloyvalues.RelabelConfig{
+		{Action: "replace", TargetLabel: "giantswarm_observability_tenant", Replacement: common.DefaultWriteTenant},
+	}, appLabelsRelabelings...)
+
+	if !isWorkloadCluster {
+		return []alloyvalues.PodLogs{
+			{
+				Name:      "all-pods",
+				Namespace: "kube-system",
+				Spec:      alloyvalues.PodLogsSpec{Relabelings: defaultTenantRelabelings},
+			},
+		}
+	}
+
+	if strings.Join(defaultNamespaces, ",") == "" {
+		return nil
+	}
+
+	return []alloyvalues.PodLogs{
+		{
+			Name:      "default-namespaces",
+			Namespace: "kube-system",
+			Spec: alloyvalues.PodLogsSpec{
+				NamespaceSelector: alloyvalues.LabelSelector{MatchExpressions: []alloyvalues.LabelSelectorRequirement{
+					{Key: "kubernetes.io/metadata.name", Operator: "In", Values: defaultNamespaces},
+				}},
+				Relabelings: defaultTenantRelabelings,
+			},
+		},
+		{
+			Name:      "customers-logs",
+			Namespace: "kube-system",
+			Spec: alloyvalues.PodLogsSpec{
+				Selector: alloyvalues.LabelSelector{MatchExpressions: []alloyvalues.LabelSelectorRequirement{
+					{Key: "observability.giantswarm.io/tenant", Operator: "Exists"},
+				}},
+				NamespaceSelector: alloyvalues.LabelSelector{MatchExpressions: []alloyvalues.LabelSelectorRequirement{
+					{Key: "kubernetes.io/metadata.name", Operator: "NotIn", Values: defaultNamespaces},
+				}},
+				Relabelings: append([]alloyvalues.RelabelConfig{
+					{Action: "replace", SourceLabels: []string{"__meta_kubernetes_pod_label_observability_giantswarm_io_tenant"}, TargetLabel: "giantswarm_observability_tenant"},
+				}, appLabelsRelabelings...),
+			},
+		},
+	}
+}
+
+// beylaServiceMonitor returns the ServiceMonitor scraping the metrics of the Beyla network monitoring component.
+func beylaServiceMonitor() map[string]any {
+	return map[string]any{
+		"apiVersion": "monitoring.coreos.com/v1",
+		"kind":       "ServiceMonitor",
+		"metadata": map[string]any{
+			"labels": map[string]any{
+				"observability.giantswarm.io/tenant": common.DefaultWriteTenant,
+			},
+			"name":      "alloy-logs-beyla",
+			"namespace": "kube-system",
+		},
+		"spec": map[string]any{
+			"endpoints": []any{
+				map[string]any{
+					"honorLabels": true,
+					"port":        "http-metrics",
+					"path":        "/api/v0/component/beyla.ebpf.default/metric",
+					"scheme":      "http",
+				},
+			},
+			"selector": map[string]any{
+				"matchLabels": alloyLogsLabels,
+			},
+		},
+	}
+}
+
+// networkMonitoringPolicies are the Kyverno policies the privileged network monitoring pods are excepted from.
+var networkMonitoringPolicies = []string{
+	"restrict-volume-types",
+	"always-allow-heartbeats-and-all-pipelines-alerts",
+	"block-k8s-initiator-app-deployment-capa",
+	"disallow-capabilities",
+	"disallow-capabilities-strict",
+	"disallow-host-namespaces",
+	"disallow-host-path",
+	"disallow-host-ports",
+	"disallow-host-process",
+	"disallow-noisy-policy-contexts",
+	"disallow-privilege-escalation",
+	"disallow-privileged-containers",
+	"disallow-proc-mount",
+	"disallow-selinux",
+	"require-emptydir-requests-and-limits",
+	"require-run-as-non-root-user",
+	"require-run-as-nonroot",
+	"restrict-apparmor-profiles",
+	"restrict-polex-namespaces",
+	"restrict-policy-kind-wildcards",
+	"restrict-seccomp",
+	"restrict-seccomp-strict",
+	"restrict-sysctls",
+	"restrict-volume-types",
+}
+
+// networkMonitoringPolicyExceptions excepts the logs agent from all the rules of the network monitoring policies.
+func networkMonitoringPolicyExceptions() *alloyvalues.KyvernoPolicyExceptions {
+	exceptions := make([]alloyvalues.PolicyException, 0, len(networkMonitoringPolicies))
+	for _, policy := range networkMonitoringPolicies {
+		exceptions = append(exceptions, alloyvalues.PolicyException{PolicyName: policy, RuleNames: []string{"*"}})
+	}
+	return &alloyvalues.KyvernoPolicyExceptions{
+		Enabled:    true,
+		Namespace:  "giantswarm",
+		Exceptions: exceptions,
+	}
+}
@@ -2,31 +2,33 @@ package loggingconfig
 
 import (
 	"fmt"
+	"text/template"
 
 	"github.com/blang/semver"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	capi "sigs.k8s.io/cluster-api/api/core/v1beta1" //nolint:staticcheck // SA1019 deprecated package
 
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
 	"github.com/giantswarm/logging-operator/pkg/common"
+	"github.com/giantswarm/logging-operator/pkg/config"
 )
 
 const (
 	loggingConfigName = "logging-config"
 )
 
-func (r *Resource) GenerateLoggingConfig(cluster *capi.Cluster, observabilityBundleVersion semver.Version, defaultNamespaces, tenants []string, clusterLabels common.ClusterLabels) (v1.ConfigMap, error) {
+func GenerateLoggingConfig(cluster *capicluster.Cluster, cfg config.Config, observabilityBundleVersion semver.Version, tenants []common.Tenant, clusterLabels common.ClusterLabels, proxy common.Proxy, configTemplate *template.Template) (v1.ConfigMap, error) {
 	var values string
 	var err error
 
 	// Check if network monitoring should be enabled
-	networkMonitoringEnabled := common.IsNetworkMonitoringEnabled(cluster, r.Config.EnableNetworkMonitoringFlag)
+	networkMonitoringEnabled := common.IsNetworkMonitoringEnabled(cluster, cfg.EnableNetworkMonitoringFlag)
 	// Beyla network monitoring requires observability bundle >= 2.3.0
 	if networkMonitoringEnabled && observabilityBundleVersion.LT(semver.MustParse("2.3.0")) {
 		networkMonitoringEnabled = false
 	}
 
-	values, err = GenerateAlloyLoggingConfig(cluster, observabilityBundleVersion, defaultNamespaces, tenants, clusterLabels, r.Config.InsecureCA, r.Config.EnableNodeFilteringFlag, networkMonitoringEnabled)
+	values, err = GenerateAlloyLoggingConfig(cluster, observabilityBundleVersion, cfg.DefaultNamespaces, tenants, clusterLabels, common.ManagementClusterLokiURLs(cfg), cfg.InsecureCA, common.SecretTLSKeys(cfg), proxy, cfg.EnableNodeFilteringFlag, networkMonitoringEnabled, configTemplate)
 	if err != nil {
 		return v1.ConfigMap{}, err
 	}
@@ -42,7 +44,7 @@ func (r *Resource) GenerateLoggingConfig(cluster *capi.Cluster, observabilityBun
 }
 
 // ConfigMeta returns metadata for the logging-config
-func ConfigMeta(cluster *capi.Cluster) metav1.ObjectMeta {
+func ConfigMeta(cluster *capicluster.Cluster) metav1.ObjectMeta {
 	metadata := metav1.ObjectMeta{
 		Name:      getLoggingConfigName(cluster),
 		Namespace: cluster.GetNamespace(),
@@ -50,9 +52,10 @@ func ConfigMeta(cluster *capi.Cluster) metav1.ObjectMeta {
 	}
 
 	common.AddCommonLabels(metadata.Labels)
+	common.AddClusterOwner(&metadata, cluster)
 	return metadata
 }
 
-func getLoggingConfigName(cluster *capi.Cluster) string {
+func getLoggingConfigName(cluster *capicluster.Cluster) string {
 	return fmt.Sprintf("%s-%s", cluster.GetName(), loggingConfigName)
 }
@@ -2,106 +2,226 @@ package loggingconfig
 
 import (
 	"context"
-	"reflect"
+	"text/template"
 	"time"
 
 	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
 	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
-	capi "sigs.k8s.io/cluster-api/api/core/v1beta1" //nolint:staticcheck // SA1019 deprecated package
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	ollyop "github.com/giantswarm/observability-operator/pkg/common/tenancy"
 
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
 	"github.com/giantswarm/logging-operator/pkg/common"
 	"github.com/giantswarm/logging-operator/pkg/config"
+	"github.com/giantswarm/logging-operator/pkg/key"
+	"github.com/giantswarm/logging-operator/pkg/metrics"
+	loggingsecret "github.com/giantswarm/logging-operator/pkg/resource/logging-secret"
+	"github.com/giantswarm/logging-operator/pkg/revision"
+	"github.com/giantswarm/logging-operator/pkg/rollout"
 )
 
 // Resource implements a resource.Interface to handle
 // Logging config: extra logging config defining what we want to retrieve.
 type Resource struct {
-	Client                           client.Client
-	Config                           config.Config
-	DefaultWorkloadClusterNamespaces []string
+	Client   client.Client
+	Config   config.Config
+	Recorder record.EventRecorder
+}
+
+// Name returns the name of the resource.
+func (r *Resource) Name() string {
+	return loggingConfigName
+}
+
+// DependsOn returns the resources which must be reconciled first: the secret holding the credentials the config refers to.
+func (r *Resource) DependsOn() []string {
+	return []string{loggingsecret.ResourceName}
 }
 
 // ReconcileCreate ensures logging-config is created with the right credentials
-func (r *Resource) ReconcileCreate(ctx context.Context, cluster *capi.Cluster) (ctrl.Result, error) {
+func (r *Resource) ReconcileCreate(ctx context.Context, cluster *capicluster.Cluster) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 	logger.Info("logging-config create")
 
-	observabilityBundleVersion, err := common.GetObservabilityBundleAppVersion(ctx, r.Client, cluster)
+	desiredLoggingConfig, pinnedRevision, result, err := r.desired(ctx, cluster)
+	if err != nil || !result.IsZero() {
+		return result, err
+	}
+
+	// Check if config already exists.
+	logger.Info("logging-config - getting", "namespace", desiredLoggingConfig.GetNamespace(), "name", desiredLoggingConfig.GetName())
+	var currentLoggingConfig v1.ConfigMap
+	err = r.Client.Get(ctx, types.NamespacedName{Name: desiredLoggingConfig.GetName(), Namespace: desiredLoggingConfig.GetNamespace()}, &currentLoggingConfig)
 	if err != nil {
-		// Handle case where the app is not found.
 		if apimachineryerrors.IsNotFound(err) {
-			logger.Info("logging-config - observability bundle app not found, requeueing")
-			// If the app is not found we should requeue and try again later (5 minutes is the app platform default reconciliation time)
-			return ctrl.Result{RequeueAfter: time.Duration(5 * time.Minute)}, nil
+			logger.Info("logging-config not found, creating")
+			err = r.apply(ctx, cluster, desiredLoggingConfig)
+			if err != nil {
+				return ctrl.Result{}, errors.WithStack(err)
+			}
+			r.Recorder.Eventf(cluster.Object, v1.EventTypeNormal, common.EventReason(r.Name(), common.CreatedAction), "Created configmap %s/%s", desiredLoggingConfig.GetNamespace(), desiredLoggingConfig.GetName())
+			err = r.recordRevision(ctx, cluster, desiredLoggingConfig, pinnedRevision != nil)
+			if err != nil {
+				return ctrl.Result{}, errors.WithStack(err)
+			}
+			return ctrl.Result{}, nil
 		}
 		return ctrl.Result{}, errors.WithStack(err)
 	}
 
-	// Get list of tenants
-	tenants, err := ollyop.ListTenants(ctx, r.Client)
+	// Take over the fields written before the operator used server-side apply
+	err = common.UpgradeManagedFields(ctx, r.Client, &currentLoggingConfig)
 	if err != nil {
 		return ctrl.Result{}, errors.WithStack(err)
 	}
 
-	// Extract cluster labels once at this level where we have k8s client
-	clusterLabels, err := common.ExtractClusterLabels(ctx, r.Client, cluster, r.Config)
+	upToDate, err := common.ConfigMapUpToDate(currentLoggingConfig, common.ConfigMapApplyConfiguration(desiredLoggingConfig))
+	if err != nil {
+		return ctrl.Result{}, errors.WithStack(err)
+	}
+	if upToDate {
+		logger.Info("logging-config up to date")
+		// Recording is idempotent, this records the configmaps of clusters without history and retries failed records.
+		err = r.recordRevision(ctx, cluster, desiredLoggingConfig, pinnedRevision != nil)
+		if err != nil {
+			return ctrl.Result{}, errors.WithStack(err)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// Hold the update back until the rollout reaches the cluster, the cluster is reconciled again meanwhile.
+	// Rollbacks to a pinned revision are not held back.
+	decision := rollout.FromContext(ctx)
+	if !decision.Allowed && pinnedRevision == nil {
+		logger.Info("logging-config - update held back by the rollout", "reason", decision.Reason)
+		decision.Hold()
+		return ctrl.Result{RequeueAfter: rollout.RequeueAfter}, nil
+	}
+
+	diff := common.ConfigMapDiff(currentLoggingConfig, desiredLoggingConfig)
+	logger.Info("logging-config - updating", "diff", diff)
+	err = r.apply(ctx, cluster, desiredLoggingConfig)
 	if err != nil {
 		return ctrl.Result{}, errors.WithStack(err)
 	}
+	r.Recorder.Eventf(cluster.Object, v1.EventTypeNormal, common.EventReason(r.Name(), common.UpdatedAction), "Updated configmap %s/%s:\n%s", desiredLoggingConfig.GetNamespace(), desiredLoggingConfig.GetName(), common.EventDiff(diff))
+	metrics.ObserveConfigDriftCorrection(r.Name())
 
-	// Get desired config
-	desiredLoggingConfig, err := r.GenerateLoggingConfig(cluster, observabilityBundleVersion, r.DefaultWorkloadClusterNamespaces, tenants, clusterLabels)
+	err = r.recordRevision(ctx, cluster, desiredLoggingConfig, pinnedRevision != nil)
 	if err != nil {
-		logger.Info("logging-config - failed generating logging config!", "error", err)
 		return ctrl.Result{}, errors.WithStack(err)
 	}
 
-	// Check if config already exists.
-	logger.Info("logging-config - getting", "namespace", desiredLoggingConfig.GetNamespace(), "name", desiredLoggingConfig.GetName())
-	var currentLoggingConfig v1.ConfigMap
-	err = r.Client.Get(ctx, types.NamespacedName{Name: desiredLoggingConfig.GetName(), Namespace: desiredLoggingConfig.GetNamespace()}, &currentLoggingConfig)
+	logger.Info("logging-config - done")
+	return ctrl.Result{}, nil
+}
+
+// Desired implements resource.Renderer, it returns the logging-config ReconcileCreate writes for the given cluster.
+func (r *Resource) Desired(ctx context.Context, cluster *capicluster.Cluster) ([]client.Object, error) {
+	desiredLoggingConfig, _, result, err := r.desired(ctx, cluster)
 	if err != nil {
+		return nil, err
+	}
+	if !result.IsZero() {
+		return nil, errors.New("the observability bundle app of the cluster is not found")
+	}
+	return []client.Object{&desiredLoggingConfig}, nil
+}
+
+// desired returns the desired logging-config of the cluster, with the data of the revision it is pinned to if any.
+// A non-zero result is returned when the config can not be generated yet.
+func (r *Resource) desired(ctx context.Context, cluster *capicluster.Cluster) (v1.ConfigMap, *v1.ConfigMap, ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	cfg := config.FromContext(ctx, r.Config)
+
+	observabilityBundleVersion, err := common.GetObservabilityBundleAppVersion(ctx, r.Client, cluster)
+	if err != nil {
+		// Handle case where the app is not found.
 		if apimachineryerrors.IsNotFound(err) {
-			logger.Info("logging-config not found, creating")
-			err = r.Client.Create(ctx, &desiredLoggingConfig)
-			if err != nil {
-				return ctrl.Result{}, errors.WithStack(err)
-			}
-			return ctrl.Result{}, nil
+			logger.Info("logging-config - observability bundle app not found, requeueing")
+			// If the app is not found we should requeue and try again later (5 minutes is the app platform default reconciliation time)
+			return v1.ConfigMap{}, nil, ctrl.Result{RequeueAfter: time.Duration(5 * time.Minute)}, nil
 		}
-		return ctrl.Result{}, errors.WithStack(err)
+		return v1.ConfigMap{}, nil, ctrl.Result{}, errors.WithStack(err)
 	}
 
-	if !needUpdate(currentLoggingConfig, desiredLoggingConfig) {
-		logger.Info("logging-config up to date")
-		return ctrl.Result{}, nil
+	// Get list of tenants
+	tenantNames, err := ollyop.ListTenants(ctx, r.Client)
+	if err != nil {
+		return v1.ConfigMap{}, nil, ctrl.Result{}, errors.WithStack(err)
+	}
+	tenants, invalidTenants := common.SanitizeTenants(tenantNames)
+	if len(invalidTenants) > 0 {
+		logger.Info("logging-config - ignoring invalid tenants", "tenants", invalidTenants)
+		r.Recorder.Eventf(cluster.Object, v1.EventTypeWarning, common.InvalidTenantsReason, "Ignoring invalid tenants %q, tenants must be %s", invalidTenants, common.TenantNamePattern)
 	}
 
-	logger.Info("logging-config - updating")
-	err = r.Client.Update(ctx, &desiredLoggingConfig)
+	// Extract cluster labels once at this level where we have k8s client
+	clusterLabels, err := common.ExtractClusterLabels(ctx, r.Client, cluster, cfg)
 	if err != nil {
-		return ctrl.Result{}, errors.WithStack(err)
+		return v1.ConfigMap{}, nil, ctrl.Result{}, errors.WithStack(err)
 	}
 
-	logger.Info("logging-config - done")
-	return ctrl.Result{}, nil
+	proxy, err := common.ReadProxy(cluster, cfg)
+	if err != nil {
+		r.Recorder.Eventf(cluster.Object, v1.EventTypeWarning, common.InvalidProxyReason, "Proxy of the cluster is invalid: %s", err)
+		return v1.ConfigMap{}, nil, ctrl.Result{}, errors.WithStack(err)
+	}
+
+	// Get desired config, falling back to the built-in configuration when the replacement template fails to render
+	configTemplate := r.template(ctx, cluster, cfg)
+	desiredLoggingConfig, err := GenerateLoggingConfig(cluster, cfg, observabilityBundleVersion, tenants, clusterLabels, proxy, configTemplate)
+	if configTemplate != nil && common.IsTemplateError(err) {
+		logger.Info("logging-config - failed rendering the replacement template, using the built-in configuration", "error", err)
+		r.Recorder.Eventf(cluster.Object, v1.EventTypeWarning, common.InvalidTemplateReason, "Replacement template of %s is invalid, using the built-in configuration: %s", r.Name(), err)
+		desiredLoggingConfig, err = GenerateLoggingConfig(cluster, cfg, observabilityBundleVersion, tenants, clusterLabels, proxy, nil)
+	}
+	if err != nil {
+		logger.Info("logging-config - failed generating logging config!", "error", err)
+		return v1.ConfigMap{}, nil, ctrl.Result{}, errors.WithStack(err)
+	}
+
+	// Merge the values overrides of the cluster owner over the generated values.
+	err = common.OverrideValues(ctx, r.Client, cluster, r.Name(), &desiredLoggingConfig)
+	if err != nil {
+		logger.Info("logging-config - failed merging values overrides", "error", err)
+		r.Recorder.Eventf(cluster.Object, v1.EventTypeWarning, common.InvalidValuesOverridesReason, "Failed to merge the values overrides: %s", err)
+		return v1.ConfigMap{}, nil, ctrl.Result{}, errors.WithStack(err)
+	}
+
+	// Roll the config back to the revision the cluster is pinned to, until the pin is removed.
+	pinnedRevision, err := r.pinnedRevision(ctx, cluster)
+	if err != nil {
+		return v1.ConfigMap{}, nil, ctrl.Result{}, errors.WithStack(err)
+	}
+	if pinnedRevision != nil {
+		logger.Info("logging-config - pinned to a revision", "revision", pinnedRevision.GetLabels()[key.RevisionLabel])
+		desiredLoggingConfig.Data = pinnedRevision.Data
+	}
+
+	return desiredLoggingConfig, pinnedRevision, ctrl.Result{}, nil
 }
 
 // ReconcileDelete ensure logging-config is deleted for the given cluster.
-func (r *Resource) ReconcileDelete(ctx context.Context, cluster *capi.Cluster) (ctrl.Result, error) {
+func (r *Resource) ReconcileDelete(ctx context.Context, cluster *capicluster.Cluster) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 	logger.Info("logging-config delete")
 
+	// Delete the revision history of the config.
+	err := revision.Delete(ctx, r.Client, cluster, r.Name())
+	if err != nil {
+		return ctrl.Result{}, errors.WithStack(err)
+	}
+
 	// Get expected configmap.
 	var currentLoggingConfig v1.ConfigMap
-	err := r.Client.Get(ctx, types.NamespacedName{Name: getLoggingConfigName(cluster), Namespace: cluster.GetNamespace()}, &currentLoggingConfig)
+	err = r.Client.Get(ctx, types.NamespacedName{Name: getLoggingConfigName(cluster), Namespace: cluster.GetNamespace()}, &currentLoggingConfig)
 	if err != nil {
 		if apimachineryerrors.IsNotFound(err) {
 			logger.Info("logging-config not found, nothing to delete")
@@ -122,12 +242,65 @@ func (r *Resource) ReconcileDelete(ctx context.Context, cluster *capi.Cluster) (
 		}
 		return ctrl.Result{}, errors.WithStack(err)
 	}
+	r.Recorder.Eventf(cluster.Object, v1.EventTypeNormal, common.EventReason(r.Name(), common.DeletedAction), "Deleted configmap %s/%s", currentLoggingConfig.GetNamespace(), currentLoggingConfig.GetName())
 	logger.Info("logging-config deleted")
 
 	return ctrl.Result{}, nil
 }
 
-// needUpdate return true if current.Data and desired.Data do not match.
-func needUpdate(current, desired v1.ConfigMap) bool {
-	return !reflect.DeepEqual(current.Data, desired.Data)
+// template returns the replacement template of the Alloy configuration, nil when there is none or when it does not
+// render a valid configuration for a synthetic cluster, the built-in configuration being used then.
+func (r *Resource) template(ctx context.Context, cluster *capicluster.Cluster, cfg config.Config) *template.Template {
+	logger := log.FromContext(ctx)
+
+	configTemplate, err := common.ReadTemplate(ctx, r.Client, cfg, r.Name())
+	if err == nil && configTemplate != nil {
+		err = validateConfigTemplate(configTemplate, cfg)
+	}
+	if err != nil {
+		logger.Info("logging-config - invalid replacement template, using the built-in configuration", "error", err)
+		r.Recorder.Eventf(cluster.Object, v1.EventTypeWarning, common.InvalidTemplateReason, "Replacement template of %s is invalid, using the built-in configuration: %s", r.Name(), err)
+		return nil
+	}
+	return configTemplate
+}
+
+// pinnedRevision returns the revision the config is pinned to by the revision annotation of the cluster, nil when
+// it is not pinned. An invalid annotation or a missing revision is reported with a warning event.
+func (r *Resource) pinnedRevision(ctx context.Context, cluster *capicluster.Cluster) (*v1.ConfigMap, error) {
+	number, pinned, err := revision.Pinned(cluster, r.Name())
+	if err != nil {
+		r.Recorder.Eventf(cluster.Object, v1.EventTypeWarning, common.InvalidRevisionPinReason, "Failed to pin %s to a revision: %s", r.Name(), err)
+		return nil, err
+	}
+	if !pinned {
+		return nil, nil
+	}
+
+	pinnedRevision, err := revision.Get(ctx, r.Client, cluster, r.Name(), number)
+	if err != nil {
+		r.Recorder.Eventf(cluster.Object, v1.EventTypeWarning, common.InvalidRevisionPinReason, "Failed to pin %s to revision %d: %s", r.Name(), number, err)
+		return nil, err
+	}
+	return pinnedRevision, nil
+}
+
+// recordRevision keeps the applied config in the revision history, unless it is pinned to a revision.
+func (r *Resource) recordRevision(ctx context.Context, cluster *capicluster.Cluster, desired v1.ConfigMap, pinned bool) error {
+	if pinned {
+		return nil
+	}
+	cfg := config.FromContext(ctx, r.Config)
+	_, err := revision.Record(ctx, r.Client, cluster, r.Name(), desired, cfg.RevisionHistoryLimit)
+	return err
+}
+
+// apply applies the desired configmap with server-side apply.
+// Conflicts with fields owned by other managers are reported with a warning event.
+func (r *Resource) apply(ctx context.Context, cluster *capicluster.Cluster, desired v1.ConfigMap) error {
+	err := common.Apply(ctx, r.Client, common.ConfigMapApplyConfiguration(desired))
+	if apimachineryerrors.IsConflict(err) {
+		r.Recorder.Eventf(cluster.Object, v1.EventTypeWarning, common.ApplyConflictReason, "Failed to apply configmap %s/%s, fields are owned by another manager: %s", desired.GetNamespace(), desired.GetName(), err)
+	}
+	return err
 }
@@ -1,17 +1,16 @@
 package loggingsecret
 
 import (
-	"bytes"
 	"context"
 	_ "embed"
-	"fmt"
 	"text/template"
 
-	capi "sigs.k8s.io/cluster-api/api/core/v1beta1" //nolint:staticcheck // SA1019 deprecated package
-
 	"github.com/Masterminds/sprig/v3"
 	"github.com/giantswarm/observability-operator/pkg/auth"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
 
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
 	"github.com/giantswarm/logging-operator/pkg/common"
 )
 
@@ -25,44 +24,114 @@ func init() {
 	alloySecretTemplate = template.Must(template.New("logging-secret.yaml").Funcs(sprig.FuncMap()).Parse(alloySecret))
 }
 
-func GenerateAlloyLoggingSecret(ctx context.Context, cluster *capi.Cluster, logsAuthManager, tracesAuthManager auth.AuthManager, lokiURL string, tracingEnabled bool) (map[string][]byte, error) {
-	clusterName := cluster.GetName()
-	var values bytes.Buffer
+// secretTemplateData is the data the secret templates are rendered with.
+type secretTemplateData struct {
+	ExtraSecretEnv map[string]string
+}
 
-	writePassword, err := logsAuthManager.GetClusterPassword(ctx, cluster)
-	if err != nil {
-		return nil, err
+// syntheticSecretTemplateData is the data the replacement secret templates are validated with, holding all the keys
+// a secret can hold.
+var syntheticSecretTemplateData = secretTemplateData{
+	ExtraSecretEnv: map[string]string{
+		common.LoggingURL:      "https://loki.synthetic/loki/api/v1/push",
+		common.LoggingTenantID: common.DefaultWriteTenant,
+		common.LokiRulerAPIURL: "https://loki.synthetic",
+		common.LoggingUsername: "synthetic",
+		common.LoggingPassword: "synthetic",
+		common.TracingUsername: "synthetic",
+		common.TracingPassword: "synthetic",
+		common.TLSCAPEM:        "synthetic",
+		common.TLSCertPEM:      "synthetic",
+		common.TLSKeyPEM:       "synthetic",
+	},
+}
+
+// ValidateSecretTemplate checks that the replacement secret template renders valid values for a synthetic cluster.
+func ValidateSecretTemplate(secretTemplate *template.Template) error {
+	_, err := common.RenderTemplate(secretTemplate, syntheticSecretTemplateData, validateValues)
+	return err
+}
+
+// validateValues checks that the rendered values are a YAML map. The YAML error is not returned as it may quote
+// the credentials of the cluster.
+func validateValues(values string) error {
+	var out map[string]any
+	if err := yaml.Unmarshal([]byte(values), &out); err != nil {
+		return errors.New("values are not a valid YAML map")
 	}
+	return nil
+}
 
-	templateData := struct {
-		ExtraSecretEnv map[string]string
-	}{
+// GenerateAlloyLoggingSecret returns the Alloy values holding where to send logs and traces and the credentials to use.
+// When a client certificate is given, the cluster authenticates with it instead of the basic auth credentials.
+// The values are rendered with the replacement secret template when one is given, with the built-in one otherwise.
+func GenerateAlloyLoggingSecret(ctx context.Context, cluster *capicluster.Cluster, logsAuthManager, tracesAuthManager auth.AuthManager, lokiURLs common.LokiURLs, caBundle string, clientCertificate *common.ClientCertificate, tracingEnabled bool, secretTemplate *template.Template) (map[string][]byte, error) {
+	templateData := secretTemplateData{
 		ExtraSecretEnv: map[string]string{
-			common.LoggingURL:      fmt.Sprintf(common.LokiPushURLFormat, lokiURL),
+			common.LoggingURL:      lokiURLs.Push,
 			common.LoggingTenantID: common.DefaultWriteTenant,
-			common.LoggingUsername: clusterName,
-			common.LoggingPassword: writePassword,
-			common.LokiRulerAPIURL: fmt.Sprintf(common.LokiBaseURLFormat, lokiURL),
+			common.LokiRulerAPIURL: lokiURLs.Ruler,
 		},
 	}
 
-	if tracingEnabled {
-		tracingPassword, err := tracesAuthManager.GetClusterPassword(ctx, cluster)
+	// The CA bundle is referenced with ca_pem by the Alloy configs to verify the Loki and Tempo certificates
+	if caBundle != "" {
+		templateData.ExtraSecretEnv[common.TLSCAPEM] = caBundle
+	}
+
+	if clientCertificate != nil {
+		// The client certificate is referenced with cert_pem and key_pem by the Alloy configs, for both logs and traces
+		templateData.ExtraSecretEnv[common.TLSCertPEM] = clientCertificate.CertPEM
+		templateData.ExtraSecretEnv[common.TLSKeyPEM] = clientCertificate.KeyPEM
+	} else {
+		err := addBasicAuthCredentials(ctx, templateData.ExtraSecretEnv, cluster, logsAuthManager, tracesAuthManager, tracingEnabled)
 		if err != nil {
 			return nil, err
 		}
-
-		templateData.ExtraSecretEnv[common.TracingUsername] = clusterName
-		templateData.ExtraSecretEnv[common.TracingPassword] = tracingPassword
 	}
 
-	err = alloySecretTemplate.Execute(&values, templateData)
+	if secretTemplate == nil {
+		secretTemplate = alloySecretTemplate
+	}
+	values, err := common.RenderTemplate(secretTemplate, templateData, validateValues)
 	if err != nil {
 		return nil, err
 	}
 
 	data := make(map[string][]byte)
-	data["values"] = values.Bytes()
+	data["values"] = []byte(values)
 
 	return data, nil
 }
+
+// addBasicAuthCredentials adds the logs credentials of the cluster, and its traces credentials when tracing is enabled,
+// to the given secret env.
+func addBasicAuthCredentials(ctx context.Context, extraSecretEnv map[string]string, cluster *capicluster.Cluster, logsAuthManager, tracesAuthManager auth.AuthManager, tracingEnabled bool) error {
+	clusterName := cluster.GetName()
+
+	// The auth managers still expect v1beta1 clusters.
+	v1beta1Cluster, err := cluster.V1Beta1()
+	if err != nil {
+		return err
+	}
+
+	writePassword, err := logsAuthManager.GetClusterPassword(ctx, v1beta1Cluster)
+	if err != nil {
+		return err
+	}
+
+	extraSecretEnv[common.LoggingUsername] = clusterName
+	extraSecretEnv[common.LoggingPassword] = writePassword
+
+	if tracingEnabled {
+		tracingPassword, err := tracesAuthManager.GetClusterPassword(ctx, v1beta1Cluster)
+		if err != nil {
+			return err
+		}
+
+		extraSecretEnv[common.TracingUsername] = clusterName
+		extraSecretEnv[common.TracingPassword] = tracingPassword
+	}
+
+	return nil
+}
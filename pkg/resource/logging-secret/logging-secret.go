@@ -3,25 +3,29 @@ package loggingsecret
 import (
 	"context"
 	"fmt"
+	"text/template"
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	capi "sigs.k8s.io/cluster-api/api/core/v1beta1" //nolint:staticcheck // SA1019 deprecated package
 
 	"github.com/giantswarm/observability-operator/pkg/auth"
 
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
 	"github.com/giantswarm/logging-operator/pkg/common"
 )
 
 const (
 	loggingClientSecretName = "logging-secret"
+
+	// ResourceName is the name of the logging-secret resource.
+	ResourceName = loggingClientSecretName
 )
 
-func GenerateLoggingSecret(ctx context.Context, cluster *capi.Cluster, logsAuthManager auth.AuthManager, tracesAuthManager auth.AuthManager, lokiURL string, tracingEnabled bool) (v1.Secret, error) {
+func GenerateLoggingSecret(ctx context.Context, cluster *capicluster.Cluster, logsAuthManager auth.AuthManager, tracesAuthManager auth.AuthManager, lokiURLs common.LokiURLs, caBundle string, clientCertificate *common.ClientCertificate, tracingEnabled bool, secretTemplate *template.Template) (v1.Secret, error) {
 	var data map[string][]byte
 	var err error
 
-	data, err = GenerateAlloyLoggingSecret(ctx, cluster, logsAuthManager, tracesAuthManager, lokiURL, tracingEnabled)
+	data, err = GenerateAlloyLoggingSecret(ctx, cluster, logsAuthManager, tracesAuthManager, lokiURLs, caBundle, clientCertificate, tracingEnabled, secretTemplate)
 	if err != nil {
 		return v1.Secret{}, err
 	}
@@ -35,7 +39,7 @@ func GenerateLoggingSecret(ctx context.Context, cluster *capi.Cluster, logsAuthM
 }
 
 // SecretMeta returns metadata for the logging-secret
-func SecretMeta(cluster *capi.Cluster) metav1.ObjectMeta {
+func SecretMeta(cluster *capicluster.Cluster) metav1.ObjectMeta {
 	metadata := metav1.ObjectMeta{
 		Name:      getLoggingSecretName(cluster),
 		Namespace: cluster.GetNamespace(),
@@ -43,9 +47,10 @@ func SecretMeta(cluster *capi.Cluster) metav1.ObjectMeta {
 	}
 
 	common.AddCommonLabels(metadata.Labels)
+	common.AddClusterOwner(&metadata, cluster)
 	return metadata
 }
 
-func getLoggingSecretName(cluster *capi.Cluster) string {
+func getLoggingSecretName(cluster *capicluster.Cluster) string {
 	return fmt.Sprintf("%s-%s", cluster.GetName(), loggingClientSecretName)
 }
@@ -2,7 +2,7 @@ package loggingsecret
 
 import (
 	"context"
-	"reflect"
+	"text/template"
 	"time"
 
 	"github.com/giantswarm/observability-operator/pkg/auth"
@@ -10,13 +10,17 @@ import (
 	v1 "k8s.io/api/core/v1"
 	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
-	capi "sigs.k8s.io/cluster-api/api/core/v1beta1" //nolint:staticcheck // SA1019 deprecated package
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
 	"github.com/giantswarm/logging-operator/pkg/common"
 	"github.com/giantswarm/logging-operator/pkg/config"
+	"github.com/giantswarm/logging-operator/pkg/metrics"
+	clientcertificate "github.com/giantswarm/logging-operator/pkg/resource/client-certificate"
+	"github.com/giantswarm/logging-operator/pkg/rollout"
 )
 
 // Resource implements a resource.Interface to handle
@@ -24,31 +28,30 @@ import (
 type Resource struct {
 	Client            client.Client
 	Config            config.Config
+	Recorder          record.EventRecorder
 	LogsAuthManager   auth.AuthManager
 	TracesAuthManager auth.AuthManager
 }
 
+// Name returns the name of the resource.
+func (r *Resource) Name() string {
+	return loggingClientSecretName
+}
+
+// DependsOn returns the resources which must be reconciled first: the client certificate the secret holds
+// when the cluster authenticates with mutual TLS.
+func (r *Resource) DependsOn() []string {
+	return []string{clientcertificate.ResourceName}
+}
+
 // ReconcileCreate ensures logging-secret is created with the right credentials
-func (r *Resource) ReconcileCreate(ctx context.Context, cluster *capi.Cluster) (ctrl.Result, error) {
+func (r *Resource) ReconcileCreate(ctx context.Context, cluster *capicluster.Cluster) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 	logger.Info("logging-secret create")
 
-	// Retrieve Loki ingress name
-	lokiURL, err := common.ReadLokiIngressURL(ctx, cluster, r.Client)
-	if err != nil {
-		return ctrl.Result{}, errors.WithStack(err)
-	}
-
-	// Get desired secret
-	desiredLoggingSecret, err := GenerateLoggingSecret(ctx, cluster, r.LogsAuthManager, r.TracesAuthManager, lokiURL, r.Config.EnableTracingFlag)
-	if err != nil {
-		// If the auth secret doesn't exist yet (race condition), requeue
-		if apimachineryerrors.IsNotFound(err) {
-			logger.Info("logging-secret - auth secret not found yet, requeueing", "error", err)
-			return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
-		}
-		logger.Info("logging-secret - failed generating auth config!", "error", err)
-		return ctrl.Result{}, errors.WithStack(err)
+	desiredLoggingSecret, result, err := r.desired(ctx, cluster)
+	if err != nil || !result.IsZero() {
+		return result, err
 	}
 
 	// Check if secret already exists.
@@ -58,32 +61,120 @@ func (r *Resource) ReconcileCreate(ctx context.Context, cluster *capi.Cluster) (
 	if err != nil {
 		if apimachineryerrors.IsNotFound(err) {
 			logger.Info("logging-secret not found, creating")
-			err = r.Client.Create(ctx, &desiredLoggingSecret)
+			err = r.apply(ctx, cluster, desiredLoggingSecret)
 			if err != nil {
 				return ctrl.Result{}, errors.WithStack(err)
 			}
-		} else {
-			return ctrl.Result{}, errors.WithStack(err)
+			r.Recorder.Eventf(cluster.Object, v1.EventTypeNormal, common.EventReason(r.Name(), common.CreatedAction), "Created secret %s/%s", desiredLoggingSecret.GetNamespace(), desiredLoggingSecret.GetName())
+			return ctrl.Result{}, nil
 		}
+		return ctrl.Result{}, errors.WithStack(err)
+	}
+
+	// Take over the fields written before the operator used server-side apply
+	err = common.UpgradeManagedFields(ctx, r.Client, &currentLoggingSecret)
+	if err != nil {
+		return ctrl.Result{}, errors.WithStack(err)
 	}
 
-	if !needUpdate(currentLoggingSecret, desiredLoggingSecret) {
+	upToDate, err := common.SecretUpToDate(currentLoggingSecret, common.SecretApplyConfiguration(desiredLoggingSecret))
+	if err != nil {
+		return ctrl.Result{}, errors.WithStack(err)
+	}
+	if upToDate {
 		logger.Info("logging-secret up to date")
 		return ctrl.Result{}, nil
 	}
 
-	logger.Info("logging-secret - updating")
-	err = r.Client.Update(ctx, &desiredLoggingSecret)
+	// Hold the update back until the rollout reaches the cluster, together with the configmap using the secret.
+	// The held cluster is reconciled again meanwhile, the configmap is still reconciled to apply its revision pins.
+	decision := rollout.FromContext(ctx)
+	if !decision.Allowed {
+		logger.Info("logging-secret - update held back by the rollout", "reason", decision.Reason)
+		decision.Hold()
+		return ctrl.Result{}, nil
+	}
+
+	diff := common.SecretDiff(currentLoggingSecret, desiredLoggingSecret)
+	logger.Info("logging-secret - updating", "diff", diff)
+	err = r.apply(ctx, cluster, desiredLoggingSecret)
 	if err != nil {
 		return ctrl.Result{}, errors.WithStack(err)
 	}
+	r.Recorder.Eventf(cluster.Object, v1.EventTypeNormal, common.EventReason(r.Name(), common.UpdatedAction), "Updated secret %s/%s:\n%s", desiredLoggingSecret.GetNamespace(), desiredLoggingSecret.GetName(), common.EventDiff(diff))
+	metrics.ObserveConfigDriftCorrection(r.Name())
 
 	logger.Info("logging-secret - done")
 	return ctrl.Result{}, nil
 }
 
+// Desired implements resource.Renderer, it returns the logging-secret ReconcileCreate writes for the given cluster.
+func (r *Resource) Desired(ctx context.Context, cluster *capicluster.Cluster) ([]client.Object, error) {
+	desiredLoggingSecret, result, err := r.desired(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	if !result.IsZero() {
+		return nil, errors.New("the credentials of the cluster are not available yet")
+	}
+	return []client.Object{&desiredLoggingSecret}, nil
+}
+
+// desired returns the desired logging-secret of the cluster.
+// A non-zero result is returned when the secret can not be generated yet.
+func (r *Resource) desired(ctx context.Context, cluster *capicluster.Cluster) (v1.Secret, ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	cfg := config.FromContext(ctx, r.Config)
+
+	// Retrieve Loki URLs
+	lokiURLs, err := common.ReadLokiURLs(ctx, r.Client, cfg)
+	if err != nil {
+		r.Recorder.Eventf(cluster.Object, v1.EventTypeWarning, common.LokiEndpointMissingReason, "Failed to read the Loki endpoint: %s", err)
+		return v1.Secret{}, ctrl.Result{}, errors.WithStack(err)
+	}
+
+	// Retrieve the CA bundle verifying the Loki and Tempo certificates, if any
+	caBundle, err := common.ReadCABundle(ctx, r.Client, cfg)
+	if err != nil {
+		r.Recorder.Eventf(cluster.Object, v1.EventTypeWarning, common.CABundleMissingReason, "Failed to read the CA bundle: %s", err)
+		return v1.Secret{}, ctrl.Result{}, errors.WithStack(err)
+	}
+
+	// Retrieve the client certificate of the cluster when it authenticates with mutual TLS
+	var clientCertificate *common.ClientCertificate
+	if common.IsMTLSAuth(cfg) {
+		clientCertificate, err = common.ReadClientCertificate(ctx, r.Client, cluster)
+		if err != nil {
+			r.Recorder.Eventf(cluster.Object, v1.EventTypeWarning, common.ClientCertificateNotReadyReason, "Client certificate of the cluster is not usable: %s", err)
+			return v1.Secret{}, ctrl.Result{}, errors.WithStack(err)
+		}
+	}
+
+	// Get desired secret, falling back to the built-in template when the replacement one fails to render
+	secretTemplate := r.template(ctx, cluster, cfg)
+	tracingEnabled := common.IsTracingEnabled(cluster, cfg.EnableTracingFlag)
+	desiredLoggingSecret, err := GenerateLoggingSecret(ctx, cluster, r.LogsAuthManager, r.TracesAuthManager, lokiURLs, caBundle, clientCertificate, tracingEnabled, secretTemplate)
+	if secretTemplate != nil && common.IsTemplateError(err) {
+		logger.Info("logging-secret - failed rendering the replacement template, using the built-in one", "error", err)
+		r.Recorder.Eventf(cluster.Object, v1.EventTypeWarning, common.InvalidTemplateReason, "Replacement template of %s is invalid, using the built-in one: %s", r.Name(), err)
+		desiredLoggingSecret, err = GenerateLoggingSecret(ctx, cluster, r.LogsAuthManager, r.TracesAuthManager, lokiURLs, caBundle, clientCertificate, tracingEnabled, nil)
+	}
+	if err != nil {
+		// If the auth secret doesn't exist yet (race condition), requeue
+		if apimachineryerrors.IsNotFound(err) {
+			logger.Info("logging-secret - auth secret not found yet, requeueing", "error", err)
+			r.Recorder.Eventf(cluster.Object, v1.EventTypeWarning, common.AuthSecretMissingReason, "Credentials of the cluster are not available yet: %s", err)
+			return v1.Secret{}, ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+		}
+		logger.Info("logging-secret - failed generating auth config!", "error", err)
+		return v1.Secret{}, ctrl.Result{}, errors.WithStack(err)
+	}
+
+	return desiredLoggingSecret, ctrl.Result{}, nil
+}
+
 // ReconcileDelete - Not much to do here when a cluster is deleted
-func (r *Resource) ReconcileDelete(ctx context.Context, cluster *capi.Cluster) (ctrl.Result, error) {
+func (r *Resource) ReconcileDelete(ctx context.Context, cluster *capicluster.Cluster) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 	logger.Info("logging-secret delete")
 
@@ -110,12 +201,35 @@ func (r *Resource) ReconcileDelete(ctx context.Context, cluster *capi.Cluster) (
 		}
 		return ctrl.Result{}, errors.WithStack(err)
 	}
+	r.Recorder.Eventf(cluster.Object, v1.EventTypeNormal, common.EventReason(r.Name(), common.DeletedAction), "Deleted secret %s/%s", currentLoggingSecret.GetNamespace(), currentLoggingSecret.GetName())
 	logger.Info("logging-secret deleted")
 
 	return ctrl.Result{}, nil
 }
 
-// needUpdate return true if current.Data and desired.Data do not match.
-func needUpdate(current, desired v1.Secret) bool {
-	return !reflect.DeepEqual(current.Data, desired.Data)
+// template returns the replacement template of the secret, nil when there is none or when it does not render valid
+// values for a synthetic cluster, the built-in template being used then.
+func (r *Resource) template(ctx context.Context, cluster *capicluster.Cluster, cfg config.Config) *template.Template {
+	logger := log.FromContext(ctx)
+
+	secretTemplate, err := common.ReadTemplate(ctx, r.Client, cfg, r.Name())
+	if err == nil && secretTemplate != nil {
+		err = ValidateSecretTemplate(secretTemplate)
+	}
+	if err != nil {
+		logger.Info("logging-secret - invalid replacement template, using the built-in one", "error", err)
+		r.Recorder.Eventf(cluster.Object, v1.EventTypeWarning, common.InvalidTemplateReason, "Replacement template of %s is invalid, using the built-in one: %s", r.Name(), err)
+		return nil
+	}
+	return secretTemplate
+}
+
+// apply applies the desired secret with server-side apply.
+// Conflicts with fields owned by other managers are reported with a warning event.
+func (r *Resource) apply(ctx context.Context, cluster *capicluster.Cluster, desired v1.Secret) error {
+	err := common.Apply(ctx, r.Client, common.SecretApplyConfiguration(desired))
+	if apimachineryerrors.IsConflict(err) {
+		r.Recorder.Eventf(cluster.Object, v1.EventTypeWarning, common.ApplyConflictReason, "Failed to apply secret %s/%s, fields are owned by another manager: %s", desired.GetNamespace(), desired.GetName(), err)
+	}
+	return err
 }
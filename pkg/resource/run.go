@@ -0,0 +1,117 @@
+package resource
+
+import (
+	"slices"
+
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// Result is the outcome of the reconciliation of a resource.
+type Result struct {
+	// Name is the name of the resource.
+	Name string
+	// Result and Err are returned by the reconciliation of the resource.
+	Result ctrl.Result
+	Err    error
+	// BlockedBy is the name of the dependency which was not reconciled successfully, when the resource was skipped.
+	BlockedBy string
+}
+
+// Completed returns true if the resource was reconciled successfully and does not need to be requeued.
+func (r Result) Completed() bool {
+	return r.BlockedBy == "" && r.Err == nil && r.Result.IsZero()
+}
+
+// Run calls reconcile on each of the given resources once all the resources it depends on completed,
+// so that a failing resource only blocks the resources depending on it. Resources depending on a resource
+// which did not complete are skipped. Dependencies on resources which are not in the list are ignored.
+// The results are returned in the order of the given resources.
+func Run(resources []Interface, reconcile func(Interface) (ctrl.Result, error)) []Result {
+	names := make([]string, 0, len(resources))
+	for _, resource := range resources {
+		names = append(names, resource.Name())
+	}
+
+	results := make([]*Result, len(resources))
+	done := func(name string) *Result {
+		i := slices.Index(names, name)
+		if i < 0 {
+			// Unknown dependencies are considered completed.
+			return &Result{Name: name}
+		}
+		return results[i]
+	}
+
+	for progress := true; progress; {
+		progress = false
+		for i, resource := range resources {
+			if results[i] != nil {
+				continue
+			}
+
+			ready := true
+			var blockedBy string
+			for _, dependency := range resource.DependsOn() {
+				result := done(dependency)
+				if result == nil {
+					ready = false
+					break
+				}
+				if !result.Completed() && blockedBy == "" {
+					blockedBy = dependency
+				}
+			}
+			if !ready {
+				continue
+			}
+
+			results[i] = &Result{Name: resource.Name(), BlockedBy: blockedBy}
+			if blockedBy == "" {
+				results[i].Result, results[i].Err = reconcile(resource)
+			}
+			progress = true
+		}
+	}
+
+	runResults := make([]Result, 0, len(resources))
+	for i, result := range results {
+		if result == nil {
+			// Resources left are part of a dependency cycle.
+			result = &Result{Name: names[i], BlockedBy: firstDependency(resources[i])}
+		}
+		runResults = append(runResults, *result)
+	}
+	return runResults
+}
+
+func firstDependency(resource Interface) string {
+	dependencies := resource.DependsOn()
+	if len(dependencies) == 0 {
+		return ""
+	}
+	return dependencies[0]
+}
+
+// Aggregate returns the result to requeue the reconciliation after the shortest requeue asked for by the given results,
+// or immediately if one of them asks for it, and an aggregate of their errors.
+func Aggregate(results []Result) (ctrl.Result, error) {
+	var result ctrl.Result
+	var requeue bool
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, errors.Wrap(r.Err, r.Name))
+		}
+		requeue = requeue || r.Result.Requeue //nolint:staticcheck // SA1019 still honoured by controller-runtime
+		if r.Result.RequeueAfter > 0 && (result.RequeueAfter == 0 || r.Result.RequeueAfter < result.RequeueAfter) {
+			result.RequeueAfter = r.Result.RequeueAfter
+		}
+	}
+	if requeue {
+		// The requeue is only immediate without delay.
+		result = ctrl.Result{Requeue: true} //nolint:staticcheck // SA1019 still honoured by controller-runtime
+	}
+	return result, kerrors.NewAggregate(errs)
+}
@@ -0,0 +1,149 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
+)
+
+type testResource struct {
+	name      string
+	dependsOn []string
+	result    ctrl.Result
+	err       error
+}
+
+func (r testResource) Name() string        { return r.name }
+func (r testResource) DependsOn() []string { return r.dependsOn }
+func (r testResource) ReconcileCreate(context.Context, *capicluster.Cluster) (ctrl.Result, error) {
+	return r.result, r.err
+}
+func (r testResource) ReconcileDelete(context.Context, *capicluster.Cluster) (ctrl.Result, error) {
+	return r.result, r.err
+}
+
+func TestRun(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	testCases := []struct {
+		name              string
+		resources         []Interface
+		expectedCalls     []string
+		expectedBlockedBy map[string]string
+	}{
+		{
+			name: "all completed",
+			resources: []Interface{
+				testResource{name: "logging-secret"},
+				testResource{name: "logging-config", dependsOn: []string{"logging-secret"}},
+			},
+			expectedCalls: []string{"logging-secret", "logging-config"},
+		},
+		{
+			name: "failing dependency only blocks its dependents",
+			resources: []Interface{
+				testResource{name: "logging-secret", err: errFailed},
+				testResource{name: "logging-config", dependsOn: []string{"logging-secret"}},
+				testResource{name: "events-logger-secret"},
+				testResource{name: "events-logger-config", dependsOn: []string{"events-logger-secret"}},
+			},
+			expectedCalls:     []string{"logging-secret", "events-logger-secret", "events-logger-config"},
+			expectedBlockedBy: map[string]string{"logging-config": "logging-secret"},
+		},
+		{
+			name: "requeued dependency blocks its dependents",
+			resources: []Interface{
+				testResource{name: "logging-secret", result: ctrl.Result{RequeueAfter: time.Minute}},
+				testResource{name: "logging-config", dependsOn: []string{"logging-secret"}},
+			},
+			expectedCalls:     []string{"logging-secret"},
+			expectedBlockedBy: map[string]string{"logging-config": "logging-secret"},
+		},
+		{
+			name: "dependencies declared later run first",
+			resources: []Interface{
+				testResource{name: "logging-config", dependsOn: []string{"logging-secret"}},
+				testResource{name: "logging-secret"},
+			},
+			expectedCalls: []string{"logging-secret", "logging-config"},
+		},
+		{
+			name: "unknown dependencies are ignored",
+			resources: []Interface{
+				testResource{name: "logging-config", dependsOn: []string{"logging-secret"}},
+			},
+			expectedCalls: []string{"logging-config"},
+		},
+		{
+			name: "dependency cycle",
+			resources: []Interface{
+				testResource{name: "a", dependsOn: []string{"b"}},
+				testResource{name: "b", dependsOn: []string{"a"}},
+			},
+			expectedBlockedBy: map[string]string{"a": "b", "b": "a"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []string
+			results := Run(tc.resources, func(r Interface) (ctrl.Result, error) {
+				calls = append(calls, r.Name())
+				return r.ReconcileCreate(context.Background(), nil)
+			})
+
+			if !reflect.DeepEqual(calls, tc.expectedCalls) {
+				t.Errorf("expected calls %v, got %v", tc.expectedCalls, calls)
+			}
+			if len(results) != len(tc.resources) {
+				t.Fatalf("expected %d results, got %d", len(tc.resources), len(results))
+			}
+			for i, result := range results {
+				if result.Name != tc.resources[i].Name() {
+					t.Errorf("expected result %d to be %s, got %s", i, tc.resources[i].Name(), result.Name)
+				}
+				if result.BlockedBy != tc.expectedBlockedBy[result.Name] {
+					t.Errorf("expected %s to be blocked by %q, got %q", result.Name, tc.expectedBlockedBy[result.Name], result.BlockedBy)
+				}
+			}
+		})
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	result, err := Aggregate([]Result{
+		{Name: "logging-secret", Err: errors.New("auth secret missing")},
+		{Name: "logging-config", Result: ctrl.Result{RequeueAfter: 5 * time.Minute}},
+		{Name: "events-logger-config", Result: ctrl.Result{RequeueAfter: time.Minute}},
+		{Name: "events-logger-secret"},
+	})
+
+	if result.RequeueAfter != time.Minute {
+		t.Errorf("expected the shortest requeue, got %s", result.RequeueAfter)
+	}
+	if err == nil || err.Error() != "logging-secret: auth secret missing" {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	result, err = Aggregate([]Result{
+		{Name: "logging-secret", Result: ctrl.Result{Requeue: true}},
+		{Name: "logging-config", Result: ctrl.Result{RequeueAfter: time.Minute}},
+	})
+	if !result.Requeue || result.RequeueAfter != 0 { //nolint:staticcheck // SA1019 still honoured by controller-runtime
+		t.Errorf("expected an immediate requeue, got %v", result)
+	}
+	if err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	result, err = Aggregate([]Result{{Name: "logging-secret"}})
+	if !result.IsZero() || err != nil {
+		t.Errorf("expected no requeue and no error, got %v and %v", result, err)
+	}
+}
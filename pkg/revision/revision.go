@@ -0,0 +1,160 @@
+package revision
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
+	"github.com/giantswarm/logging-operator/pkg/common"
+	"github.com/giantswarm/logging-operator/pkg/config"
+	"github.com/giantswarm/logging-operator/pkg/key"
+)
+
+// DefaultHistoryLimit is the number of revisions kept for each generated configmap.
+const DefaultHistoryLimit = 10
+
+// ValidateConfig checks the revision history limit is not negative.
+func ValidateConfig(cfg config.Config) error {
+	if cfg.RevisionHistoryLimit < 0 {
+		return errors.Errorf("revision history limit must not be negative, got %d", cfg.RevisionHistoryLimit)
+	}
+	return nil
+}
+
+// Name returns the name of the configmap holding the given revision of the configmap generated by the resource,
+// e.g. <cluster>-logging-config-rev-3.
+func Name(cluster *capicluster.Cluster, resourceName string, revision int) string {
+	return fmt.Sprintf("%s-%s-rev-%d", cluster.GetName(), resourceName, revision)
+}
+
+// selector selects the revisions of the configmap generated by the resource for the cluster.
+func selector(cluster *capicluster.Cluster, resourceName string) client.MatchingLabels {
+	return client.MatchingLabels{key.ClusterLabel: cluster.GetName(), key.RevisionOfLabel: resourceName}
+}
+
+// number returns the revision number of the given revision configmap, or false if it has none.
+func number(configmap v1.ConfigMap) (int, bool) {
+	revision, err := strconv.Atoi(configmap.GetLabels()[key.RevisionLabel])
+	return revision, err == nil
+}
+
+// List returns the revisions of the configmap generated by the resource for the cluster, oldest first.
+func List(ctx context.Context, c client.Client, cluster *capicluster.Cluster, resourceName string) ([]v1.ConfigMap, error) {
+	var list v1.ConfigMapList
+	err := c.List(ctx, &list, client.InNamespace(cluster.GetNamespace()), selector(cluster, resourceName))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	revisions := slices.DeleteFunc(list.Items, func(configmap v1.ConfigMap) bool {
+		_, ok := number(configmap)
+		return !ok
+	})
+	slices.SortFunc(revisions, func(a, b v1.ConfigMap) int {
+		numberA, _ := number(a)
+		numberB, _ := number(b)
+		return numberA - numberB
+	})
+	return revisions, nil
+}
+
+// Record keeps the data of the configmap generated by the resource as a new revision, unless it matches the latest
+// revision, and deletes the oldest revisions beyond the limit. Nothing is recorded when the limit is 0. Recording
+// the same data again is a no-op, so it is safe on every reconciliation.
+// The number of the revision matching the configmap is returned.
+func Record(ctx context.Context, c client.Client, cluster *capicluster.Cluster, resourceName string, configmap v1.ConfigMap, limit int) (int, error) {
+	if limit <= 0 {
+		return 0, nil
+	}
+
+	revisions, err := List(ctx, c, cluster, resourceName)
+	if err != nil {
+		return 0, err
+	}
+
+	latest := 0
+	if len(revisions) > 0 {
+		latest, _ = number(revisions[len(revisions)-1])
+	}
+	if len(revisions) == 0 || !maps.Equal(revisions[len(revisions)-1].Data, configmap.Data) {
+		latest++
+		revision := v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      Name(cluster, resourceName, latest),
+				Namespace: cluster.GetNamespace(),
+				Labels: map[string]string{
+					key.RevisionOfLabel: resourceName,
+					key.RevisionLabel:   strconv.Itoa(latest),
+				},
+			},
+			Immutable: ptr.To(true),
+			Data:      maps.Clone(configmap.Data),
+		}
+		common.AddCommonLabels(revision.Labels)
+		common.AddClusterOwner(&revision.ObjectMeta, cluster)
+
+		err = c.Create(ctx, &revision)
+		if apimachineryerrors.IsAlreadyExists(err) {
+			// The revision was recorded by a previous reconciliation the cache does not reflect yet.
+			return latest, nil
+		}
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		revisions = append(revisions, revision)
+	}
+
+	for _, revision := range revisions[:max(len(revisions)-limit, 0)] {
+		err = c.Delete(ctx, &revision)
+		if client.IgnoreNotFound(err) != nil {
+			return 0, errors.WithStack(err)
+		}
+	}
+	return latest, nil
+}
+
+// Get returns the given revision of the configmap generated by the resource for the cluster.
+func Get(ctx context.Context, c client.Client, cluster *capicluster.Cluster, resourceName string, revision int) (*v1.ConfigMap, error) {
+	var configmap v1.ConfigMap
+	err := c.Get(ctx, types.NamespacedName{Name: Name(cluster, resourceName, revision), Namespace: cluster.GetNamespace()}, &configmap)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &configmap, nil
+}
+
+// Delete deletes all the revisions of the configmap generated by the resource for the cluster.
+func Delete(ctx context.Context, c client.Client, cluster *capicluster.Cluster, resourceName string) error {
+	err := c.DeleteAllOf(ctx, &v1.ConfigMap{}, client.InNamespace(cluster.GetNamespace()), selector(cluster, resourceName))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// Pinned returns the revision the configmap generated by the resource is pinned to by the revision annotation of
+// the cluster, or false when it is not pinned.
+func Pinned(cluster *capicluster.Cluster, resourceName string) (int, bool, error) {
+	annotation := key.RevisionAnnotation(resourceName)
+	value, ok := cluster.GetAnnotations()[annotation]
+	if !ok {
+		return 0, false, nil
+	}
+
+	revision, err := strconv.Atoi(value)
+	if err != nil || revision < 1 {
+		return 0, false, errors.Errorf("invalid %s annotation %q, must be a revision number", annotation, value)
+	}
+	return revision, true, nil
+}
@@ -0,0 +1,142 @@
+package revision
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	capiv1beta2 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
+	"github.com/giantswarm/logging-operator/pkg/key"
+)
+
+func TestRecord(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+
+	ctx := context.Background()
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	cluster := capicluster.New(&capiv1beta2.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", Namespace: "org-test"}})
+	configmap := func(values string) v1.ConfigMap {
+		return v1.ConfigMap{Data: map[string]string{"values": values}}
+	}
+
+	steps := []struct {
+		values   string
+		limit    int
+		expected int
+		kept     []string
+	}{
+		{values: "a", limit: 3, expected: 1, kept: []string{"test-cluster-logging-config-rev-1"}},
+		{values: "a", limit: 3, expected: 1, kept: []string{"test-cluster-logging-config-rev-1"}},
+		{values: "b", limit: 3, expected: 2, kept: []string{"test-cluster-logging-config-rev-1", "test-cluster-logging-config-rev-2"}},
+		{values: "a", limit: 3, expected: 3, kept: []string{"test-cluster-logging-config-rev-1", "test-cluster-logging-config-rev-2", "test-cluster-logging-config-rev-3"}},
+		{values: "c", limit: 2, expected: 4, kept: []string{"test-cluster-logging-config-rev-3", "test-cluster-logging-config-rev-4"}},
+		{values: "d", limit: 0, expected: 0, kept: []string{"test-cluster-logging-config-rev-3", "test-cluster-logging-config-rev-4"}},
+	}
+
+	for i, step := range steps {
+		revision, err := Record(ctx, c, cluster, "logging-config", configmap(step.values), step.limit)
+		if err != nil {
+			t.Fatalf("step %d: unexpected error: %v", i, err)
+		}
+		if revision != step.expected {
+			t.Errorf("step %d: expected revision %d, got %d", i, step.expected, revision)
+		}
+
+		revisions, err := List(ctx, c, cluster, "logging-config")
+		if err != nil {
+			t.Fatalf("step %d: unexpected error: %v", i, err)
+		}
+		var kept []string
+		for _, revision := range revisions {
+			kept = append(kept, revision.GetName())
+		}
+		if !reflect.DeepEqual(kept, step.kept) {
+			t.Errorf("step %d: expected revisions %v, got %v", i, step.kept, kept)
+		}
+	}
+
+	revision, err := Get(ctx, c, cluster, "logging-config", 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if revision.Data["values"] != "a" || revision.Immutable == nil || !*revision.Immutable {
+		t.Errorf("unexpected revision %+v", revision)
+	}
+	if revision.GetLabels()[key.ClusterLabel] != "test-cluster" || len(revision.GetOwnerReferences()) != 1 {
+		t.Errorf("expected revision to be owned by the cluster, got %+v", revision.ObjectMeta)
+	}
+
+	err = Delete(ctx, c, cluster, "logging-config")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	revisions, err := List(ctx, c, cluster, "logging-config")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(revisions) != 0 {
+		t.Errorf("expected no revision left, got %d", len(revisions))
+	}
+}
+
+func TestPinned(t *testing.T) {
+	testCases := []struct {
+		name           string
+		annotations    map[string]string
+		expected       int
+		expectedPinned bool
+		expectError    bool
+	}{
+		{
+			name: "not pinned",
+		},
+		{
+			name:           "pinned",
+			annotations:    map[string]string{"giantswarm.io/logging-config-revision": "3"},
+			expected:       3,
+			expectedPinned: true,
+		},
+		{
+			name:        "other resource",
+			annotations: map[string]string{"giantswarm.io/events-logger-config-revision": "3"},
+		},
+		{
+			name:        "invalid revision",
+			annotations: map[string]string{"giantswarm.io/logging-config-revision": "latest"},
+			expectError: true,
+		},
+		{
+			name:        "negative revision",
+			annotations: map[string]string{"giantswarm.io/logging-config-revision": "-1"},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := capicluster.New(&capiv1beta2.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", Namespace: "org-test", Annotations: tc.annotations}})
+
+			revision, pinned, err := Pinned(cluster, "logging-config")
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("expected error, got revision %d", revision)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if revision != tc.expected || pinned != tc.expectedPinned {
+				t.Errorf("expected revision %d pinned %t, got %d %t", tc.expected, tc.expectedPinned, revision, pinned)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package rollout
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/cluster-api/controllers/remote"
+	"sigs.k8s.io/cluster-api/util/secret"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/logging-operator/pkg/alloyvalues"
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
+	"github.com/giantswarm/logging-operator/pkg/common"
+	"github.com/giantswarm/logging-operator/pkg/config"
+)
+
+// alloyNamespace is the namespace the Alloy apps run in on the clusters.
+const alloyNamespace = "kube-system"
+
+// HealthChecker checks whether the logging agents of a cluster run the latest configuration.
+type HealthChecker interface {
+	// Check returns an error explaining why the cluster is not healthy, nil once it is.
+	Check(ctx context.Context, cluster *capicluster.Cluster) error
+	// Forget releases what is kept to check the given cluster, once it is deleted or no longer logged.
+	Forget(cluster client.ObjectKey)
+}
+
+// AlloyHealthChecker checks that the Alloy daemonset and deployment of the reconciled apps run the configuration of
+// their values, and are ready and updated. Alloy reloads its configuration without restarting, the configuration its
+// pods run is told by their config hash annotation, which rolls them out on changes.
+// Workload clusters are reached through the kubeconfig secret CAPI generates for them, their clients are kept
+// until the secret changes.
+type AlloyHealthChecker struct {
+	Client client.Client
+	Config config.Config
+	// Apps are the names of the checked Alloy apps, with the metadata of the configmap holding their values.
+	Apps map[string]func(cluster *capicluster.Cluster) metav1.ObjectMeta
+	// ClusterClient returns the client of a workload cluster, remote.NewClusterClient by default.
+	ClusterClient func(ctx context.Context, sourceName string, c client.Client, cluster client.ObjectKey) (client.Client, error)
+
+	mu      sync.Mutex
+	clients map[client.ObjectKey]clusterClient
+}
+
+// clusterClient is the client of a workload cluster, built from the given version of its kubeconfig secret.
+type clusterClient struct {
+	client                    client.Client
+	kubeconfigResourceVersion string
+}
+
+// Check implements HealthChecker.
+func (h *AlloyHealthChecker) Check(ctx context.Context, cluster *capicluster.Cluster) error {
+	c, err := h.clusterClient(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
+	for _, app := range slices.Sorted(maps.Keys(h.Apps)) {
+		configHash, err := h.configHash(ctx, h.Apps[app](cluster))
+		if err != nil {
+			return err
+		}
+		err = checkWorkload(ctx, c, types.NamespacedName{Name: app, Namespace: alloyNamespace}, configHash)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configHash returns the hash of the Alloy configuration the values of the given configmap annotate the pods with,
+// empty for the values written before the pods were annotated.
+func (h *AlloyHealthChecker) configHash(ctx context.Context, configMeta metav1.ObjectMeta) (string, error) {
+	var configmap v1.ConfigMap
+	err := h.Client.Get(ctx, types.NamespacedName{Name: configMeta.GetName(), Namespace: configMeta.GetNamespace()}, &configmap)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read configmap %s/%s", configMeta.GetNamespace(), configMeta.GetName())
+	}
+	configHash, err := alloyvalues.ConfigHash(configmap.Data["values"])
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid values in configmap %s/%s", configMeta.GetNamespace(), configMeta.GetName())
+	}
+	return configHash, nil
+}
+
+// Forget implements HealthChecker, the client of the cluster is dropped.
+func (h *AlloyHealthChecker) Forget(cluster client.ObjectKey) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.clients, cluster)
+}
+
+// clusterClient returns the client of the given cluster, the management cluster client for the management cluster.
+// The client of a workload cluster is built again when its kubeconfig secret changes.
+func (h *AlloyHealthChecker) clusterClient(ctx context.Context, cluster *capicluster.Cluster) (client.Client, error) {
+	if !common.IsWorkloadCluster(h.Config.InstallationName, cluster.GetName()) {
+		return h.Client, nil
+	}
+
+	key := client.ObjectKey{Name: cluster.GetName(), Namespace: cluster.GetNamespace()}
+	kubeconfig, err := secret.GetFromNamespacedName(ctx, h.Client, key, secret.Kubeconfig)
+	if err != nil {
+		h.mu.Lock()
+		delete(h.clients, key)
+		h.mu.Unlock()
+		return nil, errors.Wrap(err, "failed to read the workload cluster kubeconfig")
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if cached, ok := h.clients[key]; ok && cached.kubeconfigResourceVersion == kubeconfig.GetResourceVersion() {
+		return cached.client, nil
+	}
+
+	newClient := h.ClusterClient
+	if newClient == nil {
+		newClient = remote.NewClusterClient
+	}
+	c, err := newClient(ctx, "logging-operator", h.Client, key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to the workload cluster")
+	}
+	if h.clients == nil {
+		h.clients = map[client.ObjectKey]clusterClient{}
+	}
+	h.clients[key] = clusterClient{client: c, kubeconfigResourceVersion: kubeconfig.GetResourceVersion()}
+	return c, nil
+}
+
+// checkWorkload checks the Alloy daemonset, or deployment when there is no daemonset, with the given name.
+// Its pods must be annotated with the given config hash, unless it is empty.
+func checkWorkload(ctx context.Context, c client.Client, name types.NamespacedName, configHash string) error {
+	var daemonSet appsv1.DaemonSet
+	err := c.Get(ctx, name, &daemonSet)
+	if err == nil {
+		err = configApplied("daemonset", &daemonSet, daemonSet.Spec.Template, configHash)
+		if err != nil {
+			return err
+		}
+		return daemonSetReady(&daemonSet)
+	}
+	if !apimachineryerrors.IsNotFound(err) {
+		return errors.WithStack(err)
+	}
+
+	var deployment appsv1.Deployment
+	err = c.Get(ctx, name, &deployment)
+	if apimachineryerrors.IsNotFound(err) {
+		return errors.Errorf("no daemonset or deployment %s found", name)
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = configApplied("deployment", &deployment, deployment.Spec.Template, configHash)
+	if err != nil {
+		return err
+	}
+	return deploymentReady(&deployment)
+}
+
+// configApplied returns an error unless the pod template of the workload is annotated with the given config hash.
+func configApplied(kind string, workload client.Object, template v1.PodTemplateSpec, configHash string) error {
+	if configHash == "" {
+		return nil
+	}
+	if template.GetAnnotations()[alloyvalues.ConfigHashAnnotation] != configHash {
+		return errors.Errorf("%s %s/%s does not run the current configuration yet", kind, workload.GetNamespace(), workload.GetName())
+	}
+	return nil
+}
+
+// daemonSetReady returns an error unless all the pods of the daemonset are updated and available.
+func daemonSetReady(daemonSet *appsv1.DaemonSet) error {
+	status := daemonSet.Status
+	if status.ObservedGeneration < daemonSet.GetGeneration() {
+		return errors.Errorf("daemonset %s/%s update is not observed yet", daemonSet.GetNamespace(), daemonSet.GetName())
+	}
+	if status.UpdatedNumberScheduled != status.DesiredNumberScheduled || status.NumberAvailable != status.DesiredNumberScheduled {
+		return errors.Errorf("daemonset %s/%s has %d updated and %d available pods out of %d",
+			daemonSet.GetNamespace(), daemonSet.GetName(), status.UpdatedNumberScheduled, status.NumberAvailable, status.DesiredNumberScheduled)
+	}
+	return nil
+}
+
+// deploymentReady returns an error unless all the replicas of the deployment are updated and available.
+func deploymentReady(deployment *appsv1.Deployment) error {
+	status := deployment.Status
+	if status.ObservedGeneration < deployment.GetGeneration() {
+		return errors.Errorf("deployment %s/%s update is not observed yet", deployment.GetNamespace(), deployment.GetName())
+	}
+
+	desired := int32(1)
+	if deployment.Spec.Replicas != nil {
+		desired = *deployment.Spec.Replicas
+	}
+	if status.UpdatedReplicas != desired || status.AvailableReplicas != desired || status.Replicas != desired {
+		return errors.Errorf("deployment %s/%s has %d updated and %d available replicas out of %d",
+			deployment.GetNamespace(), deployment.GetName(), status.UpdatedReplicas, status.AvailableReplicas, desired)
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package rollout
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	capiv1beta2 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/logging-operator/pkg/alloyvalues"
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
+	"github.com/giantswarm/logging-operator/pkg/config"
+)
+
+func TestAlloyHealthChecker(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+
+	kubeconfig := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster-kubeconfig", Namespace: "org-test"}}
+	configMeta := func(app string) func(*capicluster.Cluster) metav1.ObjectMeta {
+		return func(cluster *capicluster.Cluster) metav1.ObjectMeta {
+			return metav1.ObjectMeta{Name: cluster.GetName() + "-" + app + "-config", Namespace: cluster.GetNamespace()}
+		}
+	}
+	values := func(config string) map[string]string {
+		return map[string]string{"values": "alloy:\n  controller:\n    podAnnotations:\n      " + alloyvalues.ConfigHashAnnotation + ": " + alloyvalues.ConfigPodAnnotations(config)[alloyvalues.ConfigHashAnnotation] + "\n"}
+	}
+	logsConfig := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster-alloy-logs-config", Namespace: "org-test"}, Data: values("logs")}
+	eventsConfig := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster-alloy-events-config", Namespace: "org-test"}, Data: values("events")}
+
+	logs := metav1.ObjectMeta{Name: "alloy-logs", Namespace: "kube-system", Generation: 2}
+	logsTemplate := v1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Annotations: alloyvalues.ConfigPodAnnotations("logs")}}
+	events := metav1.ObjectMeta{Name: "alloy-events", Namespace: "kube-system", Generation: 1}
+	readyEvents := &appsv1.Deployment{
+		ObjectMeta: events,
+		Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(1)), Template: v1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Annotations: alloyvalues.ConfigPodAnnotations("events")}}},
+		Status:     appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1},
+	}
+
+	testCases := []struct {
+		name              string
+		managementObjects []client.Object
+		objects           []client.Object
+		expectedError     string
+	}{
+		{
+			name: "ready",
+			objects: []client.Object{
+				&appsv1.DaemonSet{ObjectMeta: logs, Spec: appsv1.DaemonSetSpec{Template: logsTemplate}, Status: appsv1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 3}},
+				readyEvents,
+			},
+		},
+		{
+			name: "update not observed",
+			objects: []client.Object{
+				&appsv1.DaemonSet{ObjectMeta: logs, Spec: appsv1.DaemonSetSpec{Template: logsTemplate}, Status: appsv1.DaemonSetStatus{ObservedGeneration: 1, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 3}},
+				readyEvents,
+			},
+			expectedError: "daemonset kube-system/alloy-logs update is not observed yet",
+		},
+		{
+			name: "daemonset rolling",
+			objects: []client.Object{
+				&appsv1.DaemonSet{ObjectMeta: logs, Spec: appsv1.DaemonSetSpec{Template: logsTemplate}, Status: appsv1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 1, NumberAvailable: 3}},
+				readyEvents,
+			},
+			expectedError: "daemonset kube-system/alloy-logs has 1 updated and 3 available pods out of 3",
+		},
+		{
+			name: "deployment rolling",
+			objects: []client.Object{
+				&appsv1.DaemonSet{ObjectMeta: logs, Spec: appsv1.DaemonSetSpec{Template: logsTemplate}, Status: appsv1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 3}},
+				&appsv1.Deployment{
+					ObjectMeta: events,
+					Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(1)), Template: readyEvents.Spec.Template},
+					Status:     appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 2, UpdatedReplicas: 1, AvailableReplicas: 1},
+				},
+			},
+			expectedError: "deployment kube-system/alloy-events has 1 updated and 1 available replicas out of 1",
+		},
+		{
+			name: "configuration not applied",
+			objects: []client.Object{
+				&appsv1.DaemonSet{ObjectMeta: logs, Status: appsv1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 3}},
+				readyEvents,
+			},
+			expectedError: "daemonset kube-system/alloy-logs does not run the current configuration yet",
+		},
+		{
+			name: "values without config hash",
+			managementObjects: []client.Object{
+				&v1.ConfigMap{ObjectMeta: logsConfig.ObjectMeta, Data: map[string]string{"values": "alloy: {}\n"}},
+				eventsConfig,
+			},
+			objects: []client.Object{
+				&appsv1.DaemonSet{ObjectMeta: logs, Status: appsv1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 3}},
+				readyEvents,
+			},
+		},
+		{
+			name:              "missing configmap",
+			managementObjects: []client.Object{logsConfig},
+			objects:           []client.Object{readyEvents},
+			expectedError:     `failed to read configmap org-test/test-cluster-alloy-events-config: configmaps "test-cluster-alloy-events-config" not found`,
+		},
+		{
+			name:          "missing",
+			objects:       []client.Object{readyEvents},
+			expectedError: "no daemonset or deployment kube-system/alloy-logs found",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			managementObjects := tc.managementObjects
+			if managementObjects == nil {
+				managementObjects = []client.Object{logsConfig, eventsConfig}
+			}
+			workloadClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tc.objects...).Build()
+			checker := &AlloyHealthChecker{
+				Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(append(managementObjects, kubeconfig)...).Build(),
+				Config: config.Config{InstallationName: "test-installation"},
+				Apps:   map[string]func(*capicluster.Cluster) metav1.ObjectMeta{"alloy-logs": configMeta("alloy-logs"), "alloy-events": configMeta("alloy-events")},
+				ClusterClient: func(_ context.Context, _ string, _ client.Client, cluster client.ObjectKey) (client.Client, error) {
+					if cluster.Name != "test-cluster" || cluster.Namespace != "org-test" {
+						t.Errorf("unexpected cluster %s", cluster)
+					}
+					return workloadClient, nil
+				},
+			}
+			cluster := capicluster.New(&capiv1beta2.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", Namespace: "org-test"}})
+
+			err := checker.Check(context.Background(), cluster)
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expectedError {
+				t.Errorf("expected error %q, got %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestAlloyHealthCheckerClusterClient(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+
+	ctx := context.Background()
+	kubeconfig := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster-kubeconfig", Namespace: "org-test"}}
+	managementClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(kubeconfig).Build()
+	var built int
+	checker := &AlloyHealthChecker{
+		Client: managementClient,
+		Config: config.Config{InstallationName: "test-installation"},
+		ClusterClient: func(_ context.Context, _ string, _ client.Client, _ client.ObjectKey) (client.Client, error) {
+			built++
+			return fake.NewClientBuilder().WithScheme(scheme).Build(), nil
+		},
+	}
+	cluster := capicluster.New(&capiv1beta2.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", Namespace: "org-test"}})
+
+	steps := []struct {
+		name          string
+		update        func()
+		expectedBuilt int
+		expectError   bool
+	}{
+		{name: "first check", expectedBuilt: 1},
+		{name: "kubeconfig unchanged", expectedBuilt: 1},
+		{
+			name: "kubeconfig rotated",
+			update: func() {
+				kubeconfig.Data = map[string][]byte{"value": []byte("rotated")}
+				if err := managementClient.Update(ctx, kubeconfig); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			},
+			expectedBuilt: 2,
+		},
+		{
+			name: "cluster forgotten",
+			update: func() {
+				checker.Forget(client.ObjectKeyFromObject(cluster.Object))
+			},
+			expectedBuilt: 3,
+		},
+		{
+			name: "kubeconfig deleted",
+			update: func() {
+				if err := managementClient.Delete(ctx, kubeconfig); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			},
+			expectedBuilt: 3,
+			expectError:   true,
+		},
+	}
+
+	for _, step := range steps {
+		if step.update != nil {
+			step.update()
+		}
+		_, err := checker.clusterClient(ctx, cluster)
+		if (err != nil) != step.expectError {
+			t.Errorf("%s: unexpected error %v", step.name, err)
+		}
+		if built != step.expectedBuilt {
+			t.Errorf("%s: expected %d clients built, got %d", step.name, step.expectedBuilt, built)
+		}
+	}
+	if len(checker.clients) != 0 {
+		t.Errorf("expected the client of the deleted kubeconfig to be dropped, got %v", checker.clients)
+	}
+}
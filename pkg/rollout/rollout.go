@@ -0,0 +1,402 @@
+package rollout
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	loggingv1alpha1 "github.com/giantswarm/logging-operator/api/v1alpha1"
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
+	"github.com/giantswarm/logging-operator/pkg/common"
+	"github.com/giantswarm/logging-operator/pkg/config"
+)
+
+const (
+	DefaultCanaryPercentage = 10
+	DefaultBatchSize        = 10
+	DefaultSoakDuration     = 5 * time.Minute
+	DefaultHealthTimeout    = 15 * time.Minute
+
+	// RequeueAfter is how often clusters are reconciled while their configuration update is held back or in progress.
+	RequeueAfter = time.Minute
+
+	// maxReportedClusters is the number of clusters named in the reasons an update is held back.
+	maxReportedClusters = 3
+)
+
+// ValidateConfig checks the rollout settings when the rollout is enabled.
+func ValidateConfig(cfg config.Config) error {
+	if !cfg.RolloutEnabled {
+		return nil
+	}
+	if _, err := labels.Parse(cfg.RolloutCanarySelector); err != nil {
+		return errors.Wrap(err, "invalid rollout canary selector")
+	}
+	if cfg.RolloutCanaryPercentage < 0 || cfg.RolloutCanaryPercentage > 100 {
+		return errors.Errorf("rollout canary percentage must be between 0 and 100, got %d", cfg.RolloutCanaryPercentage)
+	}
+	if cfg.RolloutBatchSize < 1 {
+		return errors.Errorf("rollout batch size must be at least 1, got %d", cfg.RolloutBatchSize)
+	}
+	if cfg.RolloutSoakDuration < 0 {
+		return errors.Errorf("rollout soak duration must not be negative, got %s", cfg.RolloutSoakDuration)
+	}
+	if cfg.RolloutHealthTimeout <= cfg.RolloutSoakDuration {
+		return errors.Errorf("rollout health timeout %s must be longer than the soak duration %s", cfg.RolloutHealthTimeout, cfg.RolloutSoakDuration)
+	}
+	return nil
+}
+
+// Rollout decides when the configuration of a cluster may be updated, and tracks the health of the clusters once
+// they are. Updates belonging to a new revision reach the canary clusters first, and the other clusters in batches
+// once all canaries are healthy. The rollout pauses as soon as a cluster fails to become healthy.
+type Rollout struct {
+	Client client.Client
+	Config config.Config
+	Health HealthChecker
+}
+
+// Decision tells the resources whether they may update the configuration of a cluster.
+type Decision struct {
+	Allowed bool
+	// Reason explains why the update is not allowed.
+	Reason string
+	Canary bool
+	// Revision is the revision the cluster is rolled out to.
+	Revision string
+
+	held  bool
+	phase string
+}
+
+// Hold records that a resource held its update back because it was not allowed.
+func (d *Decision) Hold() {
+	d.held = true
+}
+
+// Held returns true if a resource held its update back.
+func (d *Decision) Held() bool {
+	return d.held
+}
+
+// InProgress returns true while the cluster waits for its update or for its Alloy workloads to become healthy.
+func (d *Decision) InProgress() bool {
+	return d.phase == loggingv1alpha1.RolloutPendingPhase || d.phase == loggingv1alpha1.RolloutProgressingPhase
+}
+
+type decisionKey struct{}
+
+// NewContext returns a context carrying the rollout decision for the reconciled cluster.
+func NewContext(ctx context.Context, decision *Decision) context.Context {
+	return context.WithValue(ctx, decisionKey{}, decision)
+}
+
+// FromContext returns the rollout decision carried by the context, or a decision allowing all updates.
+func FromContext(ctx context.Context) *Decision {
+	if decision, ok := ctx.Value(decisionKey{}).(*Decision); ok {
+		return decision
+	}
+	return &Decision{Allowed: true}
+}
+
+// buildRevision identifies the operator build, whose upgrades change the configurations.
+var buildRevision = func() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	revision := info.Main.Version
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			revision += "/" + setting.Value
+		}
+	}
+	return revision
+}()
+
+// revisionSettings are the operator settings shaping the configurations of the clusters. The rollout and revision
+// history settings do not change the configurations, and credentials are redacted from the URLs.
+type revisionSettings struct {
+	LogsReconciliationEnabled      bool
+	EventsReconciliationEnabled    bool
+	EnableNodeFilteringFlag        bool
+	EnableTracingFlag              bool
+	EnableNetworkMonitoringFlag    bool
+	InstallationName               string
+	InsecureCA                     bool
+	CABundleSecret                 string
+	CABundleConfigMap              string
+	CABundleKey                    string
+	DefaultNamespaces              []string
+	IncludeEventsFromNamespaces    []string
+	ExcludeEventsFromNamespaces    []string
+	EndpointSource                 string
+	LokiEndpoint                   string
+	TempoEndpoint                  string
+	LokiPushURL                    string
+	LokiRulerURL                   string
+	ManagementClusterLokiPushURL   string
+	ManagementClusterLokiRulerURL  string
+	ManagementClusterTempoEndpoint string
+	AuthMode                       string
+	ProxyURL                       string
+	NoProxy                        []string
+	TemplatesConfigMap             string
+}
+
+// newRevisionSettings returns the settings of the given configuration taken into the revision.
+func newRevisionSettings(cfg config.Config) revisionSettings {
+	return revisionSettings{
+		LogsReconciliationEnabled:      cfg.LogsReconciliationEnabled,
+		EventsReconciliationEnabled:    cfg.EventsReconciliationEnabled,
+		EnableNodeFilteringFlag:        cfg.EnableNodeFilteringFlag,
+		EnableTracingFlag:              cfg.EnableTracingFlag,
+		EnableNetworkMonitoringFlag:    cfg.EnableNetworkMonitoringFlag,
+		InstallationName:               cfg.InstallationName,
+		InsecureCA:                     cfg.InsecureCA,
+		CABundleSecret:                 cfg.CABundleSecret,
+		CABundleConfigMap:              cfg.CABundleConfigMap,
+		CABundleKey:                    cfg.CABundleKey,
+		DefaultNamespaces:              cfg.DefaultNamespaces,
+		IncludeEventsFromNamespaces:    cfg.IncludeEventsFromNamespaces,
+		ExcludeEventsFromNamespaces:    cfg.ExcludeEventsFromNamespaces,
+		EndpointSource:                 cfg.EndpointSource,
+		LokiEndpoint:                   redactURL(cfg.LokiEndpoint),
+		TempoEndpoint:                  redactURL(cfg.TempoEndpoint),
+		LokiPushURL:                    redactURL(cfg.LokiPushURL),
+		LokiRulerURL:                   redactURL(cfg.LokiRulerURL),
+		ManagementClusterLokiPushURL:   redactURL(cfg.ManagementClusterLokiPushURL),
+		ManagementClusterLokiRulerURL:  redactURL(cfg.ManagementClusterLokiRulerURL),
+		ManagementClusterTempoEndpoint: redactURL(cfg.ManagementClusterTempoEndpoint),
+		AuthMode:                       cfg.AuthMode,
+		ProxyURL:                       redactURL(cfg.ProxyURL),
+		NoProxy:                        cfg.NoProxy,
+		TemplatesConfigMap:             cfg.TemplatesConfigMap,
+	}
+}
+
+// redactURL returns the given URL without the password it may hold, nothing when it cannot be parsed.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return u.Redacted()
+}
+
+// Revision returns the revision of the configurations: the hash of the operator build, its settings, the replacement
+// templates and the LoggingPolicies, which change the configurations of many clusters at once.
+func (r *Rollout) Revision(ctx context.Context) (string, error) {
+	templatesHash, err := common.TemplatesHash(ctx, r.Client, r.Config)
+	if err != nil {
+		return "", err
+	}
+
+	var policies loggingv1alpha1.LoggingPolicyList
+	err = r.Client.List(ctx, &policies)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	return hashRevision(buildRevision, newRevisionSettings(r.Config), templatesHash, policies.Items)
+}
+
+// hashRevision hashes the given build, settings, templates hash and LoggingPolicies, regardless of the order of the
+// policies.
+func hashRevision(build string, settings revisionSettings, templatesHash string, policies []loggingv1alpha1.LoggingPolicy) (string, error) {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00%s\x00", build, templatesHash)
+
+	encoder := json.NewEncoder(hash)
+	err := encoder.Encode(settings)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	policies = slices.Clone(policies)
+	slices.SortFunc(policies, func(a, b loggingv1alpha1.LoggingPolicy) int {
+		return strings.Compare(a.GetNamespace()+"/"+a.GetName(), b.GetNamespace()+"/"+b.GetName())
+	})
+	for _, policy := range policies {
+		fmt.Fprintf(hash, "%s/%s\x00", policy.GetNamespace(), policy.GetName())
+		err = encoder.Encode(policy.Spec)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:12], nil
+}
+
+// IsCanary returns true if the cluster matches the canary selector or, without selector, belongs to the canary
+// percentage of the clusters, picked from the hash of their names.
+func (r *Rollout) IsCanary(cluster *capicluster.Cluster) (bool, error) {
+	if r.Config.RolloutCanarySelector != "" {
+		selector, err := labels.Parse(r.Config.RolloutCanarySelector)
+		if err != nil {
+			return false, errors.WithStack(err)
+		}
+		return selector.Matches(labels.Set(cluster.GetLabels())), nil
+	}
+
+	hash := fnv.New32a()
+	fmt.Fprintf(hash, "%s/%s", cluster.GetNamespace(), cluster.GetName())
+	return int(hash.Sum32()%100) < r.Config.RolloutCanaryPercentage, nil
+}
+
+// Forget releases what the health checks keep for the given cluster, once it is deleted or no longer logged.
+func (r *Rollout) Forget(cluster client.ObjectKey) {
+	r.Health.Forget(cluster)
+}
+
+// Decide returns whether the configuration of the cluster may be updated, given the rollout status of the clusters.
+func (r *Rollout) Decide(ctx context.Context, cluster *capicluster.Cluster) (*Decision, error) {
+	revision, err := r.Revision(ctx)
+	if err != nil {
+		return nil, err
+	}
+	canary, err := r.IsCanary(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses loggingv1alpha1.ClusterLoggingStatusList
+	err = r.Client.List(ctx, &statuses)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return decide(cluster, canary, revision, statuses.Items, r.Config.RolloutBatchSize), nil
+}
+
+// decide applies the rollout rules to the given cluster: canaries are always updated, the other clusters once all
+// canaries are healthy in the current revision, in batches, unless a cluster failed. Only the clusters which are not
+// in the current revision yet are held back.
+func decide(cluster *capicluster.Cluster, canary bool, revision string, statuses []loggingv1alpha1.ClusterLoggingStatus, batchSize int) *Decision {
+	decision := &Decision{Allowed: true, Canary: canary, Revision: revision}
+	if canary {
+		return decision
+	}
+
+	var failed, waitingCanaries []string
+	var progressing int
+	for _, status := range statuses {
+		rollout := status.Status.Rollout
+		if rollout == nil {
+			continue
+		}
+		if status.GetNamespace() == cluster.GetNamespace() && status.GetName() == cluster.GetName() {
+			// The cluster was already let through in this revision: further updates are either part of the same step
+			// or changes of the cluster itself, e.g. its values overrides, proxy or tenants, which are not rolled out.
+			if rollout.Revision == revision {
+				return decision
+			}
+			continue
+		}
+
+		name := status.GetNamespace() + "/" + status.GetName()
+		switch {
+		case rollout.Phase == loggingv1alpha1.RolloutFailedPhase:
+			failed = append(failed, name)
+		case rollout.Canary && (rollout.Revision != revision || rollout.Phase != loggingv1alpha1.RolloutHealthyPhase):
+			waitingCanaries = append(waitingCanaries, name)
+		case !rollout.Canary && rollout.Revision == revision && rollout.Phase == loggingv1alpha1.RolloutProgressingPhase:
+			progressing++
+		}
+	}
+
+	switch {
+	case len(failed) > 0:
+		decision.Allowed = false
+		decision.Reason = fmt.Sprintf("rollout paused, clusters failed to become healthy: %s", clusterList(failed))
+	case len(waitingCanaries) > 0:
+		decision.Allowed = false
+		decision.Reason = fmt.Sprintf("waiting for canary clusters to become healthy: %s", clusterList(waitingCanaries))
+	case progressing >= batchSize:
+		decision.Allowed = false
+		decision.Reason = fmt.Sprintf("waiting for %d clusters in progress, the batch size is %d", progressing, batchSize)
+	}
+	return decision
+}
+
+// Status returns the rollout status of the cluster once its resources are reconciled with the given decision and
+// its configurations have the given hash. The health of the cluster is checked while its update is in progress.
+func (r *Rollout) Status(ctx context.Context, cluster *capicluster.Cluster, previous *loggingv1alpha1.RolloutStatus, decision *Decision, configHash string) *loggingv1alpha1.RolloutStatus {
+	status := nextStatus(previous, decision, configHash, time.Now(), r.Config, func() error {
+		return r.Health.Check(ctx, cluster)
+	})
+	decision.phase = status.Phase
+	return status
+}
+
+// nextStatus computes the rollout status following the previous one, checking the health of the cluster when
+// its update is in progress.
+func nextStatus(previous *loggingv1alpha1.RolloutStatus, decision *Decision, configHash string, now time.Time, cfg config.Config, check func() error) *loggingv1alpha1.RolloutStatus {
+	status := &loggingv1alpha1.RolloutStatus{}
+	if previous != nil {
+		status = previous.DeepCopy()
+	}
+	status.Canary = decision.Canary
+	status.Message = ""
+
+	if decision.Held() {
+		status.Phase = loggingv1alpha1.RolloutPendingPhase
+		status.Message = decision.Reason
+		return status
+	}
+
+	status.Revision = decision.Revision
+	if previous == nil {
+		// Clusters seen for the first time, e.g. new clusters still being created, are not part of a rollout.
+		status.ConfigHash = configHash
+		status.UpdateTime = &metav1.Time{Time: now}
+		status.Phase = loggingv1alpha1.RolloutHealthyPhase
+		return status
+	}
+	if configHash != status.ConfigHash || status.UpdateTime == nil {
+		status.ConfigHash = configHash
+		status.UpdateTime = &metav1.Time{Time: now}
+		status.Phase = loggingv1alpha1.RolloutProgressingPhase
+	}
+	if status.Phase == loggingv1alpha1.RolloutHealthyPhase {
+		return status
+	}
+
+	err := check()
+	elapsed := now.Sub(status.UpdateTime.Time)
+	switch {
+	case err == nil && elapsed >= cfg.RolloutSoakDuration:
+		status.Phase = loggingv1alpha1.RolloutHealthyPhase
+	case err == nil:
+		status.Phase = loggingv1alpha1.RolloutProgressingPhase
+		status.Message = fmt.Sprintf("Alloy is ready, soaking until %s", status.UpdateTime.Add(cfg.RolloutSoakDuration).Format(time.RFC3339))
+	case elapsed >= cfg.RolloutHealthTimeout:
+		status.Phase = loggingv1alpha1.RolloutFailedPhase
+		status.Message = err.Error()
+	default:
+		status.Phase = loggingv1alpha1.RolloutProgressingPhase
+		status.Message = err.Error()
+	}
+	return status
+}
+
+// clusterList formats the names of the given clusters, truncated to the first few ones.
+func clusterList(names []string) string {
+	slices.Sort(names)
+	if len(names) <= maxReportedClusters {
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(names[:maxReportedClusters], ", "), len(names)-maxReportedClusters)
+}
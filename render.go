@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/tools/record"
+	capiv1beta2 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/logging-operator/internal/render"
+	"github.com/giantswarm/logging-operator/pkg/capicluster"
+	"github.com/giantswarm/logging-operator/pkg/common"
+	"github.com/giantswarm/logging-operator/pkg/config"
+	"github.com/giantswarm/logging-operator/pkg/revision"
+	"github.com/giantswarm/logging-operator/pkg/rollout"
+)
+
+// runRender implements the render subcommand, which prints the logging-config, events-logger-config
+// and redacted secret values the operator would generate for a cluster, without writing anything.
+//
+// Usage: logging-operator render --cluster <namespace>/<name> [--fixtures <dir>] [--diff] [config flags]
+func runRender(args []string) error {
+	fs := flag.NewFlagSet("render", flag.ContinueOnError)
+	var appConfig config.Config
+	var clusterRef string
+	var fixtures string
+	var diff bool
+	bindConfigFlags(fs, &appConfig)
+	fs.StringVar(&clusterRef, "cluster", "", "Cluster to render the values for, as <namespace>/<name>")
+	fs.StringVar(&fixtures, "fixtures", "", "Directory of YAML files to read the objects from instead of the current kubeconfig")
+	fs.BoolVar(&diff, "diff", false, "Print the diff against the existing objects instead of the rendered values")
+	opts := zap.Options{
+		Development: false,
+	}
+	opts.BindFlags(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+
+	err = common.ValidateEndpointsConfig(appConfig)
+	if err != nil {
+		return err
+	}
+	err = common.ValidateCABundleConfig(appConfig)
+	if err != nil {
+		return err
+	}
+	err = common.ValidateAuthConfig(appConfig)
+	if err != nil {
+		return err
+	}
+	err = common.ValidateProxyConfig(appConfig)
+	if err != nil {
+		return err
+	}
+	err = common.ValidateTemplatesConfig(appConfig)
+	if err != nil {
+		return err
+	}
+	err = rollout.ValidateConfig(appConfig)
+	if err != nil {
+		return err
+	}
+	err = revision.ValidateConfig(appConfig)
+	if err != nil {
+		return err
+	}
+
+	namespace, name, ok := strings.Cut(clusterRef, "/")
+	if !ok || namespace == "" || name == "" {
+		return errors.Errorf("--cluster must be set as <namespace>/<name>, got %q", clusterRef)
+	}
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+
+	var c client.Client
+	var version capicluster.Version
+	if fixtures != "" {
+		c, version, err = fixturesClient(fixtures, key)
+	} else {
+		c, version, err = kubeconfigClient()
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	ctx := context.Background()
+	cluster, err := capicluster.Get(ctx, c, version, key)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get cluster %s", clusterRef)
+	}
+
+	recorder := record.NewFakeRecorder(100)
+	resources, _ := newResources(c, appConfig, recorder)
+
+	objects, renderErr := render.Render(ctx, c, cluster, appConfig, resources)
+	close(recorder.Events)
+	// Only warnings are relevant, the resources do not write anything while rendering.
+	for event := range recorder.Events {
+		if strings.HasPrefix(event, v1.EventTypeWarning) {
+			fmt.Fprintln(os.Stderr, event)
+		}
+	}
+
+	if diff {
+		err = render.WriteDiff(ctx, os.Stdout, c, objects)
+	} else {
+		err = render.Write(os.Stdout, objects)
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return renderErr
+}
+
+// kubeconfigClient returns a client for the cluster of the current kubeconfig.
+func kubeconfigClient() (client.Client, capicluster.Version, error) {
+	restConfig, err := ctrl.GetConfig()
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	c, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	version, err := capicluster.ServedVersion(c.RESTMapper())
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+	capicluster.RegisterConversion(c.RESTMapper())
+
+	return c, version, nil
+}
+
+// fixturesClient returns a fake client serving the objects of the YAML files found in the given directory.
+// Clusters are read in the version of the fixture of the given cluster.
+func fixturesClient(dir string, key types.NamespacedName) (client.Client, capicluster.Version, error) {
+	var objects []client.Object
+	var groupVersions []schema.GroupVersion
+	var kinds []schema.GroupVersionKind
+	decoder := serializer.NewCodecFactory(scheme).UniversalDeserializer()
+
+	err := filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || (filepath.Ext(path) != ".yaml" && filepath.Ext(path) != ".yml") {
+			return nil
+		}
+
+		file, err := os.Open(path) // #nosec G304
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer file.Close() // nolint: errcheck
+
+		reader := utilyaml.NewYAMLReader(bufio.NewReader(file))
+		for {
+			document, err := reader.Read()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return errors.Wrapf(err, "failed to read %s", path)
+			}
+			if len(bytes.TrimSpace(document)) == 0 {
+				continue
+			}
+
+			var typeMeta metav1.TypeMeta
+			err = yaml.Unmarshal(document, &typeMeta)
+			if err != nil {
+				return errors.Wrapf(err, "failed to read %s", path)
+			}
+			if typeMeta.Kind == "" {
+				continue
+			}
+			gvk := typeMeta.GroupVersionKind()
+			groupVersions = append(groupVersions, gvk.GroupVersion())
+			kinds = append(kinds, gvk)
+
+			object, _, err := decoder.Decode(document, nil, nil)
+			if runtime.IsNotRegisteredError(err) {
+				// Objects the operator does not read, like infrastructure clusters,
+				// are only used to resolve the references of the clusters.
+				continue
+			}
+			if err != nil {
+				return errors.Wrapf(err, "failed to decode %s", path)
+			}
+			objects = append(objects, object.(client.Object))
+		}
+	})
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	mapper := meta.NewDefaultRESTMapper(groupVersions)
+	for _, gvk := range kinds {
+		mapper.Add(gvk, meta.RESTScopeNamespace)
+	}
+	capicluster.RegisterConversion(mapper)
+
+	version := capicluster.V1Beta1
+	for _, object := range objects {
+		if _, ok := object.(*capiv1beta2.Cluster); ok && object.GetNamespace() == key.Namespace && object.GetName() == key.Name {
+			version = capicluster.V1Beta2
+		}
+	}
+
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(), version, nil
+}